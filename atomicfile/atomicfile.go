@@ -0,0 +1,43 @@
+// Package atomicfile writes files so that readers never see them partially written, even after a crash.
+package atomicfile
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// tmpExtension is appended to the path of a file while it is being written.
+const tmpExtension = ".tmp"
+
+// WriteFile writes data to a temporary file next to path, syncs it to disk and renames it to path, replacing the file
+// at path, if any. The temporary file is removed if writing fails.
+func WriteFile(path string, data []byte, perm os.FileMode) (err error) {
+	tmpPath := path + tmpExtension
+	//nolint:gosec
+	f, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			//nolint:errcheck
+			os.Remove(tmpPath)
+		}
+	}()
+	if _, err = f.Write(data); err != nil {
+		//nolint:errcheck,gosec
+		f.Close()
+		return err
+	}
+	// Without a sync, a crash after the rename can leave an empty or partial file at path.
+	if err = f.Sync(); err != nil {
+		//nolint:errcheck,gosec
+		f.Close()
+		return errors.Wrap(err, "error syncing file")
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
@@ -0,0 +1,48 @@
+package atomicfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.viam.com/test"
+)
+
+func TestWriteFile(t *testing.T) {
+	t.Run("Write a new file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "file.json")
+		test.That(t, WriteFile(path, []byte("new"), 0o644), test.ShouldBeNil)
+
+		data, err := os.ReadFile(path)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, string(data), test.ShouldEqual, "new")
+		_, err = os.Stat(path + tmpExtension)
+		test.That(t, os.IsNotExist(err), test.ShouldBeTrue)
+	})
+
+	t.Run("Replace a file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "file.json")
+		test.That(t, os.WriteFile(path, []byte("a longer old file"), 0o644), test.ShouldBeNil)
+		test.That(t, WriteFile(path, []byte("new"), 0o644), test.ShouldBeNil)
+
+		data, err := os.ReadFile(path)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, string(data), test.ShouldEqual, "new")
+	})
+
+	t.Run("Remove the temporary file when the rename fails", func(t *testing.T) {
+		// A directory at path cannot be replaced by a file.
+		path := filepath.Join(t.TempDir(), "file.json")
+		test.That(t, os.Mkdir(path, 0o755), test.ShouldBeNil)
+		test.That(t, os.WriteFile(filepath.Join(path, "child"), nil, 0o644), test.ShouldBeNil)
+
+		test.That(t, WriteFile(path, []byte("new"), 0o644), test.ShouldNotBeNil)
+		_, err := os.Stat(path + tmpExtension)
+		test.That(t, os.IsNotExist(err), test.ShouldBeTrue)
+	})
+
+	t.Run("Fail in a missing folder", func(t *testing.T) {
+		err := WriteFile(filepath.Join(t.TempDir(), "missing", "file.json"), []byte("new"), 0o644)
+		test.That(t, os.IsNotExist(err), test.ShouldBeTrue)
+	})
+}
@@ -0,0 +1,325 @@
+// Package main provides a command-line tool to inspect and maintain the data directory of the ORB_SLAM3 SLAM service
+// without running viam-server.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/edaniels/golog"
+	"github.com/pkg/errors"
+	"go.viam.com/rdk/rimage/transform"
+
+	viamorbslam3 "github.com/viamrobotics/viam-orb-slam3"
+	"github.com/viamrobotics/viam-orb-slam3/config"
+	"github.com/viamrobotics/viam-orb-slam3/datadir"
+)
+
+// defaultDataRateMsec is the data rate the SLAM service uses if the config does not set one.
+const defaultDataRateMsec = 200
+
+const usage = `usage: orb-slam3-data <command> [flags] [args]
+
+commands:
+  list <data_dir>              list the maps and frames in a data directory
+  validate <config.json>       validate the attributes of a SLAM service
+  yaml -intrinsics <file.json> -out <file.yaml>
+                               generate the ORB_SLAM3 .yaml config of a camera
+  pairs <data_dir>             check that every RGB frame has a depth frame
+  prune <data_dir>             remove old frames and maps
+  replay -config <config.json> run the SLAM service offline against a data directory and write its results
+`
+
+var commands = map[string]func(args []string) error{
+	"list":     list,
+	"validate": validate,
+	"yaml":     genYAML,
+	"pairs":    pairs,
+	"prune":    prune,
+	"replay":   replay,
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	command, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+	if err := command(os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// parseFlags parses the flags of the command and returns its positional arguments, of which there must be nArgs.
+func parseFlags(flags *flag.FlagSet, args []string, nArgs int, argsUsage string) ([]string, error) {
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: orb-slam3-data %v [flags] %v\n", flags.Name(), argsUsage)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	if flags.NArg() != nArgs {
+		flags.Usage()
+		return nil, errors.Errorf("%v expects %d arguments, got %d", flags.Name(), nArgs, flags.NArg())
+	}
+	return flags.Args(), nil
+}
+
+// list prints the maps of the data directory with their metadata, and the number and time range of its frames.
+func list(args []string) error {
+	flags := flag.NewFlagSet("list", flag.ExitOnError)
+	args, err := parseFlags(flags, args, 1, "<data_dir>")
+	if err != nil {
+		return err
+	}
+	maps, err := datadir.ListMaps(args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Printf("maps: %d\n", len(maps))
+	for _, m := range maps {
+		fmt.Printf("  %v\n", m.Path)
+		switch {
+		case m.MetadataErr != nil:
+			fmt.Printf("    metadata: %v\n", m.MetadataErr)
+		case m.Metadata != nil:
+			md := m.Metadata
+			fmt.Printf("    created_at: %v, mode: %v, keyframes: %d, map_points: %d, module_version: %v\n",
+				md.CreatedAt.Format(time.RFC3339), md.Mode, md.KeyFrames, md.MapPoints, md.ModuleVersion)
+		default:
+			fmt.Println("    no metadata")
+		}
+	}
+	for _, folder := range []string{datadir.RGBDirectory, datadir.DepthDirectory} {
+		frames, err := datadir.ListFrames(args[0], folder)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%v frames: %d", folder, len(frames))
+		if len(frames) > 0 {
+			fmt.Printf(", from %v to %v", frames[0].Timestamp.Format(time.RFC3339Nano),
+				frames[len(frames)-1].Timestamp.Format(time.RFC3339Nano))
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+// validate checks the attributes of a SLAM service, given either on their own or as a service config holding them
+// under "attributes", and prints the dependencies they imply.
+func validate(args []string) error {
+	flags := flag.NewFlagSet("validate", flag.ExitOnError)
+	args, err := parseFlags(flags, args, 1, "<config.json>")
+	if err != nil {
+		return err
+	}
+	cfg, err := readConfig(args[0])
+	if err != nil {
+		return err
+	}
+	deps, err := cfg.Validate("attributes")
+	if err != nil {
+		return err
+	}
+	fmt.Println("config is valid")
+	for _, dep := range deps {
+		fmt.Printf("  depends on %v\n", dep)
+	}
+	return nil
+}
+
+// readConfig reads the attributes of a SLAM service from the JSON file at path. Unknown attributes are rejected.
+func readConfig(path string) (*config.Config, error) {
+	//nolint:gosec
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var service struct {
+		Attributes json.RawMessage `json:"attributes"`
+	}
+	if err := json.Unmarshal(data, &service); err != nil {
+		return nil, errors.Wrapf(err, "invalid config %v", path)
+	}
+	if service.Attributes != nil {
+		data = service.Attributes
+	}
+	var cfg config.Config
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&cfg); err != nil {
+		return nil, errors.Wrapf(err, "invalid config %v", path)
+	}
+	return &cfg, nil
+}
+
+// cameraParameters are the parameters of a camera, named as in the attributes of the camera components.
+type cameraParameters struct {
+	Intrinsics *transform.PinholeCameraIntrinsics `json:"intrinsic_parameters"`
+	Distortion *transform.BrownConrady            `json:"distortion_parameters"`
+}
+
+// genYAML writes the ORB_SLAM3 .yaml config of a camera, as the SLAM service generates it from the properties of
+// the camera and its config.
+func genYAML(args []string) error {
+	flags := flag.NewFlagSet("yaml", flag.ExitOnError)
+	intrinsicsPath := flags.String("intrinsics", "", "JSON file with the intrinsic_parameters and distortion_parameters of the camera")
+	configPath := flags.String("config", "", "JSON file with the attributes of the SLAM service, for its config_params and data_rate_msec")
+	outPath := flags.String("out", "", "path of the .yaml file to write")
+	mapPath := flags.String("load-map", "", "path of a map to load, without its extension")
+	if _, err := parseFlags(flags, args, 0, ""); err != nil {
+		return err
+	}
+	if *intrinsicsPath == "" || *outPath == "" {
+		flags.Usage()
+		return errors.New("-intrinsics and -out are required")
+	}
+
+	//nolint:gosec
+	data, err := os.ReadFile(*intrinsicsPath)
+	if err != nil {
+		return err
+	}
+	var params cameraParameters
+	if err := json.Unmarshal(data, &params); err != nil {
+		return errors.Wrapf(err, "invalid camera parameters %v", *intrinsicsPath)
+	}
+	if params.Intrinsics == nil {
+		return transform.NewNoIntrinsicsError("Intrinsics do not exist")
+	}
+	if err := params.Intrinsics.CheckValid(); err != nil {
+		return err
+	}
+	if params.Distortion == nil {
+		return transform.NewNoIntrinsicsError("Distortion parameters do not exist")
+	}
+
+	configParams, dataRateMsec := map[string]string{}, defaultDataRateMsec
+	if *configPath != "" {
+		cfg, err := readConfig(*configPath)
+		if err != nil {
+			return err
+		}
+		configParams = cfg.ConfigParams
+		if cfg.DataRateMsec != 0 {
+			dataRateMsec = cfg.DataRateMsec
+		}
+	}
+	settings, err := viamorbslam3.NewORBSettings(
+		&transform.PinholeCameraModel{PinholeCameraIntrinsics: params.Intrinsics, Distortion: params.Distortion},
+		configParams, dataRateMsec, golog.NewLogger("orb-slam3-data"))
+	if err != nil {
+		return err
+	}
+	if *mapPath != "" {
+		settings.LoadMapLoc = "\"" + *mapPath + "\""
+	}
+
+	return viamorbslam3.WriteORBYAML(*outPath, settings)
+}
+
+// pairs checks that every RGB frame of the data directory has a depth frame and vice versa, and fails if not.
+func pairs(args []string) error {
+	flags := flag.NewFlagSet("pairs", flag.ExitOnError)
+	args, err := parseFlags(flags, args, 1, "<data_dir>")
+	if err != nil {
+		return err
+	}
+	pairing, err := datadir.CheckPairing(args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Printf("pairs: %d\n", pairing.Pairs)
+	for _, path := range pairing.MissingDepth {
+		fmt.Printf("  no depth frame for %v\n", path)
+	}
+	for _, path := range pairing.MissingRGB {
+		fmt.Printf("  no RGB frame for %v\n", path)
+	}
+	if unmatched := len(pairing.MissingDepth) + len(pairing.MissingRGB); unmatched > 0 {
+		return errors.Errorf("%d frames are unmatched", unmatched)
+	}
+	return nil
+}
+
+// prune removes the frames and maps of the data directory selected by its flags.
+func prune(args []string) error {
+	flags := flag.NewFlagSet("prune", flag.ExitOnError)
+	olderThan := flags.Duration("older-than", 0, "remove the frames captured longer ago than this, e.g. 72h")
+	keepMaps := flags.Int("keep-maps", 0, "remove all but this number of newest maps, keeping all of them if 0")
+	dryRun := flags.Bool("dry-run", false, "print the files that would be removed without removing them")
+	args, err := parseFlags(flags, args, 1, "<data_dir>")
+	if err != nil {
+		return err
+	}
+	if *olderThan < 0 {
+		return errors.New("-older-than must not be negative")
+	}
+	opts := datadir.PruneOptions{KeepMaps: *keepMaps, DryRun: *dryRun}
+	if *olderThan > 0 {
+		opts.FramesBefore = time.Now().Add(-*olderThan)
+	}
+	removed, err := datadir.Prune(args[0], opts)
+	verb := "removed"
+	if *dryRun {
+		verb = "would remove"
+	}
+	for _, path := range removed {
+		fmt.Printf("%v %v\n", verb, path)
+	}
+	return err
+}
+
+// replay runs the SLAM service offline against the data directory of a config, and writes the final pose, point cloud
+// map and internal state once all recorded data is processed.
+func replay(args []string) error {
+	flags := flag.NewFlagSet("replay", flag.ExitOnError)
+	configPath := flags.String("config", "", "JSON file with the attributes of the SLAM service")
+	dataDir := flags.String("data-dir", "", "data directory to replay, overriding the data_dir of the config")
+	outDir := flags.String("out", "", "folder to write the results to, a new folder in the exports folder of the data directory if empty")
+	timeout := flags.Duration("timeout", 0, "time given to the SLAM process to process all data, unlimited if 0")
+	executable := flags.String("executable", viamorbslam3.DefaultExecutableName, "SLAM process to run")
+	if _, err := parseFlags(flags, args, 0, ""); err != nil {
+		return err
+	}
+	if *configPath == "" {
+		flags.Usage()
+		return errors.New("-config is required")
+	}
+	cfg, err := readConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	if *dataDir != "" {
+		cfg.DataDirectory = *dataDir
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	result, err := viamorbslam3.Replay(ctx, cfg, viamorbslam3.ReplayOptions{
+		ExecutableName:  *executable,
+		OutputDirectory: *outDir,
+		Timeout:         *timeout,
+	}, golog.NewLogger("orb-slam3-data"))
+	if err != nil {
+		return err
+	}
+	out, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(out))
+	return nil
+}
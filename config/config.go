@@ -2,9 +2,26 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+
 	"github.com/edaniels/golog"
+	"github.com/golang/geo/r3"
 	"github.com/pkg/errors"
+	"go.viam.com/rdk/spatialmath"
 	"go.viam.com/utils"
+
+	"github.com/viamrobotics/viam-orb-slam3/occupancy"
+	"github.com/viamrobotics/viam-orb-slam3/pcd"
+)
+
+// Policies of the map_compatibility attribute.
+const (
+	// MapCompatibilityRefuse refuses to start when the newest saved map was made with settings that are incompatible
+	// with the current ones. It is the default policy.
+	MapCompatibilityRefuse = "refuse"
+	// MapCompatibilityStartFresh starts a new map instead of loading an incompatible one.
+	MapCompatibilityStartFresh = "start_fresh"
 )
 
 // newError returns an error specific to a failure in the SLAM config.
@@ -48,14 +65,165 @@ func DetermineUseLiveData(logger golog.Logger, liveData *bool, sensors []string)
 
 // Config describes how to configure the SLAM service.
 type Config struct {
-	Sensors             []string          `json:"sensors"`
-	ConfigParams        map[string]string `json:"config_params"`
-	DataDirectory       string            `json:"data_dir"`
-	UseLiveData         *bool             `json:"use_live_data"`
-	DataRateMsec        int               `json:"data_rate_msec"`
-	MapRateSec          *int              `json:"map_rate_sec"`
-	Port                string            `json:"port"`
-	DeleteProcessedData *bool             `json:"delete_processed_data"`
+	Sensors                []string          `json:"sensors"`
+	ConfigParams           map[string]string `json:"config_params"`
+	DataDirectory          string            `json:"data_dir"`
+	UseLiveData            *bool             `json:"use_live_data"`
+	DataRateMsec           int               `json:"data_rate_msec"`
+	MapRateSec             *int              `json:"map_rate_sec"`
+	Port                   string            `json:"port"`
+	DeleteProcessedData    *bool             `json:"delete_processed_data"`
+	ExtrapolationLimitMsec int               `json:"extrapolation_limit_msec"`
+	CameraExtrinsic        *ExtrinsicConfig  `json:"camera_extrinsic"`
+	BaseFrame              string            `json:"base_frame"`
+	AxisConvention         string            `json:"axis_convention"`
+	OdometrySensor         string            `json:"odometry_sensor"`
+	GravityAlignment       string            `json:"gravity_alignment"`
+	IMUSensor              string            `json:"imu_sensor"`
+	PositionCacheTTLMsec   int               `json:"position_cache_ttl_msec"`
+	SLAMServer             *SLAMServerConfig `json:"slam_server"`
+	Transport              string            `json:"transport"`
+	PointCloudFilters      *FiltersConfig    `json:"point_cloud_filters"`
+	OccupancyGrid          *OccupancyConfig  `json:"occupancy_grid"`
+	MapCompatibility       string            `json:"map_compatibility"`
+	VocabularyPath         string            `json:"vocabulary_path"`
+	VocabularySHA256       string            `json:"vocabulary_sha256"`
+}
+
+// FiltersConfig describes the filters applied to the point cloud map, in the order crop, radius, statistical outlier
+// removal and voxel grid downsampling. Distances are in the units of the point cloud map, which are mm, or SLAM units
+// for monocular maps without odometry_sensor. Each filter is optional.
+type FiltersConfig struct {
+	Crop               *CropConfig               `json:"crop,omitempty"`
+	Radius             *RadiusConfig             `json:"radius,omitempty"`
+	StatisticalOutlier *StatisticalOutlierConfig `json:"statistical_outlier,omitempty"`
+	VoxelSize          float64                   `json:"voxel_size,omitempty"`
+}
+
+// CropConfig describes the axis aligned box points are kept within.
+type CropConfig struct {
+	Min r3.Vector `json:"min"`
+	Max r3.Vector `json:"max"`
+}
+
+// RadiusConfig describes the removal of points with fewer than min_neighbors other points within radius.
+type RadiusConfig struct {
+	Radius       float64 `json:"radius"`
+	MinNeighbors int     `json:"min_neighbors"`
+}
+
+// StatisticalOutlierConfig describes the removal of points whose mean distance to their nearest neighbors is more
+// than std_dev_multiplier standard deviations above average.
+type StatisticalOutlierConfig struct {
+	Neighbors        int     `json:"neighbors"`
+	StdDevMultiplier float64 `json:"std_dev_multiplier"`
+}
+
+// Validate checks that the filter parameters are in range.
+func (filters *FiltersConfig) Validate() error {
+	if crop := filters.Crop; crop != nil {
+		if crop.Min.X > crop.Max.X || crop.Min.Y > crop.Max.Y || crop.Min.Z > crop.Max.Z {
+			return errors.New("point_cloud_filters.crop.min must not be greater than point_cloud_filters.crop.max")
+		}
+	}
+	if radius := filters.Radius; radius != nil && (radius.Radius <= 0 || radius.MinNeighbors <= 0) {
+		return errors.New("point_cloud_filters.radius requires a radius and min_neighbors greater than zero")
+	}
+	if outlier := filters.StatisticalOutlier; outlier != nil && (outlier.Neighbors <= 0 || outlier.StdDevMultiplier <= 0) {
+		return errors.New("point_cloud_filters.statistical_outlier requires neighbors and std_dev_multiplier greater than zero")
+	}
+	if filters.VoxelSize < 0 {
+		return errors.New("cannot specify point_cloud_filters.voxel_size less than zero")
+	}
+	return nil
+}
+
+// Filters returns the configured filters in the order they are applied.
+func (filters *FiltersConfig) Filters() []pcd.Filter {
+	var result []pcd.Filter
+	if filters.Crop != nil {
+		result = append(result, pcd.Crop{Min: filters.Crop.Min, Max: filters.Crop.Max})
+	}
+	if filters.Radius != nil {
+		result = append(result, pcd.RadiusOutlier{Radius: filters.Radius.Radius, MinNeighbors: filters.Radius.MinNeighbors})
+	}
+	if filters.StatisticalOutlier != nil {
+		result = append(result, pcd.StatisticalOutlier{
+			Neighbors:        filters.StatisticalOutlier.Neighbors,
+			StdDevMultiplier: filters.StatisticalOutlier.StdDevMultiplier,
+		})
+	}
+	if filters.VoxelSize > 0 {
+		result = append(result, pcd.VoxelGrid{LeafSize: filters.VoxelSize})
+	}
+	return result
+}
+
+// OccupancyConfig describes the 2D occupancy grid built from the point cloud map. Distances are in the units of the
+// point cloud map, like those of FiltersConfig, and heights are measured up from the origin of the map.
+type OccupancyConfig struct {
+	Resolution      float64 `json:"resolution"`
+	MinHeight       float64 `json:"min_height"`
+	MaxHeight       float64 `json:"max_height"`
+	InflationRadius float64 `json:"inflation_radius"`
+}
+
+// Grid returns the parameters of the occupancy grid.
+func (cfg *OccupancyConfig) Grid() occupancy.Config {
+	return occupancy.Config{
+		Resolution:      cfg.Resolution,
+		MinHeight:       cfg.MinHeight,
+		MaxHeight:       cfg.MaxHeight,
+		InflationRadius: cfg.InflationRadius,
+	}
+}
+
+// SLAMServerConfig describes an externally managed SLAM server to connect to instead of starting one locally. The
+// server may run on another machine, so it captures its own frames and keeps its maps in its own data directory.
+// Connections use TLS unless insecure is set; the CA certificate defaults to the system roots, and a client
+// certificate and key can be given for mutual TLS. A token, if given, is sent as a bearer token with every request.
+type SLAMServerConfig struct {
+	Address    string `json:"address"`
+	CACert     string `json:"ca_cert"`
+	ClientCert string `json:"client_cert"`
+	ClientKey  string `json:"client_key"`
+	ServerName string `json:"server_name"`
+	Token      string `json:"token"`
+	Insecure   bool   `json:"insecure"`
+}
+
+// Validate checks that the SLAM server config is complete and consistent.
+func (server *SLAMServerConfig) Validate(path string) error {
+	if server.Address == "" {
+		return utils.NewConfigValidationFieldRequiredError(path, "slam_server.address")
+	}
+	if (server.ClientCert == "") != (server.ClientKey == "") {
+		return errors.New("slam_server.client_cert and slam_server.client_key must be given together")
+	}
+	if server.Insecure {
+		if server.CACert != "" || server.ClientCert != "" || server.ServerName != "" {
+			return errors.New("slam_server TLS settings cannot be given when insecure is set")
+		}
+		if server.Token != "" {
+			return errors.New("slam_server.token cannot be sent over an insecure connection")
+		}
+	}
+	return nil
+}
+
+// ExtrinsicConfig describes the pose of the camera relative to the robot base, with the translation in mm and the
+// orientation as an orientation vector in degrees.
+type ExtrinsicConfig struct {
+	Translation r3.Vector                             `json:"translation"`
+	Orientation *spatialmath.OrientationVectorDegrees `json:"orientation"`
+}
+
+// Pose returns the pose described by the extrinsic.
+func (extrinsic *ExtrinsicConfig) Pose() spatialmath.Pose {
+	if extrinsic.Orientation == nil {
+		return spatialmath.NewPoseFromPoint(extrinsic.Translation)
+	}
+	return spatialmath.NewPose(extrinsic.Translation, extrinsic.Orientation)
 }
 
 // Validate creates the list of implicit dependencies.
@@ -76,7 +244,94 @@ func (config *Config) Validate(path string) ([]string, error) {
 		return nil, errors.New("cannot specify map_rate_sec less than zero")
 	}
 
-	deps := config.Sensors
+	if config.ExtrapolationLimitMsec < 0 {
+		return nil, errors.New("cannot specify extrapolation_limit_msec less than zero")
+	}
+
+	if config.PositionCacheTTLMsec < 0 {
+		return nil, errors.New("cannot specify position_cache_ttl_msec less than zero")
+	}
+
+	if config.AxisConvention != "" && config.AxisConvention != "camera" && config.AxisConvention != "robot" {
+		return nil, errors.Errorf("axis_convention must be either camera or robot, got %v", config.AxisConvention)
+	}
+
+	// Poses in robot axes are reported in the base frame, whose pose relative to the camera must be known.
+	if config.AxisConvention == "robot" && config.CameraExtrinsic == nil {
+		return nil, errors.New("axis_convention robot requires camera_extrinsic")
+	}
+
+	// The translation of the extrinsic is in mm, while monocular maps have no known scale without odometry.
+	if config.CameraExtrinsic != nil && config.CameraExtrinsic.Translation != (r3.Vector{}) &&
+		config.ConfigParams["mode"] == "mono" && config.OdometrySensor == "" {
+		return nil, errors.New("a camera_extrinsic translation in mono mode requires odometry_sensor to recover the scale of the map")
+	}
+
+	switch config.GravityAlignment {
+	case "", "floor_plane":
+	case "imu":
+		if config.IMUSensor == "" {
+			return nil, utils.NewConfigValidationFieldRequiredError(path, "imu_sensor")
+		}
+		// The IMU measures gravity in the base frame, which the camera extrinsic rotates into the camera frame.
+		if config.CameraExtrinsic == nil {
+			return nil, errors.New("gravity_alignment imu requires camera_extrinsic, the pose of the camera relative to the imu")
+		}
+	default:
+		return nil, errors.Errorf("gravity_alignment must be either imu or floor_plane, got %v", config.GravityAlignment)
+	}
+
+	if config.SLAMServer != nil {
+		if err := config.SLAMServer.Validate(path); err != nil {
+			return nil, err
+		}
+	}
+
+	if config.PointCloudFilters != nil {
+		if err := config.PointCloudFilters.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	if config.OccupancyGrid != nil {
+		if err := config.OccupancyGrid.Grid().Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	switch config.Transport {
+	case "", "tcp":
+	case "unix":
+		if config.SLAMServer != nil {
+			return nil, errors.New("transport unix cannot be used with slam_server")
+		}
+		if config.Port != "" {
+			return nil, errors.New("port cannot be given when transport is unix")
+		}
+	default:
+		return nil, errors.Errorf("transport must be either tcp or unix, got %v", config.Transport)
+	}
+
+	switch config.MapCompatibility {
+	case "", MapCompatibilityRefuse, MapCompatibilityStartFresh:
+	default:
+		return nil, errors.Errorf("map_compatibility must be either %v or %v, got %v",
+			MapCompatibilityRefuse, MapCompatibilityStartFresh, config.MapCompatibility)
+	}
+
+	if config.VocabularySHA256 != "" {
+		if sum, err := hex.DecodeString(config.VocabularySHA256); err != nil || len(sum) != sha256.Size {
+			return nil, errors.Errorf("vocabulary_sha256 must be a hex encoded SHA-256 checksum, got %v", config.VocabularySHA256)
+		}
+	}
+
+	deps := append([]string{}, config.Sensors...)
+	if config.OdometrySensor != "" {
+		deps = append(deps, config.OdometrySensor)
+	}
+	if config.IMUSensor != "" {
+		deps = append(deps, config.IMUSensor)
+	}
 
 	return deps, nil
 }
@@ -1,13 +1,18 @@
 package config
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/edaniels/golog"
+	"github.com/golang/geo/r3"
 	"go.viam.com/rdk/resource"
 	"go.viam.com/rdk/services/slam"
 	"go.viam.com/test"
 	"go.viam.com/utils"
+
+	"github.com/viamrobotics/viam-orb-slam3/occupancy"
+	"github.com/viamrobotics/viam-orb-slam3/pcd"
 )
 
 var (
@@ -72,6 +77,215 @@ func TestValidate(t *testing.T) {
 		cfgService.Attributes["map_rate_sec"] = -1
 		_, err = newConfig(cfgService)
 		test.That(t, err, test.ShouldBeError, newError("cannot specify map_rate_sec less than zero"))
+		cfgService.Attributes["map_rate_sec"] = 1
+		cfgService.Attributes["extrapolation_limit_msec"] = -1
+		_, err = newConfig(cfgService)
+		test.That(t, err, test.ShouldBeError, newError("cannot specify extrapolation_limit_msec less than zero"))
+		cfgService.Attributes["extrapolation_limit_msec"] = 1
+		cfgService.Attributes["position_cache_ttl_msec"] = -1
+		_, err = newConfig(cfgService)
+		test.That(t, err, test.ShouldBeError, newError("cannot specify position_cache_ttl_msec less than zero"))
+		cfgService.Attributes["position_cache_ttl_msec"] = 1
+		cfgService.Attributes["axis_convention"] = "sideways"
+		_, err = newConfig(cfgService)
+		test.That(t, err, test.ShouldBeError, newError("axis_convention must be either camera or robot, got sideways"))
+		cfgService.Attributes["axis_convention"] = "robot"
+		_, err = newConfig(cfgService)
+		test.That(t, err, test.ShouldBeError, newError("axis_convention robot requires camera_extrinsic"))
+		cfgService.Attributes["axis_convention"] = "camera"
+		cfgService.Attributes["gravity_alignment"] = "magic"
+		_, err = newConfig(cfgService)
+		test.That(t, err, test.ShouldBeError, newError("gravity_alignment must be either imu or floor_plane, got magic"))
+		cfgService.Attributes["gravity_alignment"] = "imu"
+		_, err = newConfig(cfgService)
+		test.That(t, err, test.ShouldBeError, newError(utils.NewConfigValidationFieldRequiredError(testCfgPath, "imu_sensor").Error()))
+		cfgService.Attributes["imu_sensor"] = "imu"
+		_, err = newConfig(cfgService)
+		test.That(t, err, test.ShouldBeError,
+			newError("gravity_alignment imu requires camera_extrinsic, the pose of the camera relative to the imu"))
+	})
+
+	t.Run("Config with camera extrinsic", func(t *testing.T) {
+		cfgService := makeCfgService()
+		cfgService.Attributes["axis_convention"] = "robot"
+		cfgService.Attributes["base_frame"] = "my_base"
+		cfgService.Attributes["camera_extrinsic"] = map[string]interface{}{
+			"translation": map[string]interface{}{"x": 50, "y": 0, "z": 200},
+			"orientation": map[string]interface{}{"x": 0, "y": 0, "z": 1, "th": 90},
+		}
+		cfg, err := newConfig(cfgService)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, cfg.AxisConvention, test.ShouldEqual, "robot")
+		test.That(t, cfg.BaseFrame, test.ShouldEqual, "my_base")
+		pose := cfg.CameraExtrinsic.Pose()
+		test.That(t, pose.Point(), test.ShouldResemble, r3.Vector{X: 50, Z: 200})
+		test.That(t, pose.Orientation().OrientationVectorDegrees().Theta, test.ShouldAlmostEqual, 90)
+
+		// Monocular maps have no known scale to apply a translation in mm to without odometry.
+		cfgService.Attributes["config_params"] = map[string]string{"mode": "mono"}
+		_, err = newConfig(cfgService)
+		test.That(t, err, test.ShouldBeError,
+			newError("a camera_extrinsic translation in mono mode requires odometry_sensor to recover the scale of the map"))
+		cfgService.Attributes["odometry_sensor"] = "odometry"
+		_, err = newConfig(cfgService)
+		test.That(t, err, test.ShouldBeNil)
+	})
+
+	t.Run("Config with odometry and imu sensors", func(t *testing.T) {
+		cfgService := makeCfgService()
+		cfgService.Attributes["sensors"] = []string{"color"}
+		cfgService.Attributes["odometry_sensor"] = "odometry"
+		cfgService.Attributes["gravity_alignment"] = "imu"
+		cfgService.Attributes["imu_sensor"] = "imu"
+		cfgService.Attributes["camera_extrinsic"] = map[string]interface{}{
+			"translation": map[string]interface{}{"x": 0, "y": 0, "z": 200},
+		}
+		cfg, err := resource.TransformAttributeMap[*Config](cfgService.Attributes)
+		test.That(t, err, test.ShouldBeNil)
+		deps, err := cfg.Validate(testCfgPath)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, deps, test.ShouldResemble, []string{"color", "odometry", "imu"})
+	})
+
+	t.Run("Config with slam server", func(t *testing.T) {
+		cfgService := makeCfgService()
+		cfgService.Attributes["slam_server"] = map[string]interface{}{}
+		_, err := newConfig(cfgService)
+		test.That(t, err, test.ShouldBeError,
+			newError(utils.NewConfigValidationFieldRequiredError(testCfgPath, "slam_server.address").Error()))
+
+		cfgService.Attributes["slam_server"] = map[string]interface{}{"address": "slam.local:8080", "client_cert": "cert.pem"}
+		_, err = newConfig(cfgService)
+		test.That(t, err, test.ShouldBeError, newError("slam_server.client_cert and slam_server.client_key must be given together"))
+
+		cfgService.Attributes["slam_server"] = map[string]interface{}{"address": "slam.local:8080", "insecure": true, "ca_cert": "ca.pem"}
+		_, err = newConfig(cfgService)
+		test.That(t, err, test.ShouldBeError, newError("slam_server TLS settings cannot be given when insecure is set"))
+
+		cfgService.Attributes["slam_server"] = map[string]interface{}{"address": "slam.local:8080", "insecure": true, "token": "secret"}
+		_, err = newConfig(cfgService)
+		test.That(t, err, test.ShouldBeError, newError("slam_server.token cannot be sent over an insecure connection"))
+
+		cfgService.Attributes["slam_server"] = map[string]interface{}{
+			"address":     "slam.local:8080",
+			"ca_cert":     "ca.pem",
+			"client_cert": "cert.pem",
+			"client_key":  "key.pem",
+			"token":       "secret",
+		}
+		cfg, err := newConfig(cfgService)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, cfg.SLAMServer.Address, test.ShouldEqual, "slam.local:8080")
+		test.That(t, cfg.SLAMServer.Token, test.ShouldEqual, "secret")
+	})
+
+	t.Run("Config with transport", func(t *testing.T) {
+		cfgService := makeCfgService()
+		cfgService.Attributes["transport"] = "pigeon"
+		_, err := newConfig(cfgService)
+		test.That(t, err, test.ShouldBeError, newError("transport must be either tcp or unix, got pigeon"))
+
+		cfgService.Attributes["transport"] = "unix"
+		cfgService.Attributes["port"] = "localhost:4000"
+		_, err = newConfig(cfgService)
+		test.That(t, err, test.ShouldBeError, newError("port cannot be given when transport is unix"))
+
+		delete(cfgService.Attributes, "port")
+		cfgService.Attributes["slam_server"] = map[string]interface{}{"address": "slam.local:8080"}
+		_, err = newConfig(cfgService)
+		test.That(t, err, test.ShouldBeError, newError("transport unix cannot be used with slam_server"))
+
+		delete(cfgService.Attributes, "slam_server")
+		cfg, err := newConfig(cfgService)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, cfg.Transport, test.ShouldEqual, "unix")
+	})
+
+	t.Run("Config with point cloud filters", func(t *testing.T) {
+		cfgService := makeCfgService()
+		cfgService.Attributes["point_cloud_filters"] = map[string]interface{}{"voxel_size": -1}
+		_, err := newConfig(cfgService)
+		test.That(t, err, test.ShouldBeError, newError("cannot specify point_cloud_filters.voxel_size less than zero"))
+
+		cfgService.Attributes["point_cloud_filters"] = map[string]interface{}{
+			"crop": map[string]interface{}{"min": map[string]interface{}{"z": 1}, "max": map[string]interface{}{"z": 0}},
+		}
+		_, err = newConfig(cfgService)
+		test.That(t, err, test.ShouldBeError,
+			newError("point_cloud_filters.crop.min must not be greater than point_cloud_filters.crop.max"))
+
+		cfgService.Attributes["point_cloud_filters"] = map[string]interface{}{
+			"statistical_outlier": map[string]interface{}{"neighbors": 8},
+		}
+		_, err = newConfig(cfgService)
+		test.That(t, err, test.ShouldBeError,
+			newError("point_cloud_filters.statistical_outlier requires neighbors and std_dev_multiplier greater than zero"))
+
+		cfgService.Attributes["point_cloud_filters"] = map[string]interface{}{
+			"crop":                map[string]interface{}{"min": map[string]interface{}{"z": -1}, "max": map[string]interface{}{"z": 2}},
+			"radius":              map[string]interface{}{"radius": 0.1, "min_neighbors": 3},
+			"statistical_outlier": map[string]interface{}{"neighbors": 8, "std_dev_multiplier": 2},
+			"voxel_size":          0.05,
+		}
+		cfg, err := newConfig(cfgService)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, cfg.PointCloudFilters.Filters(), test.ShouldResemble, []pcd.Filter{
+			pcd.Crop{Min: r3.Vector{Z: -1}, Max: r3.Vector{Z: 2}},
+			pcd.RadiusOutlier{Radius: 0.1, MinNeighbors: 3},
+			pcd.StatisticalOutlier{Neighbors: 8, StdDevMultiplier: 2},
+			pcd.VoxelGrid{LeafSize: 0.05},
+		})
+	})
+
+	t.Run("Config with a map compatibility policy", func(t *testing.T) {
+		cfgService := makeCfgService()
+		cfgService.Attributes["map_compatibility"] = "ignore"
+		_, err := newConfig(cfgService)
+		test.That(t, err, test.ShouldBeError, newError("map_compatibility must be either refuse or start_fresh, got ignore"))
+
+		cfgService.Attributes["map_compatibility"] = "start_fresh"
+		cfg, err := newConfig(cfgService)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, cfg.MapCompatibility, test.ShouldEqual, "start_fresh")
+	})
+
+	t.Run("Config with a vocabulary checksum", func(t *testing.T) {
+		cfgService := makeCfgService()
+		cfgService.Attributes["vocabulary_sha256"] = "abc"
+		_, err := newConfig(cfgService)
+		test.That(t, err, test.ShouldBeError,
+			newError("vocabulary_sha256 must be a hex encoded SHA-256 checksum, got abc"))
+
+		checksum := strings.Repeat("0f", 32)
+		cfgService.Attributes["vocabulary_path"] = "/opt/ORBvoc.txt"
+		cfgService.Attributes["vocabulary_sha256"] = checksum
+		cfg, err := newConfig(cfgService)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, cfg.VocabularyPath, test.ShouldEqual, "/opt/ORBvoc.txt")
+		test.That(t, cfg.VocabularySHA256, test.ShouldEqual, checksum)
+	})
+
+	t.Run("Config with an occupancy grid", func(t *testing.T) {
+		cfgService := makeCfgService()
+		cfgService.Attributes["occupancy_grid"] = map[string]interface{}{"max_height": 1}
+		_, err := newConfig(cfgService)
+		test.That(t, err, test.ShouldBeError, newError("occupancy grid resolution must be greater than zero"))
+
+		cfgService.Attributes["occupancy_grid"] = map[string]interface{}{"resolution": 0.05, "min_height": 1}
+		_, err = newConfig(cfgService)
+		test.That(t, err, test.ShouldBeError, newError("occupancy grid min_height must not be greater than max_height"))
+
+		cfgService.Attributes["occupancy_grid"] = map[string]interface{}{
+			"resolution":       0.05,
+			"min_height":       -0.5,
+			"max_height":       1.5,
+			"inflation_radius": 0.2,
+		}
+		cfg, err := newConfig(cfgService)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, cfg.OccupancyGrid.Grid(), test.ShouldResemble, occupancy.Config{
+			Resolution: 0.05, MinHeight: -0.5, MaxHeight: 1.5, InflationRadius: 0.2,
+		})
 	})
 
 	t.Run("All parameters e2e", func(t *testing.T) {
@@ -2,22 +2,18 @@ package config
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"os"
 	"path/filepath"
-	"time"
 
 	"github.com/edaniels/golog"
 	"github.com/pkg/errors"
-	"go.opencensus.io/trace"
-	pb "go.viam.com/api/service/slam/v1"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 )
 
-// This increases the message size from 4MB to 32MB to match the RDK.
-// This is necessary for transmitting large pointclouds.
-var grpcMaxMessageSize = 32 * 1024 * 1024
-
 // SetupDirectories creates the data directory at the specified path along with
 // its data, map, and config subdirectories.
 func SetupDirectories(dataDirectory string, logger golog.Logger) error {
@@ -34,23 +30,51 @@ func SetupDirectories(dataDirectory string, logger golog.Logger) error {
 	return nil
 }
 
-// SetupGRPCConnection uses the defined port to create a GRPC client for communicating with the SLAM algorithms.
-func SetupGRPCConnection(
-	ctx context.Context,
-	port string,
-	dialMaxTimeoutSec int,
-	logger golog.Logger,
-) (pb.SLAMServiceClient, func() error, error) {
-	ctx, span := trace.StartSpan(ctx, "slam::SetupGRPCConnection")
-	defer span.End()
-	ctx, timeoutCancel := context.WithTimeout(ctx, time.Duration(dialMaxTimeoutSec)*time.Second)
-	defer timeoutCancel()
-	maxMsgSizeOption := grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(grpcMaxMessageSize))
-	// TODO: If we support running SLAM in the cloud, we need to pass credentials to this function
-	connLib, err := grpc.DialContext(ctx, port, grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithBlock(), maxMsgSizeOption)
-	if err != nil {
-		logger.Errorw("error connecting to slam process", "error", err)
-		return nil, nil, err
+// DialOptions returns the gRPC dial options carrying the transport and per request credentials needed to
+// connect to the SLAM server.
+func (server *SLAMServerConfig) DialOptions() ([]grpc.DialOption, error) {
+	if server.Insecure {
+		return []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}, nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: server.ServerName,
+	}
+	if server.CACert != "" {
+		//nolint:gosec
+		caPEM, err := os.ReadFile(server.CACert)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to read slam_server.ca_cert")
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, errors.Errorf("no certificates found in slam_server.ca_cert %v", server.CACert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if server.ClientCert != "" {
+		cert, err := tls.LoadX509KeyPair(server.ClientCert, server.ClientKey)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to load slam_server client certificate")
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
 	}
-	return pb.NewSLAMServiceClient(connLib), connLib.Close, err
+
+	opts := []grpc.DialOption{grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig))}
+	if server.Token != "" {
+		opts = append(opts, grpc.WithPerRPCCredentials(tokenCredentials(server.Token)))
+	}
+	return opts, nil
+}
+
+// tokenCredentials sends a bearer token with every request. It refuses to be used over an insecure connection.
+type tokenCredentials string
+
+func (token tokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(token)}, nil
+}
+
+func (token tokenCredentials) RequireTransportSecurity() bool {
+	return true
 }
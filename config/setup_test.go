@@ -2,43 +2,32 @@ package config
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
 	"fmt"
+	"math/big"
 	"net"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/edaniels/golog"
 	"github.com/pkg/errors"
+	pb "go.viam.com/api/service/slam/v1"
 	"go.viam.com/test"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
-func setupTestGRPCServer(tb testing.TB) (*grpc.Server, int) {
-	listener, err := net.Listen("tcp", ":0")
-	test.That(tb, err, test.ShouldBeNil)
-	grpcServer := grpc.NewServer()
-	go grpcServer.Serve(listener)
-
-	return grpcServer, listener.Addr().(*net.TCPAddr).Port
-}
-
-func TestGRPCConnection(t *testing.T) {
-	logger := golog.NewTestLogger(t)
-	t.Run("Invalid grpc connection", func(t *testing.T) {
-		port := "invalid_unused_port:0"
-		_, _, err := SetupGRPCConnection(context.Background(), port, 1, logger)
-		test.That(t, err, test.ShouldBeError, errors.New("context deadline exceeded"))
-	})
-	t.Run("Valid grpc connection", func(t *testing.T) {
-		// Setup grpc server and attempt to connect to that one
-		grpcServer, portNum := setupTestGRPCServer(t)
-		defer grpcServer.Stop()
-		port := fmt.Sprintf(":%d", portNum)
-		_, _, err := SetupGRPCConnection(context.Background(), port, 1, logger)
-		test.That(t, err, test.ShouldBeNil)
-	})
-}
-
 func TestSetupDirectories(t *testing.T) {
 	logger := golog.NewTestLogger(t)
 	t.Run("Valid directories", func(t *testing.T) {
@@ -72,3 +61,108 @@ func TestSetupDirectories(t *testing.T) {
 		test.That(t, fmt.Sprint(err), test.ShouldContainSubstring, "issue creating directory at")
 	})
 }
+
+// writeTestCertificate writes a self signed certificate for localhost and its key to dir.
+func writeTestCertificate(tb testing.TB, dir string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	test.That(tb, err, test.ShouldBeNil)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "localhost"},
+		DNSNames:              []string{"localhost"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	test.That(tb, err, test.ShouldBeNil)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	test.That(tb, err, test.ShouldBeNil)
+
+	certPath := filepath.Join(dir, "cert.pem")
+	keyPath := filepath.Join(dir, "key.pem")
+	test.That(tb, os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600), test.ShouldBeNil)
+	test.That(tb, os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600), test.ShouldBeNil)
+	return certPath, keyPath
+}
+
+func TestSLAMServerDialOptions(t *testing.T) {
+	t.Run("Insecure connection", func(t *testing.T) {
+		server := &SLAMServerConfig{Address: "localhost:8080", Insecure: true}
+		opts, err := server.DialOptions()
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, opts, test.ShouldHaveLength, 1)
+	})
+
+	t.Run("Missing CA certificate", func(t *testing.T) {
+		server := &SLAMServerConfig{Address: "localhost:8080", CACert: filepath.Join(t.TempDir(), "missing.pem")}
+		_, err := server.DialOptions()
+		test.That(t, err, test.ShouldNotBeNil)
+		test.That(t, err.Error(), test.ShouldContainSubstring, "unable to read slam_server.ca_cert")
+	})
+
+	t.Run("CA certificate without certificates", func(t *testing.T) {
+		caPath := filepath.Join(t.TempDir(), "ca.pem")
+		test.That(t, os.WriteFile(caPath, []byte("not a certificate"), 0o600), test.ShouldBeNil)
+		server := &SLAMServerConfig{Address: "localhost:8080", CACert: caPath}
+		_, err := server.DialOptions()
+		test.That(t, err, test.ShouldBeError, errors.Errorf("no certificates found in slam_server.ca_cert %v", caPath))
+	})
+
+	t.Run("Mutual TLS with a token", func(t *testing.T) {
+		dir := t.TempDir()
+		certPath, keyPath := writeTestCertificate(t, dir)
+		cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+		test.That(t, err, test.ShouldBeNil)
+		caPEM, err := os.ReadFile(certPath)
+		test.That(t, err, test.ShouldBeNil)
+		pool := x509.NewCertPool()
+		test.That(t, pool.AppendCertsFromPEM(caPEM), test.ShouldBeTrue)
+
+		// The server requires a client certificate signed by the same self signed certificate and the token.
+		var authorization []string
+		grpcServer := grpc.NewServer(
+			grpc.Creds(credentials.NewTLS(&tls.Config{
+				Certificates: []tls.Certificate{cert},
+				ClientCAs:    pool,
+				ClientAuth:   tls.RequireAndVerifyClientCert,
+				MinVersion:   tls.VersionTLS12,
+			})),
+			grpc.UnaryInterceptor(func(
+				ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler,
+			) (interface{}, error) {
+				md, _ := metadata.FromIncomingContext(ctx)
+				authorization = md.Get("authorization")
+				return handler(ctx, req)
+			}),
+		)
+		pb.RegisterSLAMServiceServer(grpcServer, &pb.UnimplementedSLAMServiceServer{})
+		listener, err := net.Listen("tcp", "localhost:0")
+		test.That(t, err, test.ShouldBeNil)
+		go grpcServer.Serve(listener)
+		defer grpcServer.Stop()
+
+		server := &SLAMServerConfig{
+			Address:    listener.Addr().String(),
+			CACert:     certPath,
+			ClientCert: certPath,
+			ClientKey:  keyPath,
+			ServerName: "localhost",
+			Token:      "secret",
+		}
+		opts, err := server.DialOptions()
+		test.That(t, err, test.ShouldBeNil)
+		conn, err := grpc.Dial(server.Address, opts...)
+		test.That(t, err, test.ShouldBeNil)
+		defer conn.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_, err = pb.NewSLAMServiceClient(conn).GetPosition(ctx, &pb.GetPositionRequest{})
+		test.That(t, status.Code(err), test.ShouldEqual, codes.Unimplemented)
+		test.That(t, authorization, test.ShouldResemble, []string{"Bearer secret"})
+	})
+}
@@ -0,0 +1,221 @@
+// Package datadir inspects and maintains the data directory of the SLAM service, which holds the frames captured from
+// the cameras, the maps saved by the SLAM algorithm and its configuration.
+package datadir
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/viamrobotics/viam-orb-slam3/dataprocess"
+	"github.com/viamrobotics/viam-orb-slam3/mapmeta"
+)
+
+// Folders of the data directory.
+const (
+	MapDirectory    = "map"
+	ConfigDirectory = "config"
+	RGBDirectory    = "data/rgb"
+	DepthDirectory  = "data/depth"
+)
+
+const (
+	// frameExtension is the extension of the frames captured from the cameras.
+	frameExtension = ".png"
+	// configExtension is the extension of the ORB_SLAM3 configs generated by the SLAM service.
+	configExtension = ".yaml"
+)
+
+// Map is a map saved in the map folder.
+type Map struct {
+	Path      string
+	Timestamp time.Time
+	// Metadata is read from the sidecar of the map, or nil if it has none or it cannot be read, in which case
+	// MetadataErr is set.
+	Metadata    *mapmeta.Metadata
+	MetadataErr error
+}
+
+// Frame is a frame captured from a camera.
+type Frame struct {
+	Path      string
+	Sensor    string
+	Timestamp time.Time
+}
+
+// ListMaps returns the maps in the map folder of the data directory and its subfolders whose names hold a timestamp,
+// oldest first.
+func ListMaps(dataDirectory string) ([]Map, error) {
+	var maps []Map
+	err := walkTimestampFiles(filepath.Join(dataDirectory, MapDirectory), mapmeta.MapExtension, true,
+		func(path, _ string, timestamp time.Time) {
+			m := Map{Path: path, Timestamp: timestamp}
+			metadata, err := mapmeta.Read(path)
+			switch {
+			case err == nil:
+				m.Metadata = &metadata
+			case !os.IsNotExist(err):
+				m.MetadataErr = err
+			}
+			maps = append(maps, m)
+		})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(maps, func(i, j int) bool { return maps[i].Timestamp.Before(maps[j].Timestamp) })
+	return maps, nil
+}
+
+// ListFrames returns the frames in the given folder of the data directory, RGBDirectory or DepthDirectory, oldest
+// first.
+func ListFrames(dataDirectory, folder string) ([]Frame, error) {
+	var frames []Frame
+	err := walkTimestampFiles(filepath.Join(dataDirectory, folder), frameExtension, false,
+		func(path, sensor string, timestamp time.Time) {
+			frames = append(frames, Frame{Path: path, Sensor: sensor, Timestamp: timestamp})
+		})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(frames, func(i, j int) bool { return frames[i].Timestamp.Before(frames[j].Timestamp) })
+	return frames, nil
+}
+
+// LatestConfig returns the path of the newest ORB_SLAM3 config the SLAM service generated in the config folder of the
+// data directory, or an empty path if there is none.
+func LatestConfig(dataDirectory string) (string, error) {
+	var latestPath string
+	var latest time.Time
+	err := walkTimestampFiles(filepath.Join(dataDirectory, ConfigDirectory), configExtension, false,
+		func(path, _ string, timestamp time.Time) {
+			if latestPath == "" || timestamp.After(latest) {
+				latestPath, latest = path, timestamp
+			}
+		})
+	return latestPath, err
+}
+
+// walkTimestampFiles calls fn for every file with the extension in the folder, and its subfolders if recursive is set,
+// whose name holds a timestamp. A folder that does not exist has no files.
+func walkTimestampFiles(folder, extension string, recursive bool, fn func(path, sensor string, timestamp time.Time)) error {
+	if _, err := os.Stat(folder); os.IsNotExist(err) {
+		return nil
+	}
+	return filepath.WalkDir(folder, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if path != folder && !recursive {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(entry.Name()) != extension {
+			return nil
+		}
+		sensor, timestamp, err := dataprocess.ParseTimestampFilename(entry.Name())
+		if err != nil {
+			return nil
+		}
+		fn(path, sensor, timestamp)
+		return nil
+	})
+}
+
+// Pairing is the result of matching the RGB frames of a data directory with its depth frames, which share their
+// names.
+type Pairing struct {
+	Pairs int
+	// MissingDepth are the RGB frames without a depth frame, and MissingRGB the depth frames without an RGB frame.
+	MissingDepth []string
+	MissingRGB   []string
+}
+
+// CheckPairing matches the RGB frames of the data directory with its depth frames.
+func CheckPairing(dataDirectory string) (Pairing, error) {
+	rgb, err := ListFrames(dataDirectory, RGBDirectory)
+	if err != nil {
+		return Pairing{}, err
+	}
+	depth, err := ListFrames(dataDirectory, DepthDirectory)
+	if err != nil {
+		return Pairing{}, err
+	}
+	depthNames := make(map[string]bool, len(depth))
+	for _, frame := range depth {
+		depthNames[filepath.Base(frame.Path)] = true
+	}
+	var pairing Pairing
+	for _, frame := range rgb {
+		name := filepath.Base(frame.Path)
+		if depthNames[name] {
+			pairing.Pairs++
+			delete(depthNames, name)
+			continue
+		}
+		pairing.MissingDepth = append(pairing.MissingDepth, frame.Path)
+	}
+	for _, frame := range depth {
+		if depthNames[filepath.Base(frame.Path)] {
+			pairing.MissingRGB = append(pairing.MissingRGB, frame.Path)
+		}
+	}
+	return pairing, nil
+}
+
+// PruneOptions selects the data to prune.
+type PruneOptions struct {
+	// FramesBefore removes the frames captured before it, unless it is zero.
+	FramesBefore time.Time
+	// KeepMaps removes all but the given number of newest maps, along with their sidecars, unless it is zero.
+	KeepMaps int
+	// DryRun reports the files that would be removed without removing them.
+	DryRun bool
+}
+
+// Prune removes old frames and maps from the data directory, and returns the paths of the removed files.
+func Prune(dataDirectory string, opts PruneOptions) ([]string, error) {
+	if opts.KeepMaps < 0 {
+		return nil, errors.New("the number of maps to keep must not be negative")
+	}
+	var paths []string
+	if !opts.FramesBefore.IsZero() {
+		for _, folder := range []string{RGBDirectory, DepthDirectory} {
+			frames, err := ListFrames(dataDirectory, folder)
+			if err != nil {
+				return nil, err
+			}
+			for _, frame := range frames {
+				if frame.Timestamp.Before(opts.FramesBefore) {
+					paths = append(paths, frame.Path)
+				}
+			}
+		}
+	}
+	if opts.KeepMaps > 0 {
+		maps, err := ListMaps(dataDirectory)
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < len(maps)-opts.KeepMaps; i++ {
+			paths = append(paths, maps[i].Path)
+			if _, err := os.Stat(mapmeta.SidecarPath(maps[i].Path)); err == nil {
+				paths = append(paths, mapmeta.SidecarPath(maps[i].Path))
+			}
+		}
+	}
+	if opts.DryRun {
+		return paths, nil
+	}
+	for i, path := range paths {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return paths[:i], errors.Wrapf(err, "unable to remove %v", path)
+		}
+	}
+	return paths, nil
+}
@@ -0,0 +1,159 @@
+package datadir
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.viam.com/test"
+
+	"github.com/viamrobotics/viam-orb-slam3/dataprocess"
+	"github.com/viamrobotics/viam-orb-slam3/mapmeta"
+)
+
+var (
+	day1 = time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+	day2 = day1.Add(24 * time.Hour)
+	day3 = day2.Add(24 * time.Hour)
+)
+
+// createDataDirectory returns a data directory with two maps, of which the newest has a sidecar, RGB frames on all
+// three days and depth frames on the last two days.
+func createDataDirectory(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, folder := range []string{MapDirectory, ConfigDirectory, RGBDirectory, DepthDirectory} {
+		test.That(t, os.MkdirAll(filepath.Join(dir, folder), 0o755), test.ShouldBeNil)
+	}
+	files := []string{
+		dataprocess.CreateTimestampFilename(filepath.Join(dir, MapDirectory), "cam", mapmeta.MapExtension, day2),
+		dataprocess.CreateTimestampFilename(filepath.Join(dir, MapDirectory), "cam", mapmeta.MapExtension, day1),
+		filepath.Join(dir, MapDirectory, "imported.osa"),
+		filepath.Join(dir, RGBDirectory, "notes.txt"),
+	}
+	for _, timestamp := range []time.Time{day1, day2, day3} {
+		files = append(files, dataprocess.CreateTimestampFilename(filepath.Join(dir, RGBDirectory), "cam", ".png", timestamp))
+	}
+	for _, timestamp := range []time.Time{day2, day3} {
+		files = append(files, dataprocess.CreateTimestampFilename(filepath.Join(dir, DepthDirectory), "cam", ".png", timestamp))
+	}
+	files = append(files, dataprocess.CreateTimestampFilename(filepath.Join(dir, DepthDirectory), "cam", ".png", day3.Add(time.Second)))
+	for _, path := range files {
+		test.That(t, os.WriteFile(path, nil, 0o644), test.ShouldBeNil)
+	}
+	test.That(t, mapmeta.Write(files[0], mapmeta.Metadata{Mode: "rgbd", KeyFrames: 3}), test.ShouldBeNil)
+	return dir
+}
+
+func TestList(t *testing.T) {
+	dir := createDataDirectory(t)
+
+	t.Run("List maps", func(t *testing.T) {
+		maps, err := ListMaps(dir)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, maps, test.ShouldHaveLength, 2)
+		test.That(t, maps[0].Timestamp, test.ShouldEqual, day1)
+		test.That(t, maps[0].Metadata, test.ShouldBeNil)
+		test.That(t, maps[0].MetadataErr, test.ShouldBeNil)
+		test.That(t, maps[1].Timestamp, test.ShouldEqual, day2)
+		test.That(t, maps[1].Metadata.KeyFrames, test.ShouldEqual, 3)
+	})
+
+	t.Run("List maps in subfolders", func(t *testing.T) {
+		dir := createDataDirectory(t)
+		nested := filepath.Join(dir, MapDirectory, "backup")
+		test.That(t, os.MkdirAll(nested, 0o755), test.ShouldBeNil)
+		path := dataprocess.CreateTimestampFilename(nested, "cam_data_left", mapmeta.MapExtension, day3)
+		test.That(t, os.WriteFile(path, nil, 0o644), test.ShouldBeNil)
+
+		maps, err := ListMaps(dir)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, maps, test.ShouldHaveLength, 3)
+		test.That(t, maps[2].Path, test.ShouldEqual, path)
+		test.That(t, maps[2].Timestamp, test.ShouldEqual, day3)
+	})
+
+	t.Run("List frames", func(t *testing.T) {
+		frames, err := ListFrames(dir, RGBDirectory)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, frames, test.ShouldHaveLength, 3)
+		test.That(t, frames[0].Sensor, test.ShouldEqual, "cam")
+		test.That(t, frames[0].Timestamp, test.ShouldEqual, day1)
+
+		frames, err = ListFrames(t.TempDir(), RGBDirectory)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, frames, test.ShouldBeEmpty)
+	})
+
+	t.Run("Find the latest config", func(t *testing.T) {
+		path, err := LatestConfig(dir)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, path, test.ShouldBeEmpty)
+
+		for _, timestamp := range []time.Time{day2, day1} {
+			path := dataprocess.CreateTimestampFilename(filepath.Join(dir, ConfigDirectory), "cam", ".yaml", timestamp)
+			test.That(t, os.WriteFile(path, nil, 0o644), test.ShouldBeNil)
+		}
+		path, err = LatestConfig(dir)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, path, test.ShouldEqual,
+			dataprocess.CreateTimestampFilename(filepath.Join(dir, ConfigDirectory), "cam", ".yaml", day2))
+	})
+
+	t.Run("Check pairing", func(t *testing.T) {
+		pairing, err := CheckPairing(dir)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, pairing.Pairs, test.ShouldEqual, 2)
+		test.That(t, pairing.MissingDepth, test.ShouldResemble, []string{
+			dataprocess.CreateTimestampFilename(filepath.Join(dir, RGBDirectory), "cam", ".png", day1),
+		})
+		test.That(t, pairing.MissingRGB, test.ShouldResemble, []string{
+			dataprocess.CreateTimestampFilename(filepath.Join(dir, DepthDirectory), "cam", ".png", day3.Add(time.Second)),
+		})
+	})
+}
+
+func TestPrune(t *testing.T) {
+	t.Run("Dry run", func(t *testing.T) {
+		dir := createDataDirectory(t)
+		removed, err := Prune(dir, PruneOptions{FramesBefore: day3, KeepMaps: 1, DryRun: true})
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, removed, test.ShouldHaveLength, 4)
+		for _, path := range removed {
+			_, err := os.Stat(path)
+			test.That(t, err, test.ShouldBeNil)
+		}
+	})
+
+	t.Run("Prune frames and maps", func(t *testing.T) {
+		dir := createDataDirectory(t)
+		removed, err := Prune(dir, PruneOptions{FramesBefore: day3, KeepMaps: 1})
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, removed, test.ShouldResemble, []string{
+			dataprocess.CreateTimestampFilename(filepath.Join(dir, RGBDirectory), "cam", ".png", day1),
+			dataprocess.CreateTimestampFilename(filepath.Join(dir, RGBDirectory), "cam", ".png", day2),
+			dataprocess.CreateTimestampFilename(filepath.Join(dir, DepthDirectory), "cam", ".png", day2),
+			dataprocess.CreateTimestampFilename(filepath.Join(dir, MapDirectory), "cam", mapmeta.MapExtension, day1),
+		})
+		maps, err := ListMaps(dir)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, maps, test.ShouldHaveLength, 1)
+		test.That(t, maps[0].Metadata, test.ShouldNotBeNil)
+
+		_, err = Prune(dir, PruneOptions{KeepMaps: -1})
+		test.That(t, err.Error(), test.ShouldContainSubstring, "must not be negative")
+	})
+
+	t.Run("Prune maps with their sidecars", func(t *testing.T) {
+		dir := createDataDirectory(t)
+		newest := dataprocess.CreateTimestampFilename(filepath.Join(dir, MapDirectory), "cam", mapmeta.MapExtension, day3)
+		test.That(t, os.WriteFile(newest, nil, 0o644), test.ShouldBeNil)
+		removed, err := Prune(dir, PruneOptions{KeepMaps: 1})
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, removed, test.ShouldHaveLength, 3)
+		_, err = os.Stat(mapmeta.SidecarPath(
+			dataprocess.CreateTimestampFilename(filepath.Join(dir, MapDirectory), "cam", mapmeta.MapExtension, day2)))
+		test.That(t, os.IsNotExist(err), test.ShouldBeTrue)
+	})
+}
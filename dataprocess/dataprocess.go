@@ -5,18 +5,40 @@ import (
 	"bufio"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
+
+	"github.com/pkg/errors"
 )
 
 const (
 	// SlamTimeFormat is the timestamp format used in the dataprocess.
 	SlamTimeFormat = "2006-01-02T15:04:05.0000Z"
+	// TimestampSeparator separates the sensor name from the timestamp in filenames.
+	TimestampSeparator = "_data_"
 )
 
 // CreateTimestampFilename creates an absolute filename with a primary sensor name and timestamp written
 // into the filename.
 func CreateTimestampFilename(dataDirectory, primarySensorName, fileType string, timeStamp time.Time) string {
-	return filepath.Join(dataDirectory, primarySensorName+"_data_"+timeStamp.UTC().Format(SlamTimeFormat)+fileType)
+	return filepath.Join(dataDirectory, primarySensorName+TimestampSeparator+timeStamp.UTC().Format(SlamTimeFormat)+fileType)
+}
+
+// ParseTimestampFilename returns the sensor name and timestamp written into a filename by CreateTimestampFilename.
+// The directory and extension of the filename are ignored. The timestamp follows the last TimestampSeparator, so that
+// sensor names may contain the separator.
+func ParseTimestampFilename(filename string) (string, time.Time, error) {
+	name := filepath.Base(filename)
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	i := strings.LastIndex(name, TimestampSeparator)
+	if i == -1 {
+		return "", time.Time{}, errors.Errorf("filename %v does not contain a timestamp", filename)
+	}
+	timeStamp, err := time.Parse(SlamTimeFormat, name[i+len(TimestampSeparator):])
+	if err != nil {
+		return "", time.Time{}, errors.Wrapf(err, "filename %v does not contain a valid timestamp", filename)
+	}
+	return name[:i], timeStamp, nil
 }
 
 // WriteBytesToFile writes the passed bytes to the passed filename.
@@ -21,6 +21,23 @@ func TestCreateTimestampFilename(t *testing.T) {
 	})
 }
 
+func TestParseTimestampFilename(t *testing.T) {
+	t.Run("Parse a filename with a timestamp", func(t *testing.T) {
+		timeStamp := time.Date(1955, time.March, 13, 1, 10, 30, 0, time.UTC)
+		sensor, parsed, err := ParseTimestampFilename(CreateTimestampFilename("/Users/whoami/slam", "my_data_cam", ".png", timeStamp))
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, sensor, test.ShouldEqual, "my_data_cam")
+		test.That(t, parsed, test.ShouldEqual, timeStamp)
+	})
+
+	t.Run("Parse a filename without a timestamp", func(t *testing.T) {
+		_, _, err := ParseTimestampFilename("/Users/whoami/slam/ORBvoc.txt")
+		test.That(t, err.Error(), test.ShouldContainSubstring, "does not contain a timestamp")
+		_, _, err = ParseTimestampFilename("myCamera_data_yesterday.png")
+		test.That(t, err.Error(), test.ShouldContainSubstring, "does not contain a valid timestamp")
+	})
+}
+
 func TestWriteBytesToFile(t *testing.T) {
 	t.Run("Write bytes to file", func(t *testing.T) {
 		tempDir, err := os.MkdirTemp("", "*")
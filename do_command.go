@@ -0,0 +1,231 @@
+package viamorbslam3
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"github.com/golang/geo/r3"
+	"github.com/pkg/errors"
+	"go.opencensus.io/trace"
+	"go.viam.com/rdk/resource"
+	"go.viam.com/rdk/spatialmath"
+
+	orbSlamConfig "github.com/viamrobotics/viam-orb-slam3/config"
+)
+
+const (
+	// GetStatsCommand is the DoCommand key used to request the runtime statistics of the service.
+	GetStatsCommand = "get_stats"
+	// GetTrackingStateCommand is the DoCommand key used to request the current tracking state of the SLAM
+	// algorithm, along with the age of its pose.
+	GetTrackingStateCommand = "get_tracking_state"
+	// GetExtrapolatedPositionCommand is the DoCommand key used to request the current pose extrapolated to the time
+	// of the request, to compensate for the latency of the SLAM algorithm.
+	GetExtrapolatedPositionCommand = "get_extrapolated_position"
+	// GetScaleCommand is the DoCommand key used to request the estimated metric scale of the map.
+	GetScaleCommand = "get_scale"
+	// GetPointCloudMapCommand is the DoCommand key used to request the point cloud map with the filters given by
+	// point_cloud_filters in its value, in the format of the point_cloud_filters attribute, instead of the configured
+	// ones. An empty point_cloud_filters disables filtering. The response holds the map as a base64 encoded PCD file.
+	GetPointCloudMapCommand = "get_point_cloud_map"
+	// ExportMapCommand is the DoCommand key used to export the point cloud map to a file in the exports folder of
+	// the data directory. Its value holds the format, one of ply, pcd_ascii or las, and optionally the source, the
+	// path of a stored PCD snapshot within the data directory to export instead of the current map, or the
+	// point_cloud_filters to apply to the current map instead of the configured ones.
+	ExportMapCommand = "export_map"
+	// ExportOccupancyGridCommand is the DoCommand key used to export a 2D occupancy grid of the map as a PGM image
+	// and ROS map_server YAML in the exports folder of the data directory. Its value may replace any of the
+	// parameters of the occupancy_grid attribute, and the point_cloud_filters applied to the map.
+	ExportOccupancyGridCommand = "export_occupancy_grid"
+	// GetPointCloudMapDeltaCommand is the DoCommand key used to request the changes to the point cloud map since
+	// the version given by since_version in its value. The response holds the new version, and either the added and
+	// removed points or, if full is set, the whole map, as base64 encoded PCD files.
+	GetPointCloudMapDeltaCommand = "get_point_cloud_map_delta"
+	// ListMapsCommand is the DoCommand key used to list the maps saved in the data directory, oldest first, along
+	// with the metadata recorded when they were saved.
+	ListMapsCommand = "list_maps"
+	// BeginMapUploadCommand is the DoCommand key used to start uploading a map, replacing any upload in progress. Its
+	// value holds the size and sha256 checksum of the map and optionally its metadata, as ListMapsCommand returns it,
+	// to check that the map is compatible with the current settings. The response holds the upload_id of the upload.
+	BeginMapUploadCommand = "begin_map_upload"
+	// UploadMapChunkCommand is the DoCommand key used to send a chunk of the map being uploaded. Its value holds the
+	// upload_id, the offset of the chunk and its base64 encoded data. Chunks must be sent in order, and the response
+	// holds the number of bytes received so far.
+	UploadMapChunkCommand = "upload_map_chunk"
+	// FinishMapUploadCommand is the DoCommand key used to verify the uploaded map and save it as the newest map. The SLAM
+	// process is then restarted in localization mode with the map. The response holds the path of the map and whether
+	// it was reloaded. Maps can only be uploaded with live data.
+	FinishMapUploadCommand = "finish_map_upload"
+	// DownloadInternalStateCommand is the DoCommand key used to download the internal state of the SLAM algorithm to a
+	// file, verified against the checksum the SLAM server reports. Its value may hold the path of the file, relative to
+	// the data directory, which defaults to a new file in the exports folder. Downloading to the path of an interrupted
+	// download resumes it. The response holds the path, size and sha256 checksum of the file, and whether it was resumed.
+	DownloadInternalStateCommand = "download_internal_state"
+)
+
+// DoCommand receives arbitrary commands. Each key of the command map names a command to run and
+// its value holds the arguments of that command, if any.
+func (orbSvc *orbslamService) DoCommand(ctx context.Context, req map[string]interface{}) (map[string]interface{}, error) {
+	ctx, span := trace.StartSpan(ctx, "viamorbslam3::orbslamService::DoCommand")
+	defer span.End()
+
+	if _, ok := req[GetStatsCommand]; ok {
+		return orbSvc.stats.Snapshot(), nil
+	}
+
+	if _, ok := req[GetTrackingStateCommand]; ok {
+		// Request a pose so that the returned tracking state is current.
+		if _, _, err := orbSvc.GetPosition(ctx); err != nil {
+			return nil, err
+		}
+		orbSvc.trackingMu.Lock()
+		defer orbSvc.trackingMu.Unlock()
+		return orbSvc.trackingInfo.Map(), nil
+	}
+
+	if _, ok := req[GetExtrapolatedPositionCommand]; ok {
+		return orbSvc.getExtrapolatedPosition(ctx)
+	}
+
+	if _, ok := req[GetScaleCommand]; ok {
+		estimate, ok := orbSvc.scaleEstimator.Estimate()
+		if !ok {
+			return map[string]interface{}{"estimated": false}, nil
+		}
+		return map[string]interface{}{
+			"estimated":  true,
+			"scale":      estimate.Scale,
+			"samples":    estimate.Samples,
+			"updated_at": estimate.UpdatedAt.UTC().Format(time.RFC3339),
+		}, nil
+	}
+
+	if args, ok := req[GetPointCloudMapCommand]; ok {
+		return orbSvc.getFilteredPointCloudMap(ctx, args)
+	}
+
+	if args, ok := req[ExportMapCommand]; ok {
+		return orbSvc.exportMap(ctx, args)
+	}
+
+	if args, ok := req[ExportOccupancyGridCommand]; ok {
+		return orbSvc.exportOccupancyGrid(ctx, args)
+	}
+
+	if _, ok := req[ListMapsCommand]; ok {
+		return orbSvc.listMapsResponse()
+	}
+
+	if args, ok := req[GetPointCloudMapDeltaCommand]; ok {
+		return orbSvc.getPointCloudMapDelta(ctx, args)
+	}
+
+	if args, ok := req[BeginMapUploadCommand]; ok {
+		return orbSvc.beginMapUpload(args)
+	}
+
+	if args, ok := req[UploadMapChunkCommand]; ok {
+		return orbSvc.uploadMapChunk(args)
+	}
+
+	if args, ok := req[FinishMapUploadCommand]; ok {
+		return orbSvc.finishMapUpload(ctx, args)
+	}
+
+	if args, ok := req[DownloadInternalStateCommand]; ok {
+		return orbSvc.downloadInternalState(ctx, args)
+	}
+
+	return nil, resource.ErrDoUnimplemented
+}
+
+// getFilteredPointCloudMap returns the point cloud map with the filters given in args.
+func (orbSvc *orbslamService) getFilteredPointCloudMap(ctx context.Context, args interface{}) (map[string]interface{}, error) {
+	argsMap, _ := args.(map[string]interface{})
+	filters, err := orbSvc.requestFilters(argsMap)
+	if err != nil {
+		return nil, err
+	}
+	data, err := orbSvc.processedPointCloudMapData(ctx, filters)
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting point cloud map")
+	}
+	return map[string]interface{}{"map": base64.StdEncoding.EncodeToString(data)}, nil
+}
+
+// decodeFilters decodes and validates point cloud filters given in the format of the point_cloud_filters attribute.
+// It returns nil if no filter is given.
+func decodeFilters(args interface{}) (*orbSlamConfig.FiltersConfig, error) {
+	if args == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(args)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid point cloud filters")
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	var filters orbSlamConfig.FiltersConfig
+	if err := decoder.Decode(&filters); err != nil {
+		return nil, errors.Wrap(err, "invalid point cloud filters")
+	}
+	if err := filters.Validate(); err != nil {
+		return nil, err
+	}
+	if len(filters.Filters()) == 0 {
+		return nil, nil
+	}
+	return &filters, nil
+}
+
+// getExtrapolatedPosition requests a pose and extrapolates the pose history to the current time, up to the
+// configured limit.
+func (orbSvc *orbslamService) getExtrapolatedPosition(ctx context.Context) (map[string]interface{}, error) {
+	_, componentReference, err := orbSvc.GetPosition(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	extrapolation, err := orbSvc.poseHistory.Extrapolate(now, time.Duration(orbSvc.extrapolationLimitMs)*time.Millisecond)
+	if err != nil {
+		return nil, errors.Wrap(err, "error extrapolating SLAM position")
+	}
+
+	resp := map[string]interface{}{
+		"pose":                    poseToMap(extrapolation.Pose),
+		"component_reference":     componentReference,
+		"prediction_horizon_msec": float64(extrapolation.Horizon) / float64(time.Millisecond),
+		"pose_age_msec":           float64(now.Sub(extrapolation.Latest.Time)) / float64(time.Millisecond),
+		"limited":                 extrapolation.Limited,
+	}
+	if v, ok := orbSvc.poseHistory.Velocity(); ok {
+		resp["linear_velocity_mm_per_sec"] = vectorToMap(v.Linear)
+		resp["angular_velocity_rad_per_sec"] = v.Angular
+	}
+	return resp, nil
+}
+
+// vectorToMap converts a vector into a map with its x, y and z components.
+func vectorToMap(v r3.Vector) map[string]interface{} {
+	return map[string]interface{}{"x": v.X, "y": v.Y, "z": v.Z}
+}
+
+// poseToMap converts a pose into a map with its point and quaternion, in the format the SLAM algorithm uses in
+// the extra field of GetPosition.
+func poseToMap(pose spatialmath.Pose) map[string]interface{} {
+	q := pose.Orientation().Quaternion()
+	return map[string]interface{}{
+		"x": pose.Point().X,
+		"y": pose.Point().Y,
+		"z": pose.Point().Z,
+		"quat": map[string]interface{}{
+			"real": q.Real,
+			"imag": q.Imag,
+			"jmag": q.Jmag,
+			"kmag": q.Kmag,
+		},
+	}
+}
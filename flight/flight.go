@@ -0,0 +1,140 @@
+// Package flight coalesces concurrent calls for the same key into a single call and caches their results.
+package flight
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// errPanicked is returned to the calls waiting on a call that panicked.
+var errPanicked = errors.New("shared call panicked")
+
+// call is an in flight or completed call.
+type call[V any] struct {
+	done     chan struct{}
+	val      V
+	err      error
+	finished time.Time
+}
+
+// Group coalesces concurrent calls for the same key and caches successful results for a time to live. Errors are
+// shared with the calls waiting on the failed call, but are not cached. It is safe for concurrent use.
+type Group[K comparable, V any] struct {
+	ttl        time.Duration
+	maxEntries int
+
+	mu    sync.Mutex
+	calls map[K]*call[V]
+	// order holds the keys of completed calls from oldest to newest, for eviction.
+	order []K
+}
+
+// NewGroup returns a group that caches results for ttl, or until evicted if ttl is zero. At most maxEntries results
+// are cached, evicting the oldest first.
+func NewGroup[K comparable, V any](ttl time.Duration, maxEntries int) *Group[K, V] {
+	if maxEntries < 1 {
+		maxEntries = 1
+	}
+	return &Group[K, V]{ttl: ttl, maxEntries: maxEntries, calls: map[K]*call[V]{}}
+}
+
+// Do returns the cached result for key if there is one, waits for the in flight call for key if there is one, and
+// otherwise calls fn. Since fn is shared by all callers waiting on it, it should not depend on the context of a single
+// caller; a caller whose context is done stops waiting and returns the context's error.
+func (g *Group[K, V]) Do(ctx context.Context, key K, fn func() (V, error)) (V, error) {
+	g.mu.Lock()
+	if c, ok := g.calls[key]; ok {
+		select {
+		case <-c.done:
+			if g.ttl == 0 || time.Since(c.finished) < g.ttl {
+				g.mu.Unlock()
+				return c.val, nil
+			}
+			g.removeLocked(key)
+		default:
+			g.mu.Unlock()
+			return g.wait(ctx, c)
+		}
+	}
+
+	c := &call[V]{done: make(chan struct{})}
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	// The calls waiting on fn must be released even if it panics, in which case they fail and the panic continues.
+	returned := false
+	defer func() {
+		if !returned {
+			c.err = errPanicked
+		}
+		g.finish(key, c)
+	}()
+	c.val, c.err = fn()
+	returned = true
+	return c.val, c.err
+}
+
+// finish records the result of a call and releases the calls waiting on it.
+func (g *Group[K, V]) finish(key K, c *call[V]) {
+	c.finished = time.Now()
+	g.mu.Lock()
+	if c.err != nil {
+		if g.calls[key] == c {
+			delete(g.calls, key)
+		}
+	} else if g.calls[key] == c {
+		g.order = append(g.order, key)
+		for len(g.order) > g.maxEntries {
+			g.removeLocked(g.order[0])
+		}
+	}
+	g.mu.Unlock()
+	close(c.done)
+}
+
+// Forget removes the cached result for key, so that the next call for it calls fn again. An in flight call is not
+// affected.
+func (g *Group[K, V]) Forget(key K) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if c, ok := g.calls[key]; ok {
+		select {
+		case <-c.done:
+			g.removeLocked(key)
+		default:
+		}
+	}
+}
+
+// Reset removes all cached results, e.g. because the source of the results was restarted. In flight calls are not
+// affected.
+func (g *Group[K, V]) Reset() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for len(g.order) > 0 {
+		g.removeLocked(g.order[0])
+	}
+}
+
+func (g *Group[K, V]) wait(ctx context.Context, c *call[V]) (V, error) {
+	select {
+	case <-c.done:
+		return c.val, c.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+// removeLocked removes a completed call. Must be called with the lock held.
+func (g *Group[K, V]) removeLocked(key K) {
+	delete(g.calls, key)
+	for i, k := range g.order {
+		if k == key {
+			g.order = append(g.order[:i], g.order[i+1:]...)
+			break
+		}
+	}
+}
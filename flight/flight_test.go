@@ -0,0 +1,155 @@
+package flight
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.viam.com/test"
+)
+
+func TestGroup(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Concurrent calls share one call", func(t *testing.T) {
+		g := NewGroup[string, int](time.Minute, 1)
+		var calls int32
+		release := make(chan struct{})
+		var wg sync.WaitGroup
+		results := make([]int, 10)
+		for i := range results {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				v, err := g.Do(ctx, "key", func() (int, error) {
+					atomic.AddInt32(&calls, 1)
+					<-release
+					return 42, nil
+				})
+				test.That(t, err, test.ShouldBeNil)
+				results[i] = v
+			}(i)
+		}
+		// Give every goroutine a chance to join the in flight call.
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+		wg.Wait()
+		test.That(t, atomic.LoadInt32(&calls), test.ShouldEqual, 1)
+		for _, v := range results {
+			test.That(t, v, test.ShouldEqual, 42)
+		}
+	})
+
+	t.Run("Results expire after the ttl", func(t *testing.T) {
+		g := NewGroup[string, int](20*time.Millisecond, 1)
+		var calls int
+		fn := func() (int, error) {
+			calls++
+			return calls, nil
+		}
+		v, _ := g.Do(ctx, "key", fn)
+		test.That(t, v, test.ShouldEqual, 1)
+		v, _ = g.Do(ctx, "key", fn)
+		test.That(t, v, test.ShouldEqual, 1)
+		time.Sleep(30 * time.Millisecond)
+		v, _ = g.Do(ctx, "key", fn)
+		test.That(t, v, test.ShouldEqual, 2)
+
+		g.Forget("key")
+		v, _ = g.Do(ctx, "key", fn)
+		test.That(t, v, test.ShouldEqual, 3)
+	})
+
+	t.Run("Errors are not cached", func(t *testing.T) {
+		g := NewGroup[string, int](time.Minute, 1)
+		_, err := g.Do(ctx, "key", func() (int, error) { return 0, errors.New("failed") })
+		test.That(t, err, test.ShouldBeError, "failed")
+		v, err := g.Do(ctx, "key", func() (int, error) { return 1, nil })
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, v, test.ShouldEqual, 1)
+	})
+
+	t.Run("Oldest entries are evicted", func(t *testing.T) {
+		g := NewGroup[int, int](0, 2)
+		var calls int
+		fn := func() (int, error) {
+			calls++
+			return calls, nil
+		}
+		for key := 1; key <= 3; key++ {
+			g.Do(ctx, key, fn)
+		}
+		v, _ := g.Do(ctx, 3, fn)
+		test.That(t, v, test.ShouldEqual, 3)
+		v, _ = g.Do(ctx, 2, fn)
+		test.That(t, v, test.ShouldEqual, 2)
+		v, _ = g.Do(ctx, 1, fn)
+		test.That(t, v, test.ShouldEqual, 4)
+	})
+
+	t.Run("Waiting callers stop when their context is done", func(t *testing.T) {
+		g := NewGroup[string, int](time.Minute, 1)
+		release := make(chan struct{})
+		defer close(release)
+		started := make(chan struct{})
+		go g.Do(ctx, "key", func() (int, error) {
+			close(started)
+			<-release
+			return 1, nil
+		})
+		<-started
+		cancelCtx, cancel := context.WithCancel(ctx)
+		cancel()
+		_, err := g.Do(cancelCtx, "key", func() (int, error) { return 2, nil })
+		test.That(t, err, test.ShouldBeError, context.Canceled)
+	})
+
+	t.Run("Waiting callers are released when the call panics", func(t *testing.T) {
+		g := NewGroup[string, int](time.Minute, 1)
+		release := make(chan struct{})
+		started := make(chan struct{})
+		panicked := make(chan interface{})
+		go func() {
+			defer func() { panicked <- recover() }()
+			g.Do(ctx, "key", func() (int, error) {
+				close(started)
+				<-release
+				panic("boom")
+			})
+		}()
+		<-started
+		waitErr := make(chan error)
+		go func() {
+			_, err := g.Do(ctx, "key", func() (int, error) { return 2, nil })
+			waitErr <- err
+		}()
+		// Give the second caller a chance to join the in flight call.
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+		test.That(t, <-panicked, test.ShouldEqual, "boom")
+		test.That(t, <-waitErr, test.ShouldBeError, errPanicked)
+
+		v, err := g.Do(ctx, "key", func() (int, error) { return 3, nil })
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, v, test.ShouldEqual, 3)
+	})
+
+	t.Run("Reset removes all cached results", func(t *testing.T) {
+		g := NewGroup[int, int](0, 2)
+		var calls int
+		fn := func() (int, error) {
+			calls++
+			return calls, nil
+		}
+		g.Do(ctx, 1, fn)
+		g.Do(ctx, 2, fn)
+		g.Reset()
+		v, _ := g.Do(ctx, 1, fn)
+		test.That(t, v, test.ShouldEqual, 3)
+		v, _ = g.Do(ctx, 2, fn)
+		test.That(t, v, test.ShouldEqual, 4)
+	})
+}
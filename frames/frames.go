@@ -0,0 +1,84 @@
+// Package frames transforms poses reported by ORB_SLAM3 from the camera frame into other frames.
+package frames
+
+import (
+	"github.com/golang/geo/r3"
+	"github.com/pkg/errors"
+	"go.viam.com/rdk/spatialmath"
+)
+
+// AxisConvention is the axis convention poses are reported in.
+type AxisConvention string
+
+const (
+	// AxisConventionCamera is the optical convention used by ORB_SLAM3: x right, y down and z forward.
+	AxisConventionCamera AxisConvention = "camera"
+	// AxisConventionRobot is the convention used by robot bases: x forward, y left and z up.
+	AxisConventionRobot AxisConvention = "robot"
+)
+
+// opticalToRobotAxes is the rotation from the camera's optical axes to the same camera's axes in robot
+// convention, in row major order.
+var opticalToRobotAxes = []float64{
+	0, 0, 1,
+	-1, 0, 0,
+	0, -1, 0,
+}
+
+// Transform changes the frame of the poses reported by ORB_SLAM3. ORB_SLAM3 reports the pose of the camera relative
+// to the pose of the camera at the first frame of the map, so the transformed pose is that of the new frame relative
+// to where that frame was at the first frame of the map.
+type Transform struct {
+	cameraToFrame spatialmath.Pose
+	frameToCamera spatialmath.Pose
+}
+
+// NewTransform returns a transform into a frame the camera is attached to. The axis convention is applied to the
+// camera first, so the extrinsic is the pose of the camera in that convention relative to the frame. A nil extrinsic
+// means the camera itself is the frame.
+func NewTransform(extrinsic spatialmath.Pose, convention AxisConvention) (*Transform, error) {
+	cameraToFrame := spatialmath.NewZeroPose()
+	switch convention {
+	case AxisConventionCamera, "":
+	case AxisConventionRobot:
+		rotation, err := spatialmath.NewRotationMatrix(opticalToRobotAxes)
+		if err != nil {
+			return nil, err
+		}
+		cameraToFrame = spatialmath.NewPoseFromOrientation(rotation)
+	default:
+		return nil, errors.Errorf("unknown axis convention %q", convention)
+	}
+	if extrinsic != nil {
+		cameraToFrame = spatialmath.Compose(extrinsic, cameraToFrame)
+	}
+	return &Transform{cameraToFrame: cameraToFrame, frameToCamera: spatialmath.PoseInverse(cameraToFrame)}, nil
+}
+
+// IsIdentity returns whether the frame of the transform is the camera frame, so that applying it changes nothing.
+func (t *Transform) IsIdentity() bool {
+	return spatialmath.PoseAlmostEqual(t.cameraToFrame, spatialmath.NewZeroPose())
+}
+
+// Apply transforms a pose reported by ORB_SLAM3 into the frame of the transform.
+func (t *Transform) Apply(pose spatialmath.Pose) spatialmath.Pose {
+	return spatialmath.Compose(spatialmath.Compose(t.cameraToFrame, pose), t.frameToCamera)
+}
+
+// ApplyToPoint transforms a point of the map reported by ORB_SLAM3 into the frame of the transform, so that the map
+// lines up with the poses returned by Apply.
+func (t *Transform) ApplyToPoint(point r3.Vector) r3.Vector {
+	return spatialmath.Compose(t.cameraToFrame, spatialmath.NewPoseFromPoint(point)).Point()
+}
+
+// DirectionFromCamera rotates a direction expressed in the camera frame into the frame of the transform.
+func (t *Transform) DirectionFromCamera(direction r3.Vector) r3.Vector {
+	rotation := spatialmath.NewPoseFromOrientation(t.cameraToFrame.Orientation())
+	return spatialmath.Compose(rotation, spatialmath.NewPoseFromPoint(direction)).Point()
+}
+
+// DirectionToCamera rotates a direction expressed in the frame of the transform into the camera frame.
+func (t *Transform) DirectionToCamera(direction r3.Vector) r3.Vector {
+	rotation := spatialmath.NewPoseFromOrientation(t.frameToCamera.Orientation())
+	return spatialmath.Compose(rotation, spatialmath.NewPoseFromPoint(direction)).Point()
+}
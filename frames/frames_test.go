@@ -0,0 +1,68 @@
+package frames
+
+import (
+	"math"
+	"testing"
+
+	"github.com/golang/geo/r3"
+	"go.viam.com/rdk/spatialmath"
+	"go.viam.com/test"
+)
+
+func TestTransform(t *testing.T) {
+	// The camera moved 100mm forward and turned 90 degrees to the left, expressed in the optical convention.
+	cameraPose := spatialmath.NewPose(r3.Vector{Z: 100}, &spatialmath.R4AA{Theta: math.Pi / 2, RY: -1})
+
+	t.Run("Camera convention without an extrinsic leaves poses unchanged", func(t *testing.T) {
+		transform, err := NewTransform(nil, AxisConventionCamera)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, spatialmath.PoseAlmostEqual(transform.Apply(cameraPose), cameraPose), test.ShouldBeTrue)
+	})
+
+	t.Run("Robot convention reports x forward and z up", func(t *testing.T) {
+		transform, err := NewTransform(nil, AxisConventionRobot)
+		test.That(t, err, test.ShouldBeNil)
+		expected := spatialmath.NewPose(r3.Vector{X: 100}, &spatialmath.R4AA{Theta: math.Pi / 2, RZ: 1})
+		test.That(t, spatialmath.PoseAlmostEqual(transform.Apply(cameraPose), expected), test.ShouldBeTrue)
+	})
+
+	t.Run("Extrinsic offsets the camera from the base", func(t *testing.T) {
+		// The camera is mounted 50mm in front of the base center.
+		extrinsic := spatialmath.NewPoseFromPoint(r3.Vector{X: 50})
+		transform, err := NewTransform(extrinsic, AxisConventionRobot)
+		test.That(t, err, test.ShouldBeNil)
+		// The base moved to the point the camera turned around, minus the camera offset now pointing left.
+		expected := spatialmath.NewPose(r3.Vector{X: 150, Y: -50}, &spatialmath.R4AA{Theta: math.Pi / 2, RZ: 1})
+		test.That(t, spatialmath.PoseAlmostEqual(transform.Apply(cameraPose), expected), test.ShouldBeTrue)
+	})
+
+	t.Run("Points line up with the poses", func(t *testing.T) {
+		transform, err := NewTransform(spatialmath.NewPoseFromPoint(r3.Vector{X: 50}), AxisConventionRobot)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, transform.IsIdentity(), test.ShouldBeFalse)
+		// A point at the camera is where the base is, offset by the extrinsic rotated with the camera.
+		cameraPoint := transform.ApplyToPoint(cameraPose.Point())
+		basePose := transform.Apply(cameraPose)
+		offset := spatialmath.Compose(basePose, spatialmath.NewPoseFromPoint(r3.Vector{X: 50})).Point()
+		test.That(t, cameraPoint.Sub(offset).Norm(), test.ShouldBeLessThan, 1e-6)
+		test.That(t, cameraPoint.Sub(r3.Vector{X: 150}).Norm(), test.ShouldBeLessThan, 1e-6)
+
+		identity, err := NewTransform(nil, AxisConventionCamera)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, identity.IsIdentity(), test.ShouldBeTrue)
+		test.That(t, identity.ApplyToPoint(r3.Vector{X: 1, Y: 2, Z: 3}), test.ShouldResemble, r3.Vector{X: 1, Y: 2, Z: 3})
+	})
+
+	t.Run("Directions are rotated into the camera frame", func(t *testing.T) {
+		transform, err := NewTransform(spatialmath.NewPoseFromPoint(r3.Vector{X: 50}), AxisConventionRobot)
+		test.That(t, err, test.ShouldBeNil)
+		up := transform.DirectionToCamera(r3.Vector{Z: 9.8})
+		test.That(t, up.Sub(r3.Vector{Y: -9.8}).Norm(), test.ShouldBeLessThan, 1e-6)
+		test.That(t, transform.DirectionFromCamera(up).Sub(r3.Vector{Z: 9.8}).Norm(), test.ShouldBeLessThan, 1e-6)
+	})
+
+	t.Run("Unknown axis convention", func(t *testing.T) {
+		_, err := NewTransform(nil, "sideways")
+		test.That(t, err, test.ShouldBeError, `unknown axis convention "sideways"`)
+	})
+}
@@ -0,0 +1,198 @@
+// Package gravity estimates the direction of gravity in a SLAM map and the rotation that aligns the map with it.
+package gravity
+
+import (
+	"encoding/json"
+	"math"
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/golang/geo/r3"
+	"github.com/pkg/errors"
+
+	"github.com/viamrobotics/viam-orb-slam3/atomicfile"
+)
+
+const (
+	// parallelEpsilon is the sine of the angle below which two directions are considered parallel.
+	parallelEpsilon = 1e-9
+	// minInlierFraction is the fraction of points that must lie on the floor plane for it to be accepted.
+	minInlierFraction = 0.1
+)
+
+// Source is the way gravity was estimated.
+type Source string
+
+const (
+	// SourceIMU means gravity was measured by an IMU.
+	SourceIMU Source = "imu"
+	// SourceFloorPlane means gravity was estimated as the normal of the floor plane in the map.
+	SourceFloorPlane Source = "floor_plane"
+)
+
+// Rotation is a rotation around an axis by an angle in radians.
+type Rotation struct {
+	Axis  r3.Vector `json:"axis"`
+	Angle float64   `json:"angle"`
+}
+
+// RotationBetween returns the smallest rotation that rotates the direction from onto the direction to.
+func RotationBetween(from, to r3.Vector) Rotation {
+	from, to = from.Normalize(), to.Normalize()
+	cross := from.Cross(to)
+	sin, cos := cross.Norm(), from.Dot(to)
+	if sin < parallelEpsilon {
+		if cos > 0 {
+			return Rotation{Axis: r3.Vector{Z: 1}}
+		}
+		// Any axis perpendicular to the directions rotates one onto the other.
+		return Rotation{Axis: from.Ortho(), Angle: math.Pi}
+	}
+	return Rotation{Axis: cross.Mul(1 / sin), Angle: math.Atan2(sin, cos)}
+}
+
+// Rotate rotates a vector using Rodrigues' rotation formula.
+func (r Rotation) Rotate(v r3.Vector) r3.Vector {
+	k := r.Axis.Normalize()
+	sin, cos := math.Sincos(r.Angle)
+	return v.Mul(cos).Add(k.Cross(v).Mul(sin)).Add(k.Mul(k.Dot(v) * (1 - cos)))
+}
+
+// Plane is a plane with a unit normal, containing the points p for which Normal.Dot(p) + Offset is zero.
+type Plane struct {
+	Normal  r3.Vector
+	Offset  float64
+	Inliers int
+}
+
+// Distance returns the signed distance of a point from the plane.
+func (p Plane) Distance(point r3.Vector) float64 {
+	return p.Normal.Dot(point) + p.Offset
+}
+
+// FitFloorPlane finds the plane containing the most points within threshold using RANSAC, considering only planes
+// whose normal is within maxTilt radians of the expected up direction. The returned normal points up.
+func FitFloorPlane(points []r3.Vector, up r3.Vector, threshold, maxTilt float64, iterations int, rng *rand.Rand,
+) (Plane, error) {
+	if len(points) < 3 {
+		return Plane{}, errors.Errorf("need at least 3 points to fit a floor plane, got %d", len(points))
+	}
+	up = up.Normalize()
+	minCos := math.Cos(maxTilt)
+
+	var best Plane
+	for i := 0; i < iterations; i++ {
+		a, b, c := points[rng.Intn(len(points))], points[rng.Intn(len(points))], points[rng.Intn(len(points))]
+		normal := b.Sub(a).Cross(c.Sub(a))
+		if normal.Norm() < parallelEpsilon {
+			continue
+		}
+		normal = normal.Normalize()
+		if normal.Dot(up) < 0 {
+			normal = normal.Mul(-1)
+		}
+		if normal.Dot(up) < minCos {
+			continue
+		}
+		candidate := Plane{Normal: normal, Offset: -normal.Dot(a)}
+		candidate.Inliers = len(inliers(points, candidate, threshold))
+		if candidate.Inliers > best.Inliers {
+			best = candidate
+		}
+	}
+	if best.Inliers < 3 || float64(best.Inliers) < minInlierFraction*float64(len(points)) {
+		return Plane{}, errors.Errorf("no floor plane found in %d points", len(points))
+	}
+	return refine(inliers(points, best, threshold), best), nil
+}
+
+func inliers(points []r3.Vector, plane Plane, threshold float64) []r3.Vector {
+	var result []r3.Vector
+	for _, p := range points {
+		if math.Abs(plane.Distance(p)) <= threshold {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// refine fits a plane to the inliers of a RANSAC plane with least squares, measuring heights along the normal of the
+// RANSAC plane.
+func refine(points []r3.Vector, plane Plane) Plane {
+	n := plane.Normal
+	u := n.Ortho()
+	v := n.Cross(u)
+
+	// Solve the normal equations of h = a*x + b*y + c, where x and y are coordinates in the plane and h is the
+	// height above it.
+	var sxx, sxy, sx, syy, sy, sxh, syh, sh float64
+	for _, p := range points {
+		x, y, h := u.Dot(p), v.Dot(p), n.Dot(p)
+		sxx += x * x
+		sxy += x * y
+		sx += x
+		syy += y * y
+		sy += y
+		sxh += x * h
+		syh += y * h
+		sh += h
+	}
+	count := float64(len(points))
+	det := det3(sxx, sxy, sx, sxy, syy, sy, sx, sy, count)
+	if math.Abs(det) < parallelEpsilon {
+		return plane
+	}
+	a := det3(sxh, sxy, sx, syh, syy, sy, sh, sy, count) / det
+	b := det3(sxx, sxh, sx, sxy, syh, sy, sx, sh, count) / det
+	c := det3(sxx, sxy, sxh, sxy, syy, syh, sx, sy, sh) / det
+
+	normal := n.Sub(u.Mul(a)).Sub(v.Mul(b))
+	norm := normal.Norm()
+	return Plane{Normal: normal.Mul(1 / norm), Offset: -c / norm, Inliers: len(points)}
+}
+
+// det3 returns the determinant of a 3x3 matrix given in row major order.
+func det3(a, b, c, d, e, f, g, h, i float64) float64 {
+	return a*(e*i-f*h) - b*(d*i-f*g) + c*(d*h-e*g)
+}
+
+// Alignment is the rotation that aligns a map with gravity.
+type Alignment struct {
+	Rotation  Rotation  `json:"rotation"`
+	Source    Source    `json:"source"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// NewAlignment returns the alignment that rotates the measured up direction onto the target up direction.
+func NewAlignment(measuredUp, targetUp r3.Vector, source Source) (Alignment, error) {
+	if measuredUp.Norm() < parallelEpsilon {
+		return Alignment{}, errors.New("cannot align to gravity with a zero up direction")
+	}
+	return Alignment{Rotation: RotationBetween(measuredUp, targetUp), Source: source, UpdatedAt: time.Now()}, nil
+}
+
+// WriteFile saves the alignment to a file, replacing it atomically.
+func WriteFile(path string, alignment Alignment) error {
+	data, err := json.Marshal(alignment)
+	if err != nil {
+		return err
+	}
+	return errors.Wrap(atomicfile.WriteFile(path, data, 0o644), "error writing gravity alignment file")
+}
+
+// ReadFile loads an alignment saved with WriteFile.
+func ReadFile(path string) (Alignment, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Alignment{}, err
+	}
+	var alignment Alignment
+	if err := json.Unmarshal(data, &alignment); err != nil {
+		return Alignment{}, errors.Wrapf(err, "error parsing gravity alignment file %v", path)
+	}
+	if alignment.Rotation.Axis.Norm() < parallelEpsilon {
+		return Alignment{}, errors.Errorf("invalid rotation axis in %v", path)
+	}
+	return alignment, nil
+}
@@ -0,0 +1,92 @@
+package gravity
+
+import (
+	"math"
+	"math/rand"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/geo/r3"
+	"go.viam.com/test"
+)
+
+func vectorsAlmostEqual(t *testing.T, actual, expected r3.Vector) {
+	t.Helper()
+	test.That(t, actual.Sub(expected).Norm(), test.ShouldBeLessThan, 1e-6)
+}
+
+func TestRotation(t *testing.T) {
+	t.Run("Rotate around z", func(t *testing.T) {
+		r := Rotation{Axis: r3.Vector{Z: 1}, Angle: math.Pi / 2}
+		vectorsAlmostEqual(t, r.Rotate(r3.Vector{X: 1}), r3.Vector{Y: 1})
+	})
+
+	for _, c := range []struct {
+		name     string
+		from, to r3.Vector
+	}{
+		{"Perpendicular", r3.Vector{X: 1}, r3.Vector{Y: -2}},
+		{"Tilted", r3.Vector{X: 0.1, Y: -1, Z: 0.2}, r3.Vector{Y: -1}},
+		{"Parallel", r3.Vector{Y: 3}, r3.Vector{Y: 1}},
+		{"Antiparallel", r3.Vector{Y: 1}, r3.Vector{Y: -1}},
+	} {
+		t.Run("Rotation between "+c.name, func(t *testing.T) {
+			r := RotationBetween(c.from, c.to)
+			vectorsAlmostEqual(t, r.Rotate(c.from.Normalize()), c.to.Normalize())
+		})
+	}
+}
+
+func TestFitFloorPlane(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	// A floor tilted by 10 degrees around x, 1.5 units below the camera in the camera convention where up is -y.
+	tilt := Rotation{Axis: r3.Vector{X: 1}, Angle: 10 * math.Pi / 180}
+	var points []r3.Vector
+	for i := 0; i < 300; i++ {
+		floor := r3.Vector{X: rng.Float64()*4 - 2, Y: 1.5 + rng.NormFloat64()*0.005, Z: rng.Float64() * 5}
+		points = append(points, tilt.Rotate(floor))
+	}
+	// Features on a wall and clutter.
+	for i := 0; i < 100; i++ {
+		points = append(points, r3.Vector{X: rng.Float64()*4 - 2, Y: rng.Float64()*3 - 1.5, Z: 5})
+		points = append(points, r3.Vector{X: rng.Float64()*4 - 2, Y: rng.Float64()*3 - 1.5, Z: rng.Float64() * 5})
+	}
+	up := r3.Vector{Y: -1}
+
+	t.Run("Floor plane is found", func(t *testing.T) {
+		plane, err := FitFloorPlane(points, up, 0.02, math.Pi/4, 200, rng)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, plane.Inliers, test.ShouldBeGreaterThanOrEqualTo, 300)
+		expectedNormal := tilt.Rotate(up)
+		test.That(t, plane.Normal.Dot(expectedNormal), test.ShouldBeGreaterThan, math.Cos(0.5*math.Pi/180))
+		test.That(t, plane.Distance(r3.Vector{}), test.ShouldAlmostEqual, 1.5, 0.01)
+
+		alignment, err := NewAlignment(plane.Normal, up, SourceFloorPlane)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, alignment.Rotation.Angle, test.ShouldAlmostEqual, 10*math.Pi/180, 0.01)
+	})
+
+	t.Run("No floor plane within the tilt limit", func(t *testing.T) {
+		_, err := FitFloorPlane(points[300:], up, 0.001, math.Pi/36, 200, rng)
+		test.That(t, err.Error(), test.ShouldContainSubstring, "no floor plane found")
+	})
+
+	t.Run("Not enough points", func(t *testing.T) {
+		_, err := FitFloorPlane(points[:2], up, 0.02, math.Pi/4, 200, rng)
+		test.That(t, err, test.ShouldBeError, "need at least 3 points to fit a floor plane, got 2")
+	})
+}
+
+func TestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gravity.json")
+	alignment, err := NewAlignment(r3.Vector{X: 1}, r3.Vector{Y: -1}, SourceIMU)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, WriteFile(path, alignment), test.ShouldBeNil)
+	read, err := ReadFile(path)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, read.Rotation, test.ShouldResemble, alignment.Rotation)
+	test.That(t, read.Source, test.ShouldEqual, SourceIMU)
+
+	_, err = NewAlignment(r3.Vector{}, r3.Vector{Y: -1}, SourceIMU)
+	test.That(t, err, test.ShouldBeError, "cannot align to gravity with a zero up direction")
+}
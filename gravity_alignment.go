@@ -0,0 +1,212 @@
+package viamorbslam3
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/golang/geo/r3"
+	"github.com/pkg/errors"
+	"go.viam.com/rdk/components/movementsensor"
+	"go.viam.com/rdk/resource"
+	"go.viam.com/rdk/spatialmath"
+	goutils "go.viam.com/utils"
+
+	orbSlamConfig "github.com/viamrobotics/viam-orb-slam3/config"
+	"github.com/viamrobotics/viam-orb-slam3/gravity"
+	"github.com/viamrobotics/viam-orb-slam3/pcd"
+)
+
+const (
+	gravityFilename             = "gravity.json"
+	floorPlaneIntervalSec       = 10
+	minFloorPlanePoints         = 100
+	floorPlaneIterations        = 500
+	floorPlaneMaxTiltDeg        = 45
+	floorPlaneThresholdFraction = 0.02
+)
+
+// cameraUp is the up direction of a level camera in the optical convention used by the SLAM algorithm. The map frame
+// is the camera frame at the start of the map, so aligning a map rotates its measured up direction onto this one.
+var cameraUp = r3.Vector{Y: -1}
+
+// configureIMU returns the IMU used to align the map with gravity, if the map is aligned using an IMU.
+func configureIMU(svcConfig *orbSlamConfig.Config, deps resource.Dependencies) (movementsensor.MovementSensor, error) {
+	if gravity.Source(svcConfig.GravityAlignment) != gravity.SourceIMU {
+		return nil, nil
+	}
+	// Without the extrinsic, the axes of the IMU would be taken for the optical axes of the camera.
+	if svcConfig.CameraExtrinsic == nil {
+		return nil, errors.Errorf("gravity_alignment %v requires camera_extrinsic", gravity.SourceIMU)
+	}
+	imu, err := movementsensor.FromDependencies(deps, svcConfig.IMUSensor)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error getting movement sensor %v for slam service", svcConfig.IMUSensor)
+	}
+	return imu, nil
+}
+
+// gravityPath is the path of the gravity alignment saved along with the maps.
+func (orbSvc *orbslamService) gravityPath() string {
+	return filepath.Join(orbSvc.dataDirectory, "map", gravityFilename)
+}
+
+// gravityAlignment returns the alignment of the map with gravity, if it is known.
+func (orbSvc *orbslamService) gravityAlignment() (gravity.Alignment, bool) {
+	orbSvc.gravityMu.Lock()
+	defer orbSvc.gravityMu.Unlock()
+	if orbSvc.gravity == nil {
+		return gravity.Alignment{}, false
+	}
+	return *orbSvc.gravity, true
+}
+
+// setGravityAlignment sets and saves the alignment of the map with gravity.
+func (orbSvc *orbslamService) setGravityAlignment(alignment gravity.Alignment) error {
+	orbSvc.gravityMu.Lock()
+	orbSvc.gravity = &alignment
+	orbSvc.gravityMu.Unlock()
+	// Poses before and after the alignment are in different frames.
+	orbSvc.poseHistory.Reset()
+
+	orbSvc.logger.Infof("aligned map with gravity using %v, rotating it by %.2f degrees",
+		alignment.Source, alignment.Rotation.Angle*180/math.Pi)
+	return gravity.WriteFile(orbSvc.gravityPath(), alignment)
+}
+
+// applyGravityAlignment rotates a pose in the map frame into the gravity aligned map frame, if the alignment is known.
+func (orbSvc *orbslamService) applyGravityAlignment(pose spatialmath.Pose) spatialmath.Pose {
+	alignment, ok := orbSvc.gravityAlignment()
+	if !ok {
+		return pose
+	}
+	axis := alignment.Rotation.Axis
+	rotation := spatialmath.NewPoseFromOrientation(&spatialmath.R4AA{
+		Theta: alignment.Rotation.Angle, RX: axis.X, RY: axis.Y, RZ: axis.Z,
+	})
+	return spatialmath.Compose(rotation, pose)
+}
+
+// loadGravityAlignment loads the gravity alignment of the maps in the map directory, if one was saved. A saved
+// alignment without a map to go with it is removed, since a new map starts from a different camera pose.
+func (orbSvc *orbslamService) loadGravityAlignment() error {
+	alignment, err := gravity.ReadFile(orbSvc.gravityPath())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	hasMap, err := orbSvc.hasMap()
+	if err != nil {
+		return errors.Wrap(err, "error checking for maps while loading gravity alignment")
+	}
+	if !hasMap {
+		orbSvc.logger.Infof("removing %v, since no map exists for it", orbSvc.gravityPath())
+		return os.Remove(orbSvc.gravityPath())
+	}
+
+	orbSvc.gravityMu.Lock()
+	defer orbSvc.gravityMu.Unlock()
+	orbSvc.gravity = &alignment
+	return nil
+}
+
+// alignWithIMU aligns a new map with the gravity measured by the IMU. The camera must be at rest, since the map
+// frame is the camera frame when the map starts.
+func (orbSvc *orbslamService) alignWithIMU(ctx context.Context) error {
+	if _, ok := orbSvc.gravityAlignment(); ok {
+		return nil
+	}
+	hasMap, err := orbSvc.hasMap()
+	if err != nil {
+		return err
+	}
+	if hasMap {
+		orbSvc.logger.Warn("cannot align an existing map with gravity using an imu, since the camera is not at the " +
+			"start of the map, continuing without gravity alignment")
+		return nil
+	}
+
+	// At rest an accelerometer measures the reaction to gravity, which points up.
+	acceleration, err := orbSvc.imu.LinearAcceleration(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "error getting linear acceleration for gravity alignment")
+	}
+	alignment, err := gravity.NewAlignment(orbSvc.poseTransform.DirectionToCamera(acceleration), cameraUp, gravity.SourceIMU)
+	if err != nil {
+		return err
+	}
+	return orbSvc.setGravityAlignment(alignment)
+}
+
+// startFloorPlaneAlignment starts the background loop that aligns the map with gravity once the floor plane can be
+// found in it.
+func (orbSvc *orbslamService) startFloorPlaneAlignment(cancelCtx context.Context) {
+	if gravity.Source(orbSvc.gravityAlignmentMode) != gravity.SourceFloorPlane {
+		return
+	}
+	if _, ok := orbSvc.gravityAlignment(); ok {
+		return
+	}
+
+	orbSvc.activeBackgroundWorkers.Add(1)
+	goutils.PanicCapturingGo(func() {
+		defer orbSvc.activeBackgroundWorkers.Done()
+		rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+		for goutils.SelectContextOrWait(cancelCtx, floorPlaneIntervalSec*time.Second) {
+			err := orbSvc.alignWithFloorPlane(cancelCtx, rng)
+			if err == nil {
+				return
+			}
+			orbSvc.logger.Debugw("unable to align map with the floor plane yet", "error", err)
+		}
+	})
+}
+
+// alignWithFloorPlane finds the floor plane in the point cloud map and aligns the map with its normal.
+func (orbSvc *orbslamService) alignWithFloorPlane(ctx context.Context, rng *rand.Rand) error {
+	data, err := orbSvc.getPointCloudMapData(ctx)
+	if err != nil {
+		return err
+	}
+	points, err := pcd.Read(data)
+	if err != nil {
+		return err
+	}
+	if len(points) < minFloorPlanePoints {
+		return errors.Errorf("only %d map points, need %d", len(points), minFloorPlanePoints)
+	}
+
+	plane, err := gravity.FitFloorPlane(points, cameraUp, floorPlaneThresholdFraction*medianSpread(points),
+		floorPlaneMaxTiltDeg*math.Pi/180, floorPlaneIterations, rng)
+	if err != nil {
+		return err
+	}
+	alignment, err := gravity.NewAlignment(plane.Normal, cameraUp, gravity.SourceFloorPlane)
+	if err != nil {
+		return err
+	}
+	return orbSvc.setGravityAlignment(alignment)
+}
+
+// medianSpread returns the median distance of points from their centroid. Monocular maps have no known scale, so
+// distance thresholds are relative to it.
+func medianSpread(points []r3.Vector) float64 {
+	var centroid r3.Vector
+	for _, p := range points {
+		centroid = centroid.Add(p)
+	}
+	centroid = centroid.Mul(1 / float64(len(points)))
+	distances := make([]float64, len(points))
+	for i, p := range points {
+		distances[i] = p.Sub(centroid).Norm()
+	}
+	sort.Float64s(distances)
+	return distances[len(distances)/2]
+}
@@ -99,8 +99,10 @@ func testOrbslamPosition(t *testing.T, svc slam.Service, subAlgo viamorbslam3.Su
 		expectedPos = r3.Vector{X: 0.023, Y: -0.036, Z: -0.040}
 		expectedOri = &spatialmath.R4AA{Theta: 0.099, RX: 0.092, RY: 0.993, RZ: -0.068}
 	case subAlgo == viamorbslam3.Rgbd:
-		expectedPos = r3.Vector{X: -0.001, Y: -0.004, Z: -0.008}
+		// RGBD maps are metric, so their positions are reported in mm.
+		expectedPos = r3.Vector{X: -1, Y: -4, Z: -8}
 		expectedOri = &spatialmath.R4AA{Theta: 0.002, RX: 0.602, RY: -0.772, RZ: -0.202}
+		tolerancePos *= 1000
 	}
 
 	position, componentRef, err := svc.GetPosition(context.Background())
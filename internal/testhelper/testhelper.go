@@ -5,7 +5,10 @@ package testhelper
 import (
 	"bufio"
 	"context"
+	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/edaniels/golog"
@@ -120,3 +123,35 @@ func CheckDataDirForExpectedFiles(t *testing.T, dir string, prev int, deleteProc
 	}
 	return len(files), nil
 }
+
+// fakeSLAMExecutableScript stands in for the SLAM process. It completes the startup handshake by reporting the address
+// it was given in the ready file, prints its log lines and then waits to be stopped.
+const fakeSLAMExecutableScript = `#!/bin/sh
+for arg in "$@"; do
+	case "$arg" in
+	-port=*) address="${arg#-port=}" ;;
+	-ready_file=*) ready_file="${arg#-ready_file=}" ;;
+	esac
+done
+if [ -n "$ready_file" ]; then
+	printf '{"address":"%%s","version":"test","modes":["mono","rgbd"],"status":"ready","map_loaded":false}' \
+		"$address" > "$ready_file.tmp" && mv "$ready_file.tmp" "$ready_file"
+fi
+%s
+exec sleep 3600
+`
+
+// WriteFakeSLAMExecutable writes an executable to dir that stands in for the SLAM process, printing logLines once it
+// is ready, and returns its path.
+func WriteFakeSLAMExecutable(dir string, logLines ...string) (string, error) {
+	var printLines string
+	for _, line := range logLines {
+		printLines += "echo '" + strings.ReplaceAll(line, "'", `'\''`) + "'\n"
+	}
+	path := filepath.Join(dir, "fake_orb_grpc_server")
+	//nolint:gosec
+	if err := os.WriteFile(path, []byte(fmt.Sprintf(fakeSLAMExecutableScript, printLines)), 0o755); err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -0,0 +1,63 @@
+package viamorbslam3
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.opencensus.io/trace"
+
+	"github.com/viamrobotics/viam-orb-slam3/dataprocess"
+	"github.com/viamrobotics/viam-orb-slam3/internalstate"
+	"github.com/viamrobotics/viam-orb-slam3/mapmeta"
+)
+
+// internalStateProgressStep is the fraction of the internal state downloaded between progress logs.
+const internalStateProgressStep = 0.1
+
+// downloadInternalState writes the internal state of the SLAM algorithm to the path given in args, relative to the data
+// directory, or by default to a new file in its exports folder. A partial download left at the path by a previous
+// request is resumed if the internal state did not change since.
+func (orbSvc *orbslamService) downloadInternalState(ctx context.Context, args interface{}) (map[string]interface{}, error) {
+	ctx, span := trace.StartSpan(ctx, "viamorbslam3::orbslamService::downloadInternalState")
+	defer span.End()
+
+	var path string
+	if argsMap, ok := args.(map[string]interface{}); ok {
+		path, _ = argsMap["path"].(string)
+	}
+	if path == "" {
+		path = filepath.Join(exportsDirectoryName,
+			"internal_state_"+time.Now().UTC().Format(dataprocess.SlamTimeFormat)+mapmeta.MapExtension)
+	}
+	path, err := orbSvc.dataDirectoryPath(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return nil, errors.Wrap(err, "unable to create download directory")
+	}
+
+	var nextProgressLog float64
+	result, err := internalstate.Download(ctx, orbSvc.clientAlgo, orbSvc.Name().ShortName(), path, internalstate.Options{
+		Progress: func(p internalstate.Progress) {
+			fraction := float64(p.Received) / float64(p.Size)
+			if fraction < nextProgressLog {
+				return
+			}
+			nextProgressLog = fraction + internalStateProgressStep
+			orbSvc.logger.Infow("downloading internal state", "path", path, "received_bytes", p.Received, "size_bytes", p.Size)
+		},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "error downloading internal state")
+	}
+	return map[string]interface{}{
+		"path":    path,
+		"size":    result.Size,
+		"sha256":  result.SHA256,
+		"resumed": result.Resumed,
+	}, nil
+}
@@ -0,0 +1,273 @@
+// Package internalstate downloads the internal state of a SLAM server to a file. The download is verified against the
+// checksum the server reports, written atomically, and resumed from where it stopped if the connection is lost.
+package internalstate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	pb "go.viam.com/api/service/slam/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/viamrobotics/viam-orb-slam3/slamclient"
+)
+
+// Metadata of GetInternalState. The server reports the checksum and size of the internal state and the offset it is
+// sent from in its header. The client may request an offset to resume from, along with the checksum it expects, which
+// the server honors while it still holds that internal state, for a few minutes after sending it.
+const (
+	SHA256Header = "x-internal-state-sha256"
+	SizeHeader   = "x-internal-state-size"
+	OffsetHeader = "x-internal-state-offset"
+)
+
+const (
+	// PartialExtension is appended to the path of a download until it is complete.
+	PartialExtension = ".part"
+	// checksumExtension is appended to the path of a download for the file holding the checksum of its partial
+	// download, to resume it.
+	checksumExtension = ".part.sha256"
+
+	defaultMaxAttempts   = 5
+	defaultRetryInterval = time.Second
+)
+
+// Progress is the state of a download.
+type Progress struct {
+	Received int64
+	Size     int64
+}
+
+// Options configures a download.
+type Options struct {
+	// MaxAttempts is the number of times the internal state is requested, resuming from where the previous attempt
+	// stopped. Defaults to 5.
+	MaxAttempts int
+	// RetryInterval is the time waited before requesting the internal state again. Defaults to 1 second.
+	RetryInterval time.Duration
+	// Progress is called after every chunk is written.
+	Progress func(Progress)
+}
+
+// Result describes a completed download.
+type Result struct {
+	Size   int64
+	SHA256 string
+	// Resumed is set if the download continued from a partial download.
+	Resumed bool
+}
+
+// download is the state of a download across its attempts.
+type download struct {
+	client  pb.SLAMServiceClient
+	name    string
+	path    string
+	opts    Options
+	offset  int64
+	size    int64
+	sha256  string
+	resumed bool
+}
+
+// Download writes the internal state of the SLAM service name to path. A partial download left at path by a previous
+// call is resumed if the server still holds the internal state it was started from.
+func Download(ctx context.Context, client pb.SLAMServiceClient, name, path string, opts Options) (Result, error) {
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = defaultMaxAttempts
+	}
+	if opts.RetryInterval <= 0 {
+		opts.RetryInterval = defaultRetryInterval
+	}
+	d := &download{client: client, name: name, path: path, opts: opts}
+	if err := d.loadPartial(); err != nil {
+		return Result{}, err
+	}
+
+	for attempt := 1; ; attempt++ {
+		retry, err := d.attempt(ctx)
+		if err == nil {
+			break
+		}
+		if !retry || attempt >= opts.MaxAttempts || ctx.Err() != nil {
+			return Result{}, err
+		}
+		select {
+		case <-ctx.Done():
+			return Result{}, ctx.Err()
+		case <-time.After(opts.RetryInterval):
+		}
+	}
+	return d.finish()
+}
+
+// loadPartial resumes from the partial download at the path, if there is one along with its checksum.
+func (d *download) loadPartial() error {
+	//nolint:gosec
+	checksum, err := os.ReadFile(d.path + checksumExtension)
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "error reading partial download")
+	}
+	info, statErr := os.Stat(d.path + PartialExtension)
+	if err != nil || statErr != nil {
+		// Without its checksum, a partial download cannot be resumed.
+		return removeFiles(d.path+PartialExtension, d.path+checksumExtension)
+	}
+	d.offset, d.sha256 = info.Size(), strings.TrimSpace(string(checksum))
+	return nil
+}
+
+// attempt requests the internal state from the current offset and writes it to the partial download. It returns
+// whether the download may be resumed by another attempt if it fails.
+func (d *download) attempt(ctx context.Context) (bool, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if d.offset > 0 {
+		ctx = metadata.AppendToOutgoingContext(ctx,
+			OffsetHeader, strconv.FormatInt(d.offset, 10), SHA256Header, d.sha256)
+	}
+	stream, err := d.client.GetInternalState(ctx, &pb.GetInternalStateRequest{Name: d.name})
+	if err != nil {
+		return isTransient(err), errors.Wrap(err, "error requesting internal state")
+	}
+	header, err := stream.Header()
+	if err != nil {
+		return isTransient(err), errors.Wrap(err, "error requesting internal state")
+	}
+	sha256Sum, size, offset, err := parseHeader(header)
+	if err != nil {
+		return false, err
+	}
+	if offset != 0 && offset != d.offset {
+		return false, errors.Errorf("SLAM server sent the internal state from offset %d, requested offset %d", offset, d.offset)
+	}
+	// The server sends the internal state from the start if it no longer holds the one of the partial download.
+	d.resumed = d.resumed || offset > 0
+	d.offset, d.size, d.sha256 = offset, size, sha256Sum
+
+	file, err := os.OpenFile(d.path+PartialExtension, os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return false, errors.Wrap(err, "error creating partial download")
+	}
+	defer file.Close()
+	if err := file.Truncate(d.offset); err != nil {
+		return false, errors.Wrap(err, "error creating partial download")
+	}
+	if _, err := file.Seek(d.offset, io.SeekStart); err != nil {
+		return false, errors.Wrap(err, "error creating partial download")
+	}
+	if err := os.WriteFile(d.path+checksumExtension, []byte(d.sha256), 0o644); err != nil {
+		return false, errors.Wrap(err, "error creating partial download")
+	}
+
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return isTransient(err), errors.Wrap(err, "error receiving internal state")
+		}
+		chunk := resp.GetInternalStateChunk()
+		if d.offset+int64(len(chunk)) > d.size {
+			return false, errors.Errorf("SLAM server sent more than the %d bytes of its internal state", d.size)
+		}
+		if _, err := file.Write(chunk); err != nil {
+			return false, errors.Wrap(err, "error writing partial download")
+		}
+		d.offset += int64(len(chunk))
+		if d.opts.Progress != nil {
+			d.opts.Progress(Progress{Received: d.offset, Size: d.size})
+		}
+	}
+	if d.offset != d.size {
+		return true, errors.Errorf("internal state stream ended after %d of %d bytes", d.offset, d.size)
+	}
+	if err := file.Sync(); err != nil {
+		return false, errors.Wrap(err, "error writing partial download")
+	}
+	return false, errors.Wrap(file.Close(), "error writing partial download")
+}
+
+// finish verifies the complete partial download and moves it to the path.
+func (d *download) finish() (Result, error) {
+	partialPath := d.path + PartialExtension
+	//nolint:gosec
+	file, err := os.Open(partialPath)
+	if err != nil {
+		return Result{}, errors.Wrap(err, "error reading partial download")
+	}
+	h := sha256.New()
+	_, err = io.Copy(h, file)
+	//nolint:errcheck
+	file.Close()
+	if err != nil {
+		return Result{}, errors.Wrap(err, "error reading partial download")
+	}
+	if sum := hex.EncodeToString(h.Sum(nil)); sum != d.sha256 {
+		// The partial download is corrupt, so it is not resumed.
+		if err := removeFiles(partialPath, d.path+checksumExtension); err != nil {
+			return Result{}, err
+		}
+		return Result{}, errors.Errorf("downloaded internal state has checksum %v, expected %v", sum, d.sha256)
+	}
+	if err := os.Rename(partialPath, d.path); err != nil {
+		return Result{}, errors.Wrap(err, "error moving download")
+	}
+	if err := removeFiles(d.path + checksumExtension); err != nil {
+		return Result{}, err
+	}
+	return Result{Size: d.size, SHA256: d.sha256, Resumed: d.resumed}, nil
+}
+
+// parseHeader returns the checksum, size and offset the server reports in the header of GetInternalState.
+func parseHeader(header metadata.MD) (string, int64, int64, error) {
+	values := make(map[string]string, 3)
+	for _, key := range []string{SHA256Header, SizeHeader, OffsetHeader} {
+		v := header.Get(key)
+		if len(v) != 1 {
+			return "", 0, 0, errors.Errorf("SLAM server does not report %v for its internal state, it may be outdated", key)
+		}
+		values[key] = v[0]
+	}
+	size, err := strconv.ParseInt(values[SizeHeader], 10, 64)
+	if err != nil || size < 0 {
+		return "", 0, 0, errors.Errorf("SLAM server reported an invalid internal state size %v", values[SizeHeader])
+	}
+	offset, err := strconv.ParseInt(values[OffsetHeader], 10, 64)
+	if err != nil || offset < 0 || offset > size {
+		return "", 0, 0, errors.Errorf("SLAM server reported an invalid internal state offset %v", values[OffsetHeader])
+	}
+	return strings.ToLower(values[SHA256Header]), size, offset, nil
+}
+
+// isTransient returns whether err is caused by a lost connection, after which the download may be resumed.
+func isTransient(err error) bool {
+	if errors.Is(err, slamclient.ErrUnavailable) {
+		return true
+	}
+	switch status.Code(errors.Cause(err)) {
+	case codes.Unavailable, codes.Aborted, codes.ResourceExhausted:
+		return true
+	default:
+		return false
+	}
+}
+
+// removeFiles removes the files at paths that exist.
+func removeFiles(paths ...string) error {
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return errors.Wrap(err, "error removing partial download")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package internalstate
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	pb "go.viam.com/api/service/slam/v1"
+	"go.viam.com/test"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const testChunkSize = 4
+
+// fakeServer serves its state in chunks like the SLAM server does, resuming from the requested offset if the
+// requested checksum matches.
+type fakeServer struct {
+	pb.SLAMServiceClient
+	state []byte
+	// reportedSHA256 overrides the checksum of the state in the header, if set.
+	reportedSHA256 string
+	// failAfterChunks makes the next request fail after sending this many chunks, if greater than zero.
+	failAfterChunks int
+	omitHeader      bool
+	requestOffsets  []int64
+}
+
+func (s *fakeServer) checksum() string {
+	sum := sha256.Sum256(s.state)
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *fakeServer) GetInternalState(ctx context.Context, in *pb.GetInternalStateRequest, opts ...grpc.CallOption,
+) (pb.SLAMService_GetInternalStateClient, error) {
+	checksum := s.checksum()
+	var offset int64
+	md, _ := metadata.FromOutgoingContext(ctx)
+	if v := md.Get(OffsetHeader); len(v) == 1 && md.Get(SHA256Header)[0] == checksum {
+		offset, _ = strconv.ParseInt(v[0], 10, 64)
+	}
+	s.requestOffsets = append(s.requestOffsets, offset)
+	if s.reportedSHA256 != "" {
+		checksum = s.reportedSHA256
+	}
+
+	stream := &fakeStream{header: metadata.Pairs(
+		SHA256Header, checksum,
+		SizeHeader, strconv.Itoa(len(s.state)),
+		OffsetHeader, strconv.FormatInt(offset, 10),
+	)}
+	if s.omitHeader {
+		stream.header = metadata.MD{}
+	}
+	for i := offset; i < int64(len(s.state)); i += testChunkSize {
+		end := i + testChunkSize
+		if end > int64(len(s.state)) {
+			end = int64(len(s.state))
+		}
+		stream.chunks = append(stream.chunks, s.state[i:end])
+	}
+	if s.failAfterChunks > 0 {
+		stream.chunks = stream.chunks[:s.failAfterChunks]
+		stream.err = status.Error(codes.Unavailable, "connection lost")
+		s.failAfterChunks = 0
+	}
+	return stream, nil
+}
+
+type fakeStream struct {
+	grpc.ClientStream
+	header metadata.MD
+	chunks [][]byte
+	err    error
+}
+
+func (s *fakeStream) Header() (metadata.MD, error) {
+	return s.header, nil
+}
+
+func (s *fakeStream) Recv() (*pb.GetInternalStateResponse, error) {
+	if len(s.chunks) == 0 {
+		if s.err != nil {
+			return nil, s.err
+		}
+		return nil, io.EOF
+	}
+	chunk := s.chunks[0]
+	s.chunks = s.chunks[1:]
+	return &pb.GetInternalStateResponse{InternalStateChunk: chunk}, nil
+}
+
+func readDownload(t *testing.T, path string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	test.That(t, err, test.ShouldBeNil)
+	_, err = os.Stat(path + PartialExtension)
+	test.That(t, os.IsNotExist(err), test.ShouldBeTrue)
+	_, err = os.Stat(path + checksumExtension)
+	test.That(t, os.IsNotExist(err), test.ShouldBeTrue)
+	return data
+}
+
+func TestDownload(t *testing.T) {
+	state := []byte("the internal state of the SLAM algorithm")
+	opts := Options{RetryInterval: time.Millisecond}
+
+	t.Run("Download in chunks", func(t *testing.T) {
+		server := &fakeServer{state: state}
+		path := filepath.Join(t.TempDir(), "state.osa")
+		var progress []Progress
+		result, err := Download(context.Background(), server, "slam", path, Options{
+			Progress: func(p Progress) { progress = append(progress, p) },
+		})
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, result, test.ShouldResemble, Result{Size: int64(len(state)), SHA256: server.checksum()})
+		test.That(t, readDownload(t, path), test.ShouldResemble, state)
+		test.That(t, progress, test.ShouldHaveLength, 10)
+		test.That(t, progress[0], test.ShouldResemble, Progress{Received: testChunkSize, Size: int64(len(state))})
+		test.That(t, progress[9], test.ShouldResemble, Progress{Received: int64(len(state)), Size: int64(len(state))})
+	})
+
+	t.Run("Resume after the connection is lost", func(t *testing.T) {
+		server := &fakeServer{state: state, failAfterChunks: 3}
+		path := filepath.Join(t.TempDir(), "state.osa")
+		result, err := Download(context.Background(), server, "slam", path, opts)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, result.Resumed, test.ShouldBeTrue)
+		test.That(t, server.requestOffsets, test.ShouldResemble, []int64{0, 3 * testChunkSize})
+		test.That(t, readDownload(t, path), test.ShouldResemble, state)
+	})
+
+	t.Run("Resume a partial download of a previous call", func(t *testing.T) {
+		server := &fakeServer{state: state, failAfterChunks: 2}
+		path := filepath.Join(t.TempDir(), "state.osa")
+		_, err := Download(context.Background(), server, "slam", path, Options{MaxAttempts: 1})
+		test.That(t, err.Error(), test.ShouldContainSubstring, "connection lost")
+		partial, err := os.ReadFile(path + PartialExtension)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, partial, test.ShouldResemble, state[:2*testChunkSize])
+
+		result, err := Download(context.Background(), server, "slam", path, opts)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, result.Resumed, test.ShouldBeTrue)
+		test.That(t, server.requestOffsets, test.ShouldResemble, []int64{0, 2 * testChunkSize})
+		test.That(t, readDownload(t, path), test.ShouldResemble, state)
+	})
+
+	t.Run("Restart when the internal state changed", func(t *testing.T) {
+		server := &fakeServer{state: state, failAfterChunks: 2}
+		path := filepath.Join(t.TempDir(), "state.osa")
+		_, err := Download(context.Background(), server, "slam", path, Options{MaxAttempts: 1})
+		test.That(t, err, test.ShouldNotBeNil)
+
+		server.state = bytes.ToUpper(state)
+		result, err := Download(context.Background(), server, "slam", path, opts)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, result.Resumed, test.ShouldBeFalse)
+		test.That(t, readDownload(t, path), test.ShouldResemble, server.state)
+	})
+
+	t.Run("Checksum mismatch", func(t *testing.T) {
+		server := &fakeServer{state: state, reportedSHA256: "0123"}
+		path := filepath.Join(t.TempDir(), "state.osa")
+		_, err := Download(context.Background(), server, "slam", path, opts)
+		test.That(t, err.Error(), test.ShouldContainSubstring, "downloaded internal state has checksum "+server.checksum())
+		entries, err := os.ReadDir(filepath.Dir(path))
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, entries, test.ShouldBeEmpty)
+	})
+
+	t.Run("Server without checksum", func(t *testing.T) {
+		server := &fakeServer{state: state, omitHeader: true}
+		_, err := Download(context.Background(), server, "slam", filepath.Join(t.TempDir(), "state.osa"), opts)
+		test.That(t, err.Error(), test.ShouldContainSubstring, "SLAM server does not report x-internal-state-sha256")
+	})
+}
@@ -0,0 +1,65 @@
+package viamorbslam3
+
+import (
+	"context"
+	"os"
+
+	"github.com/pkg/errors"
+
+	orbSlamConfig "github.com/viamrobotics/viam-orb-slam3/config"
+	"github.com/viamrobotics/viam-orb-slam3/mapmeta"
+)
+
+// checkMapCompatibility returns an error if the map at mapPath, given without its extension, was made with a mode,
+// camera or vocabulary that is incompatible with the current ones. Maps without metadata cannot be checked and are
+// assumed to be compatible.
+func (orbSvc *orbslamService) checkMapCompatibility(mapPath string) error {
+	saved, err := mapmeta.Read(mapPath + mapmeta.MapExtension)
+	if os.IsNotExist(err) {
+		orbSvc.logger.Debugf("map %v has no metadata, skipping its compatibility check", mapPath)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	// The checksum is computed in the background, so wait for it.
+	orbSvc.vocabularyChecksum()
+	return mapmeta.CheckCompatibility(saved, orbSvc.mapMetadata(0, 0), mapmeta.IntrinsicsTolerance)
+}
+
+// resolveMapToLoad returns the map to load, given without its extension, and its timestamp, or empty strings if a new
+// map is started because the map is incompatible and the policy is to start fresh.
+func (orbSvc *orbslamService) resolveMapToLoad(ctx context.Context, timestamp, mapPath string) (string, string, error) {
+	if mapPath == "" {
+		return timestamp, mapPath, nil
+	}
+	err := orbSvc.checkMapCompatibility(mapPath)
+	if err == nil {
+		return timestamp, mapPath, nil
+	}
+	if orbSvc.mapCompatibility != orbSlamConfig.MapCompatibilityStartFresh {
+		return "", "", errors.Wrapf(err, "map %v is incompatible with the current settings, remove it or set "+
+			"map_compatibility to %v", mapPath, orbSlamConfig.MapCompatibilityStartFresh)
+	}
+	orbSvc.logger.Warnw("starting a new map, since the saved map is incompatible with the current settings",
+		"map", mapPath, "reason", err)
+	return "", "", orbSvc.discardMapState(ctx)
+}
+
+// discardMapState forgets the scale and gravity alignment of the saved maps when a new map is started instead of
+// loading one, since they do not apply to it.
+func (orbSvc *orbslamService) discardMapState(ctx context.Context) error {
+	orbSvc.scaleEstimator.Reset()
+	orbSvc.gravityMu.Lock()
+	orbSvc.gravity = nil
+	orbSvc.gravityMu.Unlock()
+	for _, path := range []string{orbSvc.scalePath(), orbSvc.gravityPath()} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if orbSvc.imu != nil {
+		return orbSvc.alignWithIMU(ctx)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package viamorbslam3
+
+import (
+	"context"
+	"encoding/base64"
+
+	"github.com/golang/geo/r3"
+	"github.com/pkg/errors"
+
+	"github.com/viamrobotics/viam-orb-slam3/pcd"
+)
+
+// mapDeltaHistorySize is the number of versions of the map kept to compute deltas from. Clients that fall further
+// behind receive the whole map.
+const mapDeltaHistorySize = 8
+
+// getPointCloudMapDelta returns the points added to and removed from the point cloud map since the version given by
+// since_version in args. The whole map is returned instead if no version is given, the version is too old, or the delta
+// would be as large as the map. Points are returned as base64 encoded PCD files.
+//
+// Deltas are computed from the points reported by the SLAM process, and the current scale, gravity alignment and
+// frame are applied to the points of the delta afterwards. Each version is recorded with the transform it was sent
+// with, and the whole map is returned once the transform changes, since a refined scale estimate or gravity alignment
+// moves every point the client holds. Point cloud filters are not applied, since filters such as the voxel grid depend
+// on the whole map.
+func (orbSvc *orbslamService) getPointCloudMapDelta(ctx context.Context, args interface{}) (map[string]interface{}, error) {
+	since, hasSince, err := parseSinceVersion(args)
+	if err != nil {
+		return nil, err
+	}
+
+	position, err := orbSvc.getSLAMPosition(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if position.mapVersion == nil {
+		return nil, errors.New("the SLAM server does not report the version of its map")
+	}
+	version := *position.mapVersion
+	data, err := orbSvc.cachedPointCloudMapData(ctx, version)
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting point cloud map")
+	}
+	points, err := pcd.Read(data)
+	if err != nil {
+		return nil, err
+	}
+	pointTransform, err := orbSvc.pointCloudTransform()
+	if err != nil {
+		return nil, err
+	}
+	transform := pointTransform.fn()
+
+	resp := map[string]interface{}{"version": version}
+	if hasSince {
+		if delta, ok := orbSvc.mapDeltas.Delta(since, points, pointTransform); ok {
+			orbSvc.mapDeltas.Add(version, points, pointTransform)
+			resp["full"] = false
+			resp["added"] = encodePoints(transformPoints(delta.Added, transform))
+			resp["removed"] = encodePoints(transformPoints(delta.Removed, transform))
+			return resp, nil
+		}
+	}
+	orbSvc.mapDeltas.Add(version, points, pointTransform)
+	resp["full"] = true
+	resp["map"] = encodePoints(transformPoints(points, transform))
+	return resp, nil
+}
+
+// transformPoints returns a copy of points with transform, if any, applied to every point.
+func transformPoints(points []r3.Vector, transform func(r3.Vector) r3.Vector) []r3.Vector {
+	transformed := make([]r3.Vector, len(points))
+	copy(transformed, points)
+	return processPoints(transformed, transform, nil)
+}
+
+// parseSinceVersion returns the since_version in the args of a map delta request, and false if none is given.
+func parseSinceVersion(args interface{}) (int64, bool, error) {
+	argsMap, ok := args.(map[string]interface{})
+	if !ok {
+		return 0, false, nil
+	}
+	val, ok := argsMap["since_version"]
+	if !ok || val == nil {
+		return 0, false, nil
+	}
+	// Numbers are float64 once converted to and from a protobuf struct.
+	since, ok := val.(float64)
+	if !ok || since != float64(int64(since)) {
+		return 0, false, errors.Errorf("since_version must be an integer, got %v", val)
+	}
+	return int64(since), true, nil
+}
+
+// encodePoints encodes points as a base64 encoded PCD file.
+func encodePoints(points []r3.Vector) string {
+	return base64.StdEncoding.EncodeToString(pcd.Write(points))
+}
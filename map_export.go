@@ -0,0 +1,108 @@
+package viamorbslam3
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/golang/geo/r3"
+	"github.com/pkg/errors"
+
+	"github.com/viamrobotics/viam-orb-slam3/atomicfile"
+	"github.com/viamrobotics/viam-orb-slam3/dataprocess"
+	"github.com/viamrobotics/viam-orb-slam3/mapexport"
+	"github.com/viamrobotics/viam-orb-slam3/pcd"
+)
+
+const exportsDirectoryName = "exports"
+
+// exportMap writes the current point cloud map, or a stored PCD snapshot, to the exports folder of the data directory
+// in the requested format, and returns the path of the file along with the number of points and their bounds.
+func (orbSvc *orbslamService) exportMap(ctx context.Context, args interface{}) (map[string]interface{}, error) {
+	argsMap, ok := args.(map[string]interface{})
+	if !ok {
+		return nil, errors.Errorf("%v expects an object with a format, got %v", ExportMapCommand, args)
+	}
+	formatName, _ := argsMap["format"].(string)
+	format, err := mapexport.ParseFormat(formatName)
+	if err != nil {
+		return nil, err
+	}
+
+	var points []r3.Vector
+	if source, _ := argsMap["source"].(string); source != "" {
+		if points, err = orbSvc.readSnapshot(source); err != nil {
+			return nil, err
+		}
+	} else {
+		filters, err := orbSvc.requestFilters(argsMap)
+		if err != nil {
+			return nil, err
+		}
+		data, err := orbSvc.processedPointCloudMapData(ctx, filters)
+		if err != nil {
+			return nil, errors.Wrap(err, "error getting point cloud map")
+		}
+		if points, err = pcd.Read(data); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	var buf bytes.Buffer
+	if err := mapexport.Encode(&buf, points, format, now); err != nil {
+		return nil, errors.Wrap(err, "error encoding map")
+	}
+	dir := filepath.Join(orbSvc.dataDirectory, exportsDirectoryName)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, errors.Wrap(err, "unable to create exports directory")
+	}
+	path := filepath.Join(dir, "map_"+now.UTC().Format(dataprocess.SlamTimeFormat)+format.Extension())
+	if err := atomicfile.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		return nil, errors.Wrap(err, "unable to write exported map")
+	}
+
+	resp := map[string]interface{}{
+		"path":   path,
+		"format": string(format),
+		"points": len(points),
+	}
+	if bounds, ok := mapexport.ComputeBounds(points); ok {
+		resp["bounds"] = map[string]interface{}{"min": vectorToMap(bounds.Min), "max": vectorToMap(bounds.Max)}
+	}
+	return resp, nil
+}
+
+// readSnapshot reads the points of a PCD file at path, which is relative to the data directory and must be within it.
+func (orbSvc *orbslamService) readSnapshot(path string) ([]r3.Vector, error) {
+	fullPath, err := orbSvc.dataDirectoryPath(path)
+	if err != nil {
+		return nil, err
+	}
+	//nolint:gosec
+	data, err := os.ReadFile(fullPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read map snapshot")
+	}
+	return pcd.Read(data)
+}
+
+// dataDirectoryPath resolves path relative to the data directory, and returns an error if it is outside of it.
+func (orbSvc *orbslamService) dataDirectoryPath(path string) (string, error) {
+	root, err := filepath.Abs(orbSvc.dataDirectory)
+	if err != nil {
+		return "", errors.Wrap(err, "unable to resolve the data directory")
+	}
+	fullPath := path
+	if !filepath.IsAbs(fullPath) {
+		fullPath = filepath.Join(root, fullPath)
+	}
+	rel, err := filepath.Rel(root, filepath.Clean(fullPath))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", errors.Errorf("%v is not within the data directory %v", path, root)
+	}
+	return fullPath, nil
+}
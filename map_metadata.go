@@ -0,0 +1,135 @@
+package viamorbslam3
+
+import (
+	"encoding/json"
+	"runtime/debug"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/viamrobotics/viam-orb-slam3/mapmeta"
+	"github.com/viamrobotics/viam-orb-slam3/slamlog"
+)
+
+const (
+	modulePath            = "github.com/viamrobotics/viam-orb-slam3"
+	brownConradyModelName = "brown_conrady"
+	unknownModuleVersion  = "unknown"
+)
+
+// errRemoteMaps is returned by the commands that manage the maps in the data directory, since an externally managed
+// SLAM server reads the maps in its own.
+var errRemoteMaps = errors.New("maps cannot be managed when using slam_server, which keeps them in its own data directory")
+
+// moduleVersion returns the version of this module in the running binary.
+func moduleVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return unknownModuleVersion
+	}
+	if info.Main.Path == modulePath {
+		return info.Main.Version
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == modulePath {
+			return dep.Version
+		}
+	}
+	return unknownModuleVersion
+}
+
+// setMapCamera records the intrinsics and distortion of the primary camera given to the SLAM algorithm.
+func (orbSvc *orbslamService) setMapCamera(settings *ORBsettings) {
+	orbSvc.metadataMu.Lock()
+	defer orbSvc.metadataMu.Unlock()
+	orbSvc.mapCamera = &mapmeta.Camera{
+		Name: orbSvc.primarySensorName,
+		Intrinsics: &mapmeta.Intrinsics{
+			Width:  settings.Width,
+			Height: settings.Height,
+			Fx:     settings.Fx,
+			Fy:     settings.Fy,
+			Ppx:    settings.Ppx,
+			Ppy:    settings.Ppy,
+		},
+		Distortion: &mapmeta.Distortion{
+			Model: brownConradyModelName,
+			Parameters: []float64{
+				settings.RadialK1, settings.RadialK2, settings.RadialK3, settings.TangentialP1, settings.TangentialP2,
+			},
+		},
+	}
+}
+
+// mapMetadata returns the metadata of a map saved now, with the given size. The vocabulary checksum is left out if it
+// has not been computed yet.
+func (orbSvc *orbslamService) mapMetadata(keyFrames, mapPoints int) mapmeta.Metadata {
+	orbSvc.metadataMu.Lock()
+	defer orbSvc.metadataMu.Unlock()
+
+	var cameras []mapmeta.Camera
+	for _, name := range orbSvc.sensors {
+		if orbSvc.mapCamera != nil && name == orbSvc.mapCamera.Name {
+			cameras = append(cameras, *orbSvc.mapCamera)
+			continue
+		}
+		cameras = append(cameras, mapmeta.Camera{Name: name})
+	}
+	configParams := make(map[string]string, len(orbSvc.configParams))
+	for k, v := range orbSvc.configParams {
+		configParams[k] = v
+	}
+	return mapmeta.Metadata{
+		CreatedAt:        time.Now().UTC(),
+		Mode:             string(orbSvc.subAlgo),
+		Cameras:          cameras,
+		ConfigParams:     configParams,
+		VocabularySHA256: orbSvc.vocabularySHA256,
+		ModuleVersion:    moduleVersion(),
+		ORBSLAMVersion:   orbSvc.orbslamVersion,
+		KeyFrames:        keyFrames,
+		MapPoints:        mapPoints,
+	}
+}
+
+// handleSLAMEvent writes the metadata sidecar of every map the SLAM process saves.
+func (orbSvc *orbslamService) handleSLAMEvent(event slamlog.Event) {
+	if event.Type != slamlog.MapSaved || event.MapPath == "" {
+		return
+	}
+	if err := mapmeta.Write(event.MapPath, orbSvc.mapMetadata(event.KeyFrames, event.MapPoints)); err != nil {
+		orbSvc.logger.Warnw("unable to write map metadata", "map", event.MapPath, "error", err)
+	}
+}
+
+// listMapsResponse returns the saved maps and their metadata in the format of the response of ListMapsCommand.
+func (orbSvc *orbslamService) listMapsResponse() (map[string]interface{}, error) {
+	if orbSvc.remoteSLAMServer {
+		return nil, errRemoteMaps
+	}
+	maps, err := orbSvc.listMaps()
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing maps")
+	}
+	list := make([]interface{}, 0, len(maps))
+	for _, m := range maps {
+		entry := map[string]interface{}{
+			"path":      m.path + mapmeta.MapExtension,
+			"timestamp": m.timestamp.UTC().Format(time.RFC3339),
+		}
+		if m.metadata != nil {
+			// Round trip the metadata through JSON so that it has the format of the sidecar.
+			data, err := json.Marshal(m.metadata)
+			if err != nil {
+				return nil, err
+			}
+			var metadata map[string]interface{}
+			if err := json.Unmarshal(data, &metadata); err != nil {
+				return nil, err
+			}
+			entry["metadata"] = metadata
+		}
+		list = append(list, entry)
+	}
+	return map[string]interface{}{"maps": list}, nil
+}
@@ -0,0 +1,233 @@
+package viamorbslam3
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.opencensus.io/trace"
+	goutils "go.viam.com/utils"
+	"go.viam.com/utils/pexec"
+
+	"github.com/viamrobotics/viam-orb-slam3/dataprocess"
+	"github.com/viamrobotics/viam-orb-slam3/mapmeta"
+	"github.com/viamrobotics/viam-orb-slam3/mapupload"
+)
+
+// errOfflineMapUpload is returned by the map upload commands in offline mode, since the SLAM process does not load
+// maps while it processes the data in the data directory.
+var errOfflineMapUpload = errors.New("maps can only be uploaded when use_live_data is true")
+
+// mapUploadArgs are the arguments of the map upload commands. Each command uses a subset of them.
+type mapUploadArgs struct {
+	UploadID string            `json:"upload_id"`
+	Size     int64             `json:"size"`
+	SHA256   string            `json:"sha256"`
+	Metadata *mapmeta.Metadata `json:"metadata"`
+	Offset   int64             `json:"offset"`
+	// Data is base64 encoded in the command.
+	Data []byte `json:"data"`
+}
+
+// decodeMapUploadArgs decodes the arguments of a map upload command.
+func decodeMapUploadArgs(args interface{}) (mapUploadArgs, error) {
+	var uploadArgs mapUploadArgs
+	argsMap, ok := args.(map[string]interface{})
+	if !ok {
+		return uploadArgs, errors.New("map upload arguments are required")
+	}
+	data, err := json.Marshal(argsMap)
+	if err != nil {
+		return uploadArgs, errors.Wrap(err, "invalid map upload arguments")
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&uploadArgs); err != nil {
+		return uploadArgs, errors.Wrap(err, "invalid map upload arguments")
+	}
+	return uploadArgs, nil
+}
+
+// beginMapUpload starts an upload of a map of the size and checksum given in args, replacing any upload in progress.
+// The metadata of the map may be given as well, as list_maps returns it, to check that the map is compatible with the
+// current settings before it is loaded.
+func (orbSvc *orbslamService) beginMapUpload(args interface{}) (map[string]interface{}, error) {
+	if orbSvc.remoteSLAMServer {
+		return nil, errRemoteMaps
+	}
+	if !orbSvc.useLiveData {
+		return nil, errOfflineMapUpload
+	}
+	uploadArgs, err := decodeMapUploadArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	if uploadArgs.Metadata != nil {
+		// The checksum is computed in the background, so wait for it.
+		orbSvc.vocabularyChecksum()
+		if err := mapmeta.CheckCompatibility(*uploadArgs.Metadata, orbSvc.mapMetadata(0, 0), mapmeta.IntrinsicsTolerance); err != nil {
+			return nil, errors.Wrap(err, "uploaded map is incompatible with the current settings")
+		}
+	}
+
+	orbSvc.uploadMu.Lock()
+	defer orbSvc.uploadMu.Unlock()
+	orbSvc.abortMapUpload()
+	upload, err := mapupload.New(filepath.Join(orbSvc.dataDirectory, "map"), uploadArgs.Size, uploadArgs.SHA256)
+	if err != nil {
+		return nil, err
+	}
+	orbSvc.mapUpload = upload
+	orbSvc.mapUploadMetadata = uploadArgs.Metadata
+	return map[string]interface{}{"upload_id": upload.ID}, nil
+}
+
+// uploadMapChunk appends the chunk in args to the upload in progress.
+func (orbSvc *orbslamService) uploadMapChunk(args interface{}) (map[string]interface{}, error) {
+	uploadArgs, err := decodeMapUploadArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	orbSvc.uploadMu.Lock()
+	defer orbSvc.uploadMu.Unlock()
+	upload, err := orbSvc.currentMapUpload(uploadArgs.UploadID)
+	if err != nil {
+		return nil, err
+	}
+	received, err := upload.Write(uploadArgs.Offset, uploadArgs.Data)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"received": received}, nil
+}
+
+// finishMapUpload verifies the upload in progress and saves it as the newest map, along with its metadata. The SLAM
+// process is then restarted in localization mode to load the map.
+func (orbSvc *orbslamService) finishMapUpload(ctx context.Context, args interface{}) (map[string]interface{}, error) {
+	ctx, span := trace.StartSpan(ctx, "viamorbslam3::orbslamService::finishMapUpload")
+	defer span.End()
+
+	uploadArgs, err := decodeMapUploadArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	upload, uploadMetadata, err := orbSvc.takeVerifiedMapUpload(uploadArgs.UploadID)
+	if err != nil {
+		return nil, err
+	}
+	abort := func() {
+		if err := upload.Abort(); err != nil {
+			orbSvc.logger.Warnw("unable to remove the partial map upload", "error", err)
+		}
+	}
+
+	// Close waits for slamProcessMu, so the restart gives up once the service is closed.
+	ctx, cancel := orbSvc.untilClosed(ctx)
+	defer cancel()
+	orbSvc.slamProcessMu.Lock()
+	defer orbSvc.slamProcessMu.Unlock()
+	// The SLAM process is stopped first, so that a map it saves does not replace the uploaded one as the newest.
+	if err := orbSvc.slamProcess.Stop(); err != nil {
+		abort()
+		return nil, errors.Wrap(err, "problem stopping slam process")
+	}
+
+	timestamp := time.Now().UTC().Format(dataprocess.SlamTimeFormat)
+	mapPath := filepath.Join(orbSvc.dataDirectory, "map",
+		orbSvc.primarySensorName+dataprocess.TimestampSeparator+timestamp+mapmeta.MapExtension)
+	metadata := orbSvc.mapMetadata(0, 0)
+	if uploadMetadata != nil {
+		metadata = *uploadMetadata
+	}
+	if err := upload.Finish(mapPath); err != nil {
+		abort()
+		if startErr := orbSvc.startNewSLAMProcess(ctx); startErr != nil {
+			orbSvc.logger.Errorw("error restarting slam process", "error", startErr)
+		}
+		return nil, err
+	}
+	if err := mapmeta.Write(mapPath, metadata); err != nil {
+		return nil, err
+	}
+	orbSvc.logger.Infow("saved uploaded map", "map", mapPath, "keyframes", metadata.KeyFrames, "map_points", metadata.MapPoints)
+
+	if err := orbSvc.restartInLocalizationMode(ctx); err != nil {
+		return nil, errors.Wrap(err, "error restarting slam process with the uploaded map")
+	}
+	return map[string]interface{}{"map": mapPath, "reloaded": true}, nil
+}
+
+// takeVerifiedMapUpload verifies the upload in progress if it has the given id, and takes it over along with its
+// metadata, so that uploadMu is not held while the upload is saved and loaded, which would block Close and the other
+// upload commands. An upload that fails verification is discarded.
+func (orbSvc *orbslamService) takeVerifiedMapUpload(id string) (*mapupload.Upload, *mapmeta.Metadata, error) {
+	orbSvc.uploadMu.Lock()
+	defer orbSvc.uploadMu.Unlock()
+	upload, err := orbSvc.currentMapUpload(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := upload.Verify(); err != nil {
+		orbSvc.abortMapUpload()
+		return nil, nil, err
+	}
+	metadata := orbSvc.mapUploadMetadata
+	orbSvc.mapUpload, orbSvc.mapUploadMetadata = nil, nil
+	return upload, metadata, nil
+}
+
+// untilClosed returns a context that is canceled along with ctx or once the service is closed.
+func (orbSvc *orbslamService) untilClosed(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	goutils.PanicCapturingGo(func() {
+		select {
+		case <-orbSvc.closeCtx.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	})
+	return ctx, cancel
+}
+
+// restartInLocalizationMode starts a new SLAM process in localization mode, which loads the newest map. The previous
+// process must be stopped. The state that depends on the previous map is discarded.
+func (orbSvc *orbslamService) restartInLocalizationMode(ctx context.Context) error {
+	orbSvc.mapRateSec = 0
+	orbSvc.trajectory.Reset()
+	if err := orbSvc.discardMapState(ctx); err != nil {
+		return err
+	}
+	if err := orbSvc.orbGenYAML(ctx, orbSvc.primaryCamera); err != nil {
+		return errors.Wrap(err, "error generating .yaml config")
+	}
+	return orbSvc.startNewSLAMProcess(ctx)
+}
+
+// startNewSLAMProcess starts the SLAM process again after it was stopped. A process manager cannot be restarted once
+// stopped, so a new one is used.
+func (orbSvc *orbslamService) startNewSLAMProcess(ctx context.Context) error {
+	orbSvc.slamProcess = pexec.NewProcessManager(orbSvc.logger)
+	return orbSvc.StartSLAMProcess(ctx)
+}
+
+// currentMapUpload returns the upload in progress if it has the given id. uploadMu must be held.
+func (orbSvc *orbslamService) currentMapUpload(id string) (*mapupload.Upload, error) {
+	if orbSvc.mapUpload == nil || orbSvc.mapUpload.ID != id {
+		return nil, errors.Errorf("no map upload in progress with upload_id %v", id)
+	}
+	return orbSvc.mapUpload, nil
+}
+
+// abortMapUpload discards the upload in progress, if any. uploadMu must be held.
+func (orbSvc *orbslamService) abortMapUpload() {
+	if orbSvc.mapUpload == nil {
+		return
+	}
+	if err := orbSvc.mapUpload.Abort(); err != nil {
+		orbSvc.logger.Warnw("unable to remove the partial map upload", "error", err)
+	}
+	orbSvc.mapUpload, orbSvc.mapUploadMetadata = nil, nil
+}
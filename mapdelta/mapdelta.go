@@ -0,0 +1,116 @@
+// Package mapdelta computes the points added to and removed from a point cloud map between two of its versions.
+package mapdelta
+
+import (
+	"sync"
+
+	"github.com/golang/geo/r3"
+)
+
+// Delta is the change of a map from one version to another.
+type Delta struct {
+	Added   []r3.Vector
+	Removed []r3.Vector
+}
+
+// Size is the number of points in the delta.
+func (d Delta) Size() int {
+	return len(d.Added) + len(d.Removed)
+}
+
+// Diff returns the points of to that are not in from as added, and the points of from that are not in to as
+// removed. Points are compared by their exact coordinates, and repeated points are counted as many times as they
+// appear.
+func Diff(from, to []r3.Vector) Delta {
+	counts := make(map[r3.Vector]int, len(from))
+	for _, p := range from {
+		counts[p]++
+	}
+	var delta Delta
+	for _, p := range to {
+		if counts[p] > 0 {
+			counts[p]--
+			continue
+		}
+		delta.Added = append(delta.Added, p)
+	}
+	for _, p := range from {
+		if counts[p] > 0 {
+			counts[p]--
+			delta.Removed = append(delta.Removed, p)
+		}
+	}
+	return delta
+}
+
+// snapshot is the points of a version of a map, along with the transform they were sent with.
+type snapshot[T comparable] struct {
+	version   int64
+	points    []r3.Vector
+	transform T
+}
+
+// History keeps the points of the latest versions of a map, to compute deltas from them. Points are kept as the map
+// reports them, along with the transform of type T applied to them before they were sent, since a delta only applies
+// to the points a client holds if it is sent with the same transform. It is safe for concurrent use.
+type History[T comparable] struct {
+	mu        sync.Mutex
+	size      int
+	snapshots []snapshot[T]
+}
+
+// NewHistory returns a history that keeps the given number of versions, at least one.
+func NewHistory[T comparable](size int) *History[T] {
+	if size < 1 {
+		size = 1
+	}
+	return &History[T]{size: size}
+}
+
+// Add records the points of a version of the map and the transform they were sent with, replacing what was recorded
+// for it, if anything. The points must not be modified afterwards.
+func (h *History[T]) Add(version int64, points []r3.Vector, transform T) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i, s := range h.snapshots {
+		if s.version == version {
+			h.snapshots[i].points, h.snapshots[i].transform = points, transform
+			return
+		}
+	}
+	if len(h.snapshots) == h.size {
+		h.snapshots = append(h.snapshots[:0], h.snapshots[1:]...)
+	}
+	h.snapshots = append(h.snapshots, snapshot[T]{version: version, points: points, transform: transform})
+}
+
+// Reset forgets all recorded versions, e.g. because a different map is loaded.
+func (h *History[T]) Reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.snapshots = nil
+}
+
+// Delta returns the change of the map from version since to the points of the current version, and false if the
+// points of version since are no longer kept, were sent with a transform other than the current one, or the delta
+// would be at least as large as the current map, in which case the whole map should be sent instead.
+func (h *History[T]) Delta(since int64, current []r3.Vector, transform T) (Delta, bool) {
+	h.mu.Lock()
+	var from []r3.Vector
+	found := false
+	for _, s := range h.snapshots {
+		if s.version == since {
+			from, found = s.points, s.transform == transform
+			break
+		}
+	}
+	h.mu.Unlock()
+	if !found {
+		return Delta{}, false
+	}
+	delta := Diff(from, current)
+	if len(current) > 0 && delta.Size() >= len(current) {
+		return Delta{}, false
+	}
+	return delta, true
+}
@@ -0,0 +1,77 @@
+package mapdelta
+
+import (
+	"testing"
+
+	"github.com/golang/geo/r3"
+	"go.viam.com/test"
+)
+
+func TestDiff(t *testing.T) {
+	from := []r3.Vector{{X: 1}, {X: 2}, {X: 2}, {X: 3}}
+	to := []r3.Vector{{X: 2}, {X: 3}, {X: 4}, {X: 4}}
+	delta := Diff(from, to)
+	test.That(t, delta.Added, test.ShouldResemble, []r3.Vector{{X: 4}, {X: 4}})
+	test.That(t, delta.Removed, test.ShouldResemble, []r3.Vector{{X: 1}, {X: 2}})
+	test.That(t, delta.Size(), test.ShouldEqual, 4)
+
+	test.That(t, Diff(to, to).Size(), test.ShouldEqual, 0)
+	test.That(t, Diff(nil, to).Added, test.ShouldResemble, to)
+	test.That(t, Diff(from, nil).Removed, test.ShouldResemble, from)
+}
+
+func TestHistory(t *testing.T) {
+	base := make([]r3.Vector, 10)
+	for i := range base {
+		base[i] = r3.Vector{X: float64(i)}
+	}
+	history := NewHistory[string](2)
+	history.Add(1, base, "")
+
+	t.Run("Delta from a kept version", func(t *testing.T) {
+		current := append(append([]r3.Vector{}, base[1:]...), r3.Vector{Y: 1})
+		delta, ok := history.Delta(1, current, "")
+		test.That(t, ok, test.ShouldBeTrue)
+		test.That(t, delta.Added, test.ShouldResemble, []r3.Vector{{Y: 1}})
+		test.That(t, delta.Removed, test.ShouldResemble, []r3.Vector{{X: 0}})
+	})
+
+	t.Run("Full refresh when the delta is as large as the map", func(t *testing.T) {
+		_, ok := history.Delta(1, []r3.Vector{{Z: 1}, {Z: 2}}, "")
+		test.That(t, ok, test.ShouldBeFalse)
+	})
+
+	t.Run("Full refresh when the version is no longer kept", func(t *testing.T) {
+		history.Add(2, base, "")
+		history.Add(3, base, "")
+		_, ok := history.Delta(1, base, "")
+		test.That(t, ok, test.ShouldBeFalse)
+		_, ok = history.Delta(4, base, "")
+		test.That(t, ok, test.ShouldBeFalse)
+		delta, ok := history.Delta(2, base, "")
+		test.That(t, ok, test.ShouldBeTrue)
+		test.That(t, delta.Size(), test.ShouldEqual, 0)
+	})
+
+	t.Run("Replaces the points of a version", func(t *testing.T) {
+		history.Add(3, base[:5], "")
+		delta, ok := history.Delta(3, base, "")
+		test.That(t, ok, test.ShouldBeTrue)
+		test.That(t, delta.Added, test.ShouldResemble, base[5:])
+	})
+
+	t.Run("Full refresh when the transform changed", func(t *testing.T) {
+		history.Add(4, base, "scaled")
+		_, ok := history.Delta(4, base, "aligned")
+		test.That(t, ok, test.ShouldBeFalse)
+		delta, ok := history.Delta(4, base, "scaled")
+		test.That(t, ok, test.ShouldBeTrue)
+		test.That(t, delta.Size(), test.ShouldEqual, 0)
+	})
+
+	t.Run("Full refresh after a reset", func(t *testing.T) {
+		history.Reset()
+		_, ok := history.Delta(3, base, "")
+		test.That(t, ok, test.ShouldBeFalse)
+	})
+}
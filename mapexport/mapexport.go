@@ -0,0 +1,240 @@
+// Package mapexport encodes point cloud maps in formats for other tools: PLY, ASCII PCD and LAS.
+package mapexport
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/golang/geo/r3"
+	"github.com/pkg/errors"
+)
+
+// Format is a point cloud file format.
+type Format string
+
+const (
+	// FormatPLY is the binary little endian PLY format, with float32 coordinates.
+	FormatPLY Format = "ply"
+	// FormatPCDASCII is the ASCII PCD format, with float32 coordinates.
+	FormatPCDASCII Format = "pcd_ascii"
+	// FormatLAS is the LAS 1.2 format with point data record format 0. Coordinates are stored as integers, scaled
+	// by a power of ten that is at most 1e-6.
+	FormatLAS Format = "las"
+)
+
+// Formats lists the supported formats.
+var Formats = []Format{FormatPLY, FormatPCDASCII, FormatLAS}
+
+// ParseFormat returns the format with the given name.
+func ParseFormat(name string) (Format, error) {
+	for _, format := range Formats {
+		if string(format) == name {
+			return format, nil
+		}
+	}
+	return "", errors.Errorf("unsupported map export format %q, expected one of %v", name, Formats)
+}
+
+// Extension returns the file extension of the format, including the dot.
+func (format Format) Extension() string {
+	switch format {
+	case FormatPLY:
+		return ".ply"
+	case FormatPCDASCII:
+		return ".pcd"
+	case FormatLAS:
+		return ".las"
+	default:
+		return ""
+	}
+}
+
+// Bounds is the axis aligned bounding box of a point cloud.
+type Bounds struct {
+	Min r3.Vector
+	Max r3.Vector
+}
+
+// ComputeBounds returns the bounds of points, and false if there are no points.
+func ComputeBounds(points []r3.Vector) (Bounds, bool) {
+	if len(points) == 0 {
+		return Bounds{}, false
+	}
+	bounds := Bounds{Min: points[0], Max: points[0]}
+	for _, p := range points[1:] {
+		bounds.Min = r3.Vector{X: math.Min(bounds.Min.X, p.X), Y: math.Min(bounds.Min.Y, p.Y), Z: math.Min(bounds.Min.Z, p.Z)}
+		bounds.Max = r3.Vector{X: math.Max(bounds.Max.X, p.X), Y: math.Max(bounds.Max.Y, p.Y), Z: math.Max(bounds.Max.Z, p.Z)}
+	}
+	return bounds, true
+}
+
+// Encode writes points to w in the given format. created is recorded in formats that have a creation date.
+func Encode(w io.Writer, points []r3.Vector, format Format, created time.Time) error {
+	bw := bufio.NewWriter(w)
+	var err error
+	switch format {
+	case FormatPLY:
+		err = encodePLY(bw, points)
+	case FormatPCDASCII:
+		err = encodePCDASCII(bw, points)
+	case FormatLAS:
+		err = encodeLAS(bw, points, created)
+	default:
+		return errors.Errorf("unsupported map export format %q, expected one of %v", format, Formats)
+	}
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+func encodePLY(w io.Writer, points []r3.Vector) error {
+	if _, err := fmt.Fprintf(w, "ply\nformat binary_little_endian 1.0\nelement vertex %d\n"+
+		"property float x\nproperty float y\nproperty float z\nend_header\n", len(points)); err != nil {
+		return err
+	}
+	var value [12]byte
+	for _, p := range points {
+		binary.LittleEndian.PutUint32(value[0:], math.Float32bits(float32(p.X)))
+		binary.LittleEndian.PutUint32(value[4:], math.Float32bits(float32(p.Y)))
+		binary.LittleEndian.PutUint32(value[8:], math.Float32bits(float32(p.Z)))
+		if _, err := w.Write(value[:]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func encodePCDASCII(w io.Writer, points []r3.Vector) error {
+	if _, err := fmt.Fprintf(w, "VERSION .7\nFIELDS x y z\nSIZE 4 4 4\nTYPE F F F\nCOUNT 1 1 1\nWIDTH %d\nHEIGHT 1\n"+
+		"VIEWPOINT 0 0 0 1 0 0 0\nPOINTS %d\nDATA ascii\n", len(points), len(points)); err != nil {
+		return err
+	}
+	// The shortest representation that parses back to the same float32 keeps the coordinates exact.
+	format := func(v float64) string { return strconv.FormatFloat(float64(float32(v)), 'g', -1, 32) }
+	for _, p := range points {
+		if _, err := fmt.Fprintf(w, "%s %s %s\n", format(p.X), format(p.Y), format(p.Z)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lasHeader is the public header block of a LAS 1.2 file.
+type lasHeader struct {
+	FileSignature             [4]byte
+	FileSourceID              uint16
+	GlobalEncoding            uint16
+	ProjectID1                uint32
+	ProjectID2                uint16
+	ProjectID3                uint16
+	ProjectID4                [8]byte
+	VersionMajor              uint8
+	VersionMinor              uint8
+	SystemIdentifier          [32]byte
+	GeneratingSoftware        [32]byte
+	CreationDayOfYear         uint16
+	CreationYear              uint16
+	HeaderSize                uint16
+	OffsetToPointData         uint32
+	NumberOfVariableRecords   uint32
+	PointDataFormat           uint8
+	PointDataRecordLength     uint16
+	NumberOfPointRecords      uint32
+	NumberOfPointsByReturn    [5]uint32
+	XScale, YScale, ZScale    float64
+	XOffset, YOffset, ZOffset float64
+	MaxX, MinX                float64
+	MaxY, MinY                float64
+	MaxZ, MinZ                float64
+}
+
+// lasPoint is a point data record of format 0.
+type lasPoint struct {
+	X, Y, Z        int32
+	Intensity      uint16
+	ReturnInfo     uint8
+	Classification uint8
+	ScanAngleRank  int8
+	UserData       uint8
+	PointSourceID  uint16
+}
+
+const (
+	lasHeaderSize      = 227
+	lasPointRecordSize = 20
+	// lasSingleReturn marks a point as return 1 of 1.
+	lasSingleReturn = 1 | 1<<3
+	// lasMinScale is the finest scale coordinates are stored with.
+	lasMinScale = 1e-6
+)
+
+// lasScale returns the finest power of ten, but at most lasMinScale, that fits extent in an int32.
+func lasScale(extent float64) float64 {
+	scale := lasMinScale
+	for extent/scale > math.MaxInt32 {
+		scale *= 10
+	}
+	return scale
+}
+
+func encodeLAS(w io.Writer, points []r3.Vector, created time.Time) error {
+	bounds, _ := ComputeBounds(points)
+	scale := r3.Vector{
+		X: lasScale(bounds.Max.X - bounds.Min.X),
+		Y: lasScale(bounds.Max.Y - bounds.Min.Y),
+		Z: lasScale(bounds.Max.Z - bounds.Min.Z),
+	}
+	if len(points) > math.MaxUint32 {
+		return errors.Errorf("cannot export %d points as las, the limit is %d", len(points), uint32(math.MaxUint32))
+	}
+
+	header := lasHeader{
+		FileSignature:         [4]byte{'L', 'A', 'S', 'F'},
+		VersionMajor:          1,
+		VersionMinor:          2,
+		CreationDayOfYear:     uint16(created.UTC().YearDay()),
+		CreationYear:          uint16(created.UTC().Year()),
+		HeaderSize:            lasHeaderSize,
+		OffsetToPointData:     lasHeaderSize,
+		PointDataRecordLength: lasPointRecordSize,
+		NumberOfPointRecords:  uint32(len(points)),
+		XScale:                scale.X,
+		YScale:                scale.Y,
+		ZScale:                scale.Z,
+		// Offsetting by the minimum stores it exactly, and keeps the integers small.
+		XOffset: bounds.Min.X,
+		YOffset: bounds.Min.Y,
+		ZOffset: bounds.Min.Z,
+		MaxX:    bounds.Max.X,
+		MinX:    bounds.Min.X,
+		MaxY:    bounds.Max.Y,
+		MinY:    bounds.Min.Y,
+		MaxZ:    bounds.Max.Z,
+		MinZ:    bounds.Min.Z,
+	}
+	header.NumberOfPointsByReturn[0] = header.NumberOfPointRecords
+	copy(header.SystemIdentifier[:], "viam-orb-slam3")
+	copy(header.GeneratingSoftware[:], "viam-orb-slam3 map export")
+	if err := binary.Write(w, binary.LittleEndian, &header); err != nil {
+		return err
+	}
+
+	for _, p := range points {
+		record := lasPoint{
+			X:          int32(math.Round((p.X - bounds.Min.X) / scale.X)),
+			Y:          int32(math.Round((p.Y - bounds.Min.Y) / scale.Y)),
+			Z:          int32(math.Round((p.Z - bounds.Min.Z) / scale.Z)),
+			ReturnInfo: lasSingleReturn,
+		}
+		if err := binary.Write(w, binary.LittleEndian, &record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package mapexport
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/geo/r3"
+	"go.viam.com/test"
+
+	"github.com/viamrobotics/viam-orb-slam3/pcd"
+)
+
+// testPoints are float32 values, like the points of the SLAM algorithm.
+var testPoints = []r3.Vector{
+	{X: 1, Y: 2, Z: 3},
+	{X: float64(float32(-0.123456789)), Y: float64(float32(1e-7)), Z: float64(float32(1234.5678))},
+	{X: float64(float32(3.3)), Y: -2, Z: float64(float32(0.1))},
+}
+
+func TestParseFormat(t *testing.T) {
+	for _, format := range Formats {
+		parsed, err := ParseFormat(string(format))
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, parsed, test.ShouldEqual, format)
+	}
+	_, err := ParseFormat("obj")
+	test.That(t, err.Error(), test.ShouldContainSubstring, `unsupported map export format "obj"`)
+}
+
+func TestComputeBounds(t *testing.T) {
+	_, ok := ComputeBounds(nil)
+	test.That(t, ok, test.ShouldBeFalse)
+	bounds, ok := ComputeBounds(testPoints)
+	test.That(t, ok, test.ShouldBeTrue)
+	test.That(t, bounds, test.ShouldResemble, Bounds{
+		Min: r3.Vector{X: testPoints[1].X, Y: -2, Z: testPoints[2].Z},
+		Max: r3.Vector{X: testPoints[2].X, Y: 2, Z: testPoints[1].Z},
+	})
+}
+
+func TestEncode(t *testing.T) {
+	created := time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("PLY", func(t *testing.T) {
+		var buf bytes.Buffer
+		test.That(t, Encode(&buf, testPoints, FormatPLY, created), test.ShouldBeNil)
+		header, body, found := strings.Cut(buf.String(), "end_header\n")
+		test.That(t, found, test.ShouldBeTrue)
+		test.That(t, header, test.ShouldContainSubstring, "format binary_little_endian 1.0\nelement vertex 3\n")
+		test.That(t, body, test.ShouldHaveLength, 12*len(testPoints))
+		for i, p := range testPoints {
+			record := []byte(body[12*i:])
+			test.That(t, math.Float32frombits(binary.LittleEndian.Uint32(record[0:])), test.ShouldEqual, float32(p.X))
+			test.That(t, math.Float32frombits(binary.LittleEndian.Uint32(record[4:])), test.ShouldEqual, float32(p.Y))
+			test.That(t, math.Float32frombits(binary.LittleEndian.Uint32(record[8:])), test.ShouldEqual, float32(p.Z))
+		}
+	})
+
+	t.Run("ASCII PCD keeps coordinates exact", func(t *testing.T) {
+		var buf bytes.Buffer
+		test.That(t, Encode(&buf, testPoints, FormatPCDASCII, created), test.ShouldBeNil)
+		test.That(t, buf.String(), test.ShouldContainSubstring, "POINTS 3\nDATA ascii\n1 2 3\n")
+		points, err := pcd.Read(buf.Bytes())
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, points, test.ShouldResemble, testPoints)
+	})
+
+	t.Run("LAS keeps count and bounds exact", func(t *testing.T) {
+		var buf bytes.Buffer
+		test.That(t, Encode(&buf, testPoints, FormatLAS, created), test.ShouldBeNil)
+		test.That(t, buf.Len(), test.ShouldEqual, lasHeaderSize+lasPointRecordSize*len(testPoints))
+
+		reader := bytes.NewReader(buf.Bytes())
+		var header lasHeader
+		test.That(t, binary.Read(reader, binary.LittleEndian, &header), test.ShouldBeNil)
+		test.That(t, string(header.FileSignature[:]), test.ShouldEqual, "LASF")
+		test.That(t, header.VersionMinor, test.ShouldEqual, 2)
+		test.That(t, header.CreationYear, test.ShouldEqual, 2023)
+		test.That(t, header.CreationDayOfYear, test.ShouldEqual, 32)
+		test.That(t, header.NumberOfPointRecords, test.ShouldEqual, 3)
+		test.That(t, header.NumberOfPointsByReturn[0], test.ShouldEqual, 3)
+		bounds, _ := ComputeBounds(testPoints)
+		test.That(t, []float64{header.MinX, header.MinY, header.MinZ}, test.ShouldResemble,
+			[]float64{bounds.Min.X, bounds.Min.Y, bounds.Min.Z})
+		test.That(t, []float64{header.MaxX, header.MaxY, header.MaxZ}, test.ShouldResemble,
+			[]float64{bounds.Max.X, bounds.Max.Y, bounds.Max.Z})
+
+		for _, p := range testPoints {
+			var record lasPoint
+			test.That(t, binary.Read(reader, binary.LittleEndian, &record), test.ShouldBeNil)
+			test.That(t, record.ReturnInfo, test.ShouldEqual, lasSingleReturn)
+			x := float64(record.X)*header.XScale + header.XOffset
+			y := float64(record.Y)*header.YScale + header.YOffset
+			z := float64(record.Z)*header.ZScale + header.ZOffset
+			test.That(t, x, test.ShouldAlmostEqual, p.X, header.XScale/2)
+			test.That(t, y, test.ShouldAlmostEqual, p.Y, header.YScale/2)
+			test.That(t, z, test.ShouldAlmostEqual, p.Z, header.ZScale/2)
+		}
+	})
+
+	t.Run("LAS scale fits large extents", func(t *testing.T) {
+		test.That(t, lasScale(1), test.ShouldEqual, lasMinScale)
+		test.That(t, lasScale(1e4), test.ShouldAlmostEqual, 1e-5)
+		test.That(t, 1e6/lasScale(1e6), test.ShouldBeLessThanOrEqualTo, math.MaxInt32)
+	})
+
+	t.Run("Empty point cloud", func(t *testing.T) {
+		for _, format := range Formats {
+			var buf bytes.Buffer
+			test.That(t, Encode(&buf, nil, format, created), test.ShouldBeNil)
+			test.That(t, buf.Len(), test.ShouldBeGreaterThan, 0)
+		}
+	})
+
+	t.Run("Unsupported format", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := Encode(&buf, testPoints, Format("obj"), created)
+		test.That(t, err.Error(), test.ShouldContainSubstring, `unsupported map export format "obj"`)
+	})
+}
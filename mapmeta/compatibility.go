@@ -0,0 +1,59 @@
+package mapmeta
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// IntrinsicsTolerance is the relative difference allowed between the focal lengths and principal points of the
+// camera a map was made with and the current camera, to allow for small differences between calibrations.
+const IntrinsicsTolerance = 0.01
+
+// primaryIntrinsics returns the intrinsics of the first camera with known intrinsics, which is the camera the SLAM
+// algorithm is given.
+func (metadata Metadata) primaryIntrinsics() *Intrinsics {
+	for _, camera := range metadata.Cameras {
+		if camera.Intrinsics != nil {
+			return camera.Intrinsics
+		}
+	}
+	return nil
+}
+
+// CheckCompatibility returns an error listing the differences between the metadata of a saved map and the current
+// settings that make it unsafe to load the map: a different mode, image resolution or vocabulary, or intrinsics that
+// differ by more than tolerance. Settings missing from either side are not compared.
+func CheckCompatibility(saved, current Metadata, tolerance float64) error {
+	var problems []string
+	if saved.Mode != "" && current.Mode != "" && saved.Mode != current.Mode {
+		problems = append(problems, fmt.Sprintf("mode %v does not match %v", saved.Mode, current.Mode))
+	}
+	if saved.VocabularySHA256 != "" && current.VocabularySHA256 != "" && saved.VocabularySHA256 != current.VocabularySHA256 {
+		problems = append(problems, "vocabulary checksum does not match")
+	}
+	if a, b := saved.primaryIntrinsics(), current.primaryIntrinsics(); a != nil && b != nil {
+		if a.Width != b.Width || a.Height != b.Height {
+			problems = append(problems, fmt.Sprintf("resolution %vx%v does not match %vx%v", a.Width, a.Height, b.Width, b.Height))
+		}
+		for _, param := range []struct {
+			name         string
+			saved, value float64
+		}{
+			{"fx", a.Fx, b.Fx},
+			{"fy", a.Fy, b.Fy},
+			{"ppx", a.Ppx, b.Ppx},
+			{"ppy", a.Ppy, b.Ppy},
+		} {
+			if math.Abs(param.saved-param.value) > tolerance*math.Max(math.Abs(param.saved), math.Abs(param.value)) {
+				problems = append(problems, fmt.Sprintf("%v %v does not match %v", param.name, param.saved, param.value))
+			}
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(problems, ", "))
+}
@@ -0,0 +1,102 @@
+// Package mapmeta reads and writes the metadata sidecar saved next to every map, which records how the map was made.
+package mapmeta
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/viamrobotics/viam-orb-slam3/atomicfile"
+)
+
+// MapExtension is the extension of the maps saved by the SLAM algorithm.
+const MapExtension = ".osa"
+
+// sidecarExtension replaces the extension of a map to form the path of its sidecar.
+const sidecarExtension = ".json"
+
+// Intrinsics are the pinhole intrinsics of a camera.
+type Intrinsics struct {
+	Width  int     `json:"width_px"`
+	Height int     `json:"height_px"`
+	Fx     float64 `json:"fx"`
+	Fy     float64 `json:"fy"`
+	Ppx    float64 `json:"ppx"`
+	Ppy    float64 `json:"ppy"`
+}
+
+// Distortion is the distortion model of a camera and its parameters.
+type Distortion struct {
+	Model      string    `json:"model"`
+	Parameters []float64 `json:"parameters"`
+}
+
+// Camera is a camera the map was made with.
+type Camera struct {
+	Name       string      `json:"name"`
+	Intrinsics *Intrinsics `json:"intrinsics,omitempty"`
+	Distortion *Distortion `json:"distortion,omitempty"`
+}
+
+// Metadata describes how a map was made.
+type Metadata struct {
+	CreatedAt        time.Time         `json:"created_at"`
+	Mode             string            `json:"mode"`
+	Cameras          []Camera          `json:"cameras"`
+	ConfigParams     map[string]string `json:"config_params"`
+	VocabularySHA256 string            `json:"vocabulary_sha256,omitempty"`
+	ModuleVersion    string            `json:"module_version"`
+	ORBSLAMVersion   string            `json:"orbslam_version,omitempty"`
+	KeyFrames        int               `json:"keyframes"`
+	MapPoints        int               `json:"map_points"`
+}
+
+// SidecarPath returns the path of the sidecar of the map at mapPath.
+func SidecarPath(mapPath string) string {
+	return strings.TrimSuffix(mapPath, MapExtension) + sidecarExtension
+}
+
+// Write saves the metadata of the map at mapPath to its sidecar, replacing it atomically.
+func Write(mapPath string, metadata Metadata) error {
+	data, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "error encoding map metadata")
+	}
+	return errors.Wrap(atomicfile.WriteFile(SidecarPath(mapPath), data, 0o644), "error writing map metadata")
+}
+
+// Read loads the metadata of the map at mapPath from its sidecar. The error satisfies os.IsNotExist if the map has no
+// sidecar, e.g. because it was saved before sidecars were written.
+func Read(mapPath string) (Metadata, error) {
+	//nolint:gosec
+	data, err := os.ReadFile(SidecarPath(mapPath))
+	if err != nil {
+		return Metadata{}, err
+	}
+	var metadata Metadata
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return Metadata{}, errors.Wrapf(err, "error decoding map metadata %v", SidecarPath(mapPath))
+	}
+	return metadata, nil
+}
+
+// FileSHA256 returns the hex encoded SHA-256 checksum of the file at path.
+func FileSHA256(path string) (string, error) {
+	//nolint:gosec
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,92 @@
+package mapmeta
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.viam.com/test"
+)
+
+func TestSidecarPath(t *testing.T) {
+	test.That(t, SidecarPath("/data/map/cam_data_2023-01-01T00:00:00.0000Z.osa"), test.ShouldEqual,
+		"/data/map/cam_data_2023-01-01T00:00:00.0000Z.json")
+}
+
+func TestWriteAndRead(t *testing.T) {
+	dir := t.TempDir()
+	mapPath := filepath.Join(dir, "cam_data_2023-01-01T00:00:00.0000Z.osa")
+
+	_, err := Read(mapPath)
+	test.That(t, os.IsNotExist(err), test.ShouldBeTrue)
+
+	metadata := Metadata{
+		CreatedAt: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+		Mode:      "mono",
+		Cameras: []Camera{{
+			Name:       "cam",
+			Intrinsics: &Intrinsics{Width: 640, Height: 480, Fx: 500, Fy: 501, Ppx: 320, Ppy: 240},
+			Distortion: &Distortion{Model: "brown_conrady", Parameters: []float64{0.1, -0.2, 0, 0, 0.01}},
+		}},
+		ConfigParams:     map[string]string{"mode": "mono", "orb_n_features": "1250"},
+		VocabularySHA256: "abc",
+		ModuleVersion:    "v1.2.3",
+		ORBSLAMVersion:   "1.0",
+		KeyFrames:        12,
+		MapPoints:        3456,
+	}
+	test.That(t, Write(mapPath, metadata), test.ShouldBeNil)
+	read, err := Read(mapPath)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, read, test.ShouldResemble, metadata)
+
+	test.That(t, os.WriteFile(SidecarPath(mapPath), []byte("{"), 0o644), test.ShouldBeNil)
+	_, err = Read(mapPath)
+	test.That(t, err.Error(), test.ShouldContainSubstring, "error decoding map metadata")
+}
+
+func TestFileSHA256(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vocabulary.txt")
+	test.That(t, os.WriteFile(path, []byte("abc"), 0o644), test.ShouldBeNil)
+	sum, err := FileSHA256(path)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, sum, test.ShouldEqual, "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad")
+
+	_, err = FileSHA256(filepath.Join(t.TempDir(), "missing.txt"))
+	test.That(t, os.IsNotExist(err), test.ShouldBeTrue)
+}
+
+func TestCheckCompatibility(t *testing.T) {
+	saved := Metadata{
+		Mode:             "mono",
+		Cameras:          []Camera{{Name: "cam", Intrinsics: &Intrinsics{Width: 640, Height: 480, Fx: 500, Fy: 500, Ppx: 320, Ppy: 240}}},
+		VocabularySHA256: "abc",
+	}
+
+	t.Run("Compatible within the tolerance", func(t *testing.T) {
+		current := saved
+		current.Cameras = []Camera{
+			{Name: "other_cam", Intrinsics: &Intrinsics{Width: 640, Height: 480, Fx: 504, Fy: 496, Ppx: 321, Ppy: 240}},
+		}
+		test.That(t, CheckCompatibility(saved, current, IntrinsicsTolerance), test.ShouldBeNil)
+	})
+
+	t.Run("Settings missing from either side are not compared", func(t *testing.T) {
+		test.That(t, CheckCompatibility(Metadata{}, saved, IntrinsicsTolerance), test.ShouldBeNil)
+		test.That(t, CheckCompatibility(saved, Metadata{Mode: "mono"}, IntrinsicsTolerance), test.ShouldBeNil)
+	})
+
+	t.Run("Incompatible", func(t *testing.T) {
+		current := Metadata{
+			Mode:             "rgbd",
+			Cameras:          []Camera{{Name: "cam", Intrinsics: &Intrinsics{Width: 1280, Height: 720, Fx: 520, Fy: 500, Ppx: 640, Ppy: 360}}},
+			VocabularySHA256: "def",
+		}
+		err := CheckCompatibility(saved, current, IntrinsicsTolerance)
+		test.That(t, err, test.ShouldBeError, errors.New("mode mono does not match rgbd, vocabulary checksum does not match, "+
+			"resolution 640x480 does not match 1280x720, fx 500 does not match 520, ppx 320 does not match 640, "+
+			"ppy 240 does not match 360"))
+	})
+}
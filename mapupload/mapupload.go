@@ -0,0 +1,121 @@
+// Package mapupload assembles a file uploaded in chunks, and verifies it against the size and checksum announced when
+// the upload began.
+package mapupload
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// partialPattern is the name of the file an upload is assembled in, which does not have the extension of a map so that
+// it is never loaded.
+const partialPattern = ".upload-*.part"
+
+// Upload is a file being uploaded in chunks. It is not safe for concurrent use.
+type Upload struct {
+	// ID identifies the upload to the client sending it.
+	ID       string
+	size     int64
+	sha256   string
+	file     *os.File
+	hash     hash.Hash
+	received int64
+	verified bool
+}
+
+// New starts an upload of a file of the given size and hex encoded SHA-256 checksum, assembled in dir.
+func New(dir string, size int64, sha256Sum string) (*Upload, error) {
+	if size <= 0 {
+		return nil, errors.New("upload size must be greater than zero")
+	}
+	if sum, err := hex.DecodeString(sha256Sum); err != nil || len(sum) != sha256.Size {
+		return nil, errors.Errorf("upload sha256 must be a hex encoded SHA-256 checksum, got %v", sha256Sum)
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, errors.Wrap(err, "error generating upload id")
+	}
+	file, err := os.CreateTemp(dir, partialPattern)
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating upload file")
+	}
+	return &Upload{
+		ID:     hex.EncodeToString(id),
+		size:   size,
+		sha256: strings.ToLower(sha256Sum),
+		file:   file,
+		hash:   sha256.New(),
+	}, nil
+}
+
+// Received returns the number of bytes received so far. The next chunk starts at this offset.
+func (u *Upload) Received() int64 {
+	return u.received
+}
+
+// Write appends the chunk data starting at offset and returns the number of bytes received so far. Chunks must be sent
+// in order. A chunk that was already received is ignored, so that a chunk whose acknowledgement was lost can be sent
+// again.
+func (u *Upload) Write(offset int64, data []byte) (int64, error) {
+	end := offset + int64(len(data))
+	if offset < u.received && end <= u.received {
+		return u.received, nil
+	}
+	if offset != u.received {
+		return u.received, errors.Errorf("upload chunk starts at offset %d, expected offset %d", offset, u.received)
+	}
+	if end > u.size {
+		return u.received, errors.Errorf("upload chunk ends at offset %d, past the size of %d bytes", end, u.size)
+	}
+	if _, err := u.file.Write(data); err != nil {
+		return u.received, errors.Wrap(err, "error writing upload chunk")
+	}
+	u.hash.Write(data)
+	u.received = end
+	return u.received, nil
+}
+
+// Verify returns an error if the upload is incomplete or does not match its checksum.
+func (u *Upload) Verify() error {
+	if u.verified {
+		return nil
+	}
+	if u.received != u.size {
+		return errors.Errorf("upload is incomplete, received %d of %d bytes", u.received, u.size)
+	}
+	if sum := hex.EncodeToString(u.hash.Sum(nil)); sum != u.sha256 {
+		return errors.Errorf("upload has checksum %v, expected %v", sum, u.sha256)
+	}
+	u.verified = true
+	return nil
+}
+
+// Finish verifies the upload and moves it to path.
+func (u *Upload) Finish(path string) error {
+	if err := u.Verify(); err != nil {
+		return err
+	}
+	if err := u.file.Sync(); err != nil {
+		return errors.Wrap(err, "error writing upload file")
+	}
+	if err := u.file.Close(); err != nil {
+		return errors.Wrap(err, "error writing upload file")
+	}
+	return errors.Wrap(os.Rename(u.file.Name(), path), "error moving upload file")
+}
+
+// Abort discards the upload.
+func (u *Upload) Abort() error {
+	//nolint:errcheck
+	u.file.Close()
+	if err := os.Remove(u.file.Name()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
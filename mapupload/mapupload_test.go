@@ -0,0 +1,76 @@
+package mapupload
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.viam.com/test"
+)
+
+func TestUpload(t *testing.T) {
+	content := []byte("an uploaded map")
+	sum := sha256.Sum256(content)
+	checksum := hex.EncodeToString(sum[:])
+
+	t.Run("Invalid upload", func(t *testing.T) {
+		_, err := New(t.TempDir(), 0, checksum)
+		test.That(t, err.Error(), test.ShouldContainSubstring, "upload size must be greater than zero")
+		_, err = New(t.TempDir(), 1, "abc")
+		test.That(t, err.Error(), test.ShouldContainSubstring, "upload sha256 must be a hex encoded SHA-256 checksum")
+	})
+
+	t.Run("Chunks in order", func(t *testing.T) {
+		dir := t.TempDir()
+		upload, err := New(dir, int64(len(content)), strings.ToUpper(checksum))
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, upload.ID, test.ShouldHaveLength, 32)
+
+		received, err := upload.Write(0, content[:6])
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, received, test.ShouldEqual, 6)
+
+		// A chunk sent again is ignored.
+		received, err = upload.Write(0, content[:6])
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, received, test.ShouldEqual, 6)
+
+		_, err = upload.Write(8, content[8:])
+		test.That(t, err.Error(), test.ShouldContainSubstring, "upload chunk starts at offset 8, expected offset 6")
+		_, err = upload.Write(6, append(content[6:], 'x'))
+		test.That(t, err.Error(), test.ShouldContainSubstring, "past the size of 15 bytes")
+		test.That(t, upload.Verify().Error(), test.ShouldContainSubstring, "upload is incomplete, received 6 of 15 bytes")
+
+		received, err = upload.Write(6, content[6:])
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, received, test.ShouldEqual, len(content))
+		test.That(t, upload.Received(), test.ShouldEqual, len(content))
+
+		path := filepath.Join(dir, "map.osa")
+		test.That(t, upload.Finish(path), test.ShouldBeNil)
+		data, err := os.ReadFile(path)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, data, test.ShouldResemble, content)
+		entries, err := os.ReadDir(dir)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, entries, test.ShouldHaveLength, 1)
+	})
+
+	t.Run("Checksum mismatch", func(t *testing.T) {
+		dir := t.TempDir()
+		upload, err := New(dir, int64(len(content)), strings.Repeat("0", 64))
+		test.That(t, err, test.ShouldBeNil)
+		_, err = upload.Write(0, content)
+		test.That(t, err, test.ShouldBeNil)
+		err = upload.Finish(filepath.Join(dir, "map.osa"))
+		test.That(t, err.Error(), test.ShouldContainSubstring, "upload has checksum "+checksum)
+
+		test.That(t, upload.Abort(), test.ShouldBeNil)
+		entries, err := os.ReadDir(dir)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, entries, test.ShouldBeEmpty)
+	})
+}
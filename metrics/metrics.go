@@ -0,0 +1,237 @@
+// Package metrics tracks runtime statistics of the SLAM service's data pipeline and exports them
+// as OpenCensus stats.
+package metrics
+
+import (
+	"context"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+)
+
+// latencyBoundsMs are the histogram bucket upper bounds, in milliseconds, used for every
+// latency and skew distribution.
+var latencyBoundsMs = []float64{1, 2, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+
+var (
+	framesCapturedMeasure = stats.Int64("viamorbslam3/frames_captured",
+		"number of frames captured and saved to the data directory", stats.UnitDimensionless)
+	framesSkippedMeasure = stats.Int64("viamorbslam3/frames_skipped",
+		"number of frames skipped due to an OpTimeout from the camera", stats.UnitDimensionless)
+	framesFailedMeasure = stats.Int64("viamorbslam3/frames_failed",
+		"number of frames that failed to be captured or saved", stats.UnitDimensionless)
+	inFlightGoroutinesMeasure = stats.Int64("viamorbslam3/in_flight_goroutines",
+		"number of data capture goroutines currently running", stats.UnitDimensionless)
+	slamProcessRestartsMeasure = stats.Int64("viamorbslam3/slam_process_restarts",
+		"number of times the SLAM process exited unexpectedly and was restarted", stats.UnitDimensionless)
+	pngEncodeLatencyMeasure = stats.Float64("viamorbslam3/png_encode_latency",
+		"time taken to read and PNG encode an image from a camera", stats.UnitMilliseconds)
+	pngWriteLatencyMeasure = stats.Float64("viamorbslam3/png_write_latency",
+		"time taken to write a PNG image to the data directory", stats.UnitMilliseconds)
+	rgbdSkewMeasure = stats.Float64("viamorbslam3/rgbd_skew",
+		"time between the color and depth image of an RGBD pair being received", stats.UnitMilliseconds)
+	getPositionLatencyMeasure = stats.Float64("viamorbslam3/get_position_latency",
+		"time taken to serve a GetPosition request", stats.UnitMilliseconds)
+	getPointCloudMapLatencyMeasure = stats.Float64("viamorbslam3/get_point_cloud_map_latency",
+		"time taken to stream the full pointcloud map of a GetPointCloudMap request", stats.UnitMilliseconds)
+)
+
+// Views contains the OpenCensus views of every measure recorded by this package.
+var Views = []*view.View{
+	{Measure: framesCapturedMeasure, Aggregation: view.Sum()},
+	{Measure: framesSkippedMeasure, Aggregation: view.Sum()},
+	{Measure: framesFailedMeasure, Aggregation: view.Sum()},
+	{Measure: inFlightGoroutinesMeasure, Aggregation: view.LastValue()},
+	{Measure: slamProcessRestartsMeasure, Aggregation: view.Sum()},
+	{Measure: pngEncodeLatencyMeasure, Aggregation: view.Distribution(latencyBoundsMs...)},
+	{Measure: pngWriteLatencyMeasure, Aggregation: view.Distribution(latencyBoundsMs...)},
+	{Measure: rgbdSkewMeasure, Aggregation: view.Distribution(latencyBoundsMs...)},
+	{Measure: getPositionLatencyMeasure, Aggregation: view.Distribution(latencyBoundsMs...)},
+	{Measure: getPointCloudMapLatencyMeasure, Aggregation: view.Distribution(latencyBoundsMs...)},
+}
+
+// RegisterViews registers the views of this package with OpenCensus. Registering the views
+// more than once is a no-op.
+func RegisterViews() error {
+	return view.Register(Views...)
+}
+
+// Histogram is a fixed bucket histogram that also tracks the count, sum, min and max of all
+// recorded values.
+type Histogram struct {
+	mu     sync.Mutex
+	bounds []float64
+	counts []int64
+	count  int64
+	sum    float64
+	min    float64
+	max    float64
+}
+
+// NewHistogram returns a histogram with the given bucket upper bounds. Values larger than the
+// last bound are counted in an overflow bucket.
+func NewHistogram(bounds []float64) *Histogram {
+	return &Histogram{
+		bounds: bounds,
+		counts: make([]int64, len(bounds)+1),
+		min:    math.Inf(1),
+		max:    math.Inf(-1),
+	}
+}
+
+// Record adds a value to the histogram.
+func (h *Histogram) Record(v float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	i := 0
+	for i < len(h.bounds) && v > h.bounds[i] {
+		i++
+	}
+	h.counts[i]++
+	h.count++
+	h.sum += v
+	h.min = math.Min(h.min, v)
+	h.max = math.Max(h.max, v)
+}
+
+// Snapshot returns the current state of the histogram in a form that can be serialized into
+// a protobuf struct.
+func (h *Histogram) Snapshot() map[string]interface{} {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	bounds := make([]interface{}, len(h.bounds))
+	for i, b := range h.bounds {
+		bounds[i] = b
+	}
+	counts := make([]interface{}, len(h.counts))
+	for i, c := range h.counts {
+		counts[i] = c
+	}
+	snapshot := map[string]interface{}{
+		"count":         h.count,
+		"sum":           h.sum,
+		"bucket_bounds": bounds,
+		"bucket_counts": counts,
+	}
+	if h.count > 0 {
+		snapshot["min"] = h.min
+		snapshot["max"] = h.max
+		snapshot["mean"] = h.sum / float64(h.count)
+	}
+	return snapshot
+}
+
+// Stats holds the runtime statistics of a single SLAM service. It is safe for concurrent use.
+type Stats struct {
+	framesCaptured      atomic.Int64
+	framesSkipped       atomic.Int64
+	framesFailed        atomic.Int64
+	inFlightGoroutines  atomic.Int64
+	slamProcessRestarts atomic.Int64
+
+	pngEncodeLatency        *Histogram
+	pngWriteLatency         *Histogram
+	rgbdSkew                *Histogram
+	getPositionLatency      *Histogram
+	getPointCloudMapLatency *Histogram
+}
+
+// NewStats returns an empty set of statistics.
+func NewStats() *Stats {
+	return &Stats{
+		pngEncodeLatency:        NewHistogram(latencyBoundsMs),
+		pngWriteLatency:         NewHistogram(latencyBoundsMs),
+		rgbdSkew:                NewHistogram(latencyBoundsMs),
+		getPositionLatency:      NewHistogram(latencyBoundsMs),
+		getPointCloudMapLatency: NewHistogram(latencyBoundsMs),
+	}
+}
+
+// RecordFrameCaptured records that a frame was captured and saved.
+func (s *Stats) RecordFrameCaptured(ctx context.Context) {
+	stats.Record(ctx, framesCapturedMeasure.M(1))
+	s.framesCaptured.Add(1)
+}
+
+// RecordFrameSkipped records that a frame was skipped due to an OpTimeout.
+func (s *Stats) RecordFrameSkipped(ctx context.Context) {
+	stats.Record(ctx, framesSkippedMeasure.M(1))
+	s.framesSkipped.Add(1)
+}
+
+// RecordFrameFailed records that a frame failed to be captured or saved.
+func (s *Stats) RecordFrameFailed(ctx context.Context) {
+	stats.Record(ctx, framesFailedMeasure.M(1))
+	s.framesFailed.Add(1)
+}
+
+// GoroutineStarted records that a data capture goroutine has been started.
+func (s *Stats) GoroutineStarted(ctx context.Context) {
+	stats.Record(ctx, inFlightGoroutinesMeasure.M(s.inFlightGoroutines.Add(1)))
+}
+
+// GoroutineFinished records that a data capture goroutine has finished.
+func (s *Stats) GoroutineFinished(ctx context.Context) {
+	stats.Record(ctx, inFlightGoroutinesMeasure.M(s.inFlightGoroutines.Add(-1)))
+}
+
+// RecordSLAMProcessRestart records that the SLAM process was restarted.
+func (s *Stats) RecordSLAMProcessRestart(ctx context.Context) {
+	stats.Record(ctx, slamProcessRestartsMeasure.M(1))
+	s.slamProcessRestarts.Add(1)
+}
+
+// RecordPNGEncodeLatency records the time taken to read and encode a PNG image.
+func (s *Stats) RecordPNGEncodeLatency(ctx context.Context, d time.Duration) {
+	recordDuration(ctx, pngEncodeLatencyMeasure, s.pngEncodeLatency, d)
+}
+
+// RecordPNGWriteLatency records the time taken to write a PNG image to disk.
+func (s *Stats) RecordPNGWriteLatency(ctx context.Context, d time.Duration) {
+	recordDuration(ctx, pngWriteLatencyMeasure, s.pngWriteLatency, d)
+}
+
+// RecordRGBDSkew records the time between the color and depth images of an RGBD pair.
+func (s *Stats) RecordRGBDSkew(ctx context.Context, d time.Duration) {
+	recordDuration(ctx, rgbdSkewMeasure, s.rgbdSkew, d)
+}
+
+// RecordGetPositionLatency records the time taken to serve a GetPosition request.
+func (s *Stats) RecordGetPositionLatency(ctx context.Context, d time.Duration) {
+	recordDuration(ctx, getPositionLatencyMeasure, s.getPositionLatency, d)
+}
+
+// RecordGetPointCloudMapLatency records the time taken to stream a full pointcloud map.
+func (s *Stats) RecordGetPointCloudMapLatency(ctx context.Context, d time.Duration) {
+	recordDuration(ctx, getPointCloudMapLatencyMeasure, s.getPointCloudMapLatency, d)
+}
+
+// Snapshot returns all statistics in a form that can be serialized into a protobuf struct.
+func (s *Stats) Snapshot() map[string]interface{} {
+	return map[string]interface{}{
+		"frames": map[string]interface{}{
+			"captured":           s.framesCaptured.Load(),
+			"skipped_op_timeout": s.framesSkipped.Load(),
+			"failed":             s.framesFailed.Load(),
+		},
+		"in_flight_goroutines":           s.inFlightGoroutines.Load(),
+		"slam_process_restarts":          s.slamProcessRestarts.Load(),
+		"png_encode_latency_ms":          s.pngEncodeLatency.Snapshot(),
+		"png_write_latency_ms":           s.pngWriteLatency.Snapshot(),
+		"rgbd_skew_ms":                   s.rgbdSkew.Snapshot(),
+		"get_position_latency_ms":        s.getPositionLatency.Snapshot(),
+		"get_point_cloud_map_latency_ms": s.getPointCloudMapLatency.Snapshot(),
+	}
+}
+
+func recordDuration(ctx context.Context, measure *stats.Float64Measure, h *Histogram, d time.Duration) {
+	ms := float64(d) / float64(time.Millisecond)
+	stats.Record(ctx, measure.M(ms))
+	h.Record(ms)
+}
@@ -0,0 +1,103 @@
+package metrics
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opencensus.io/stats/view"
+	"go.viam.com/test"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestHistogram(t *testing.T) {
+	t.Run("Empty histogram", func(t *testing.T) {
+		h := NewHistogram([]float64{1, 10})
+		snapshot := h.Snapshot()
+		test.That(t, snapshot["count"], test.ShouldEqual, int64(0))
+		test.That(t, snapshot["bucket_counts"], test.ShouldResemble, []interface{}{int64(0), int64(0), int64(0)})
+		_, ok := snapshot["min"]
+		test.That(t, ok, test.ShouldBeFalse)
+	})
+
+	t.Run("Values are placed in the correct buckets", func(t *testing.T) {
+		h := NewHistogram([]float64{1, 10})
+		for _, v := range []float64{0.5, 1, 5, 100} {
+			h.Record(v)
+		}
+		snapshot := h.Snapshot()
+		test.That(t, snapshot["count"], test.ShouldEqual, int64(4))
+		test.That(t, snapshot["sum"], test.ShouldEqual, 106.5)
+		test.That(t, snapshot["min"], test.ShouldEqual, 0.5)
+		test.That(t, snapshot["max"], test.ShouldEqual, 100.)
+		test.That(t, snapshot["mean"], test.ShouldEqual, 106.5/4)
+		test.That(t, snapshot["bucket_bounds"], test.ShouldResemble, []interface{}{1., 10.})
+		test.That(t, snapshot["bucket_counts"], test.ShouldResemble, []interface{}{int64(2), int64(1), int64(1)})
+	})
+}
+
+func TestStats(t *testing.T) {
+	test.That(t, RegisterViews(), test.ShouldBeNil)
+	// Registering the views twice must not fail, since every service instance registers them.
+	test.That(t, RegisterViews(), test.ShouldBeNil)
+
+	ctx := context.Background()
+	s := NewStats()
+
+	t.Run("Counters and histograms are recorded", func(t *testing.T) {
+		s.RecordFrameCaptured(ctx)
+		s.RecordFrameCaptured(ctx)
+		s.RecordFrameSkipped(ctx)
+		s.RecordFrameFailed(ctx)
+		s.RecordSLAMProcessRestart(ctx)
+		s.RecordPNGEncodeLatency(ctx, 3*time.Millisecond)
+		s.RecordPNGWriteLatency(ctx, 4*time.Millisecond)
+		s.RecordRGBDSkew(ctx, 5*time.Millisecond)
+		s.RecordGetPositionLatency(ctx, 6*time.Millisecond)
+		s.RecordGetPointCloudMapLatency(ctx, 7*time.Millisecond)
+
+		snapshot := s.Snapshot()
+		test.That(t, snapshot["frames"], test.ShouldResemble, map[string]interface{}{
+			"captured":           int64(2),
+			"skipped_op_timeout": int64(1),
+			"failed":             int64(1),
+		})
+		test.That(t, snapshot["slam_process_restarts"], test.ShouldEqual, int64(1))
+		for key, expected := range map[string]float64{
+			"png_encode_latency_ms":          3,
+			"png_write_latency_ms":           4,
+			"rgbd_skew_ms":                   5,
+			"get_position_latency_ms":        6,
+			"get_point_cloud_map_latency_ms": 7,
+		} {
+			h := snapshot[key].(map[string]interface{})
+			test.That(t, h["count"], test.ShouldEqual, int64(1))
+			test.That(t, h["sum"], test.ShouldEqual, expected)
+		}
+
+		rows, err := view.RetrieveData("viamorbslam3/frames_captured")
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, rows, test.ShouldHaveLength, 1)
+		test.That(t, rows[0].Data.(*view.SumData).Value, test.ShouldBeGreaterThanOrEqualTo, 2)
+	})
+
+	t.Run("In flight goroutines are tracked", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			s.GoroutineStarted(ctx)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				s.GoroutineFinished(ctx)
+			}()
+		}
+		wg.Wait()
+		test.That(t, s.Snapshot()["in_flight_goroutines"], test.ShouldEqual, int64(0))
+	})
+
+	t.Run("Snapshot can be converted to a protobuf struct", func(t *testing.T) {
+		_, err := structpb.NewStruct(s.Snapshot())
+		test.That(t, err, test.ShouldBeNil)
+	})
+}
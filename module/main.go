@@ -5,11 +5,14 @@ import (
 	"context"
 
 	"github.com/edaniels/golog"
+	"go.viam.com/rdk/components/camera"
 	"go.viam.com/rdk/module"
 	"go.viam.com/rdk/services/slam"
 	"go.viam.com/utils"
 
 	viamorbslam3 "github.com/viamrobotics/viam-orb-slam3"
+	"github.com/viamrobotics/viam-orb-slam3/sensors/replay"
+	"github.com/viamrobotics/viam-orb-slam3/sensors/synthetic"
 )
 
 func main() {
@@ -27,6 +30,12 @@ func mainWithArgs(ctx context.Context, args []string, logger golog.Logger) error
 	if err = orbModule.AddModelFromRegistry(ctx, slam.API, viamorbslam3.Model); err != nil {
 		return err
 	}
+	if err = orbModule.AddModelFromRegistry(ctx, camera.API, replay.Model); err != nil {
+		return err
+	}
+	if err = orbModule.AddModelFromRegistry(ctx, camera.API, synthetic.Model); err != nil {
+		return err
+	}
 
 	// Start the module
 	err = orbModule.Start(ctx)
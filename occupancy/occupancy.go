@@ -0,0 +1,289 @@
+// Package occupancy builds 2D occupancy grids from sparse 3D point cloud maps, and encodes them in the PGM and YAML
+// format of the ROS map_server.
+package occupancy
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/golang/geo/r3"
+	"github.com/pkg/errors"
+)
+
+// Values of the cells of a grid, in the convention of ROS occupancy grids.
+const (
+	Unknown  int8 = -1
+	Free     int8 = 0
+	Occupied int8 = 100
+)
+
+// maxCells is the largest grid that is built, to keep a resolution that is too fine for the map from exhausting
+// memory.
+const maxCells = 100_000_000
+
+// Values of the cells in a PGM image, as read by the ROS map_server with negate set to 0.
+const (
+	pgmOccupied = 0
+	pgmFree     = 254
+	pgmUnknown  = 205
+)
+
+// Config describes how points are projected onto a grid. Distances are in the units of the points, with z up.
+type Config struct {
+	// Resolution is the length of the side of a cell.
+	Resolution float64
+	// MinHeight and MaxHeight bound the heights of the points that are obstacles.
+	MinHeight float64
+	MaxHeight float64
+	// InflationRadius is the distance around obstacles that is also marked as occupied.
+	InflationRadius float64
+}
+
+// Validate checks that the config describes a grid that can be built.
+func (cfg Config) Validate() error {
+	if cfg.Resolution <= 0 {
+		return errors.New("occupancy grid resolution must be greater than zero")
+	}
+	if cfg.MinHeight > cfg.MaxHeight {
+		return errors.New("occupancy grid min_height must not be greater than max_height")
+	}
+	if cfg.InflationRadius < 0 {
+		return errors.New("occupancy grid inflation_radius must not be less than zero")
+	}
+	return nil
+}
+
+// Grid is a 2D occupancy grid. Cells are stored in row major order, starting from the cell at the origin, with x
+// increasing along a row and y increasing from row to row.
+type Grid struct {
+	Resolution float64
+	// OriginX and OriginY are the coordinates of the corner of the first cell.
+	OriginX float64
+	OriginY float64
+	Width   int
+	Height  int
+	Cells   []int8
+}
+
+// Cell returns the value of the cell in column col and row row.
+func (g *Grid) Cell(col, row int) int8 {
+	return g.Cells[row*g.Width+col]
+}
+
+// CellAt returns the column and row of the cell containing the point x, y, and false if it is outside the grid.
+func (g *Grid) CellAt(x, y float64) (int, int, bool) {
+	col := int(math.Floor((x - g.OriginX) / g.Resolution))
+	row := int(math.Floor((y - g.OriginY) / g.Resolution))
+	return col, row, col >= 0 && col < g.Width && row >= 0 && row < g.Height
+}
+
+func (g *Grid) set(col, row int, value int8) {
+	g.Cells[row*g.Width+col] = value
+}
+
+// Build projects the points with heights between cfg.MinHeight and cfg.MaxHeight onto a grid as obstacles. Free
+// space is marked by casting a ray to every obstacle from the nearest position of the trajectory the map was
+// recorded along, and along the trajectory itself. Obstacles are then inflated by cfg.InflationRadius. Cells that
+// are neither free nor occupied are unknown.
+func Build(points, trajectory []r3.Vector, cfg Config) (*Grid, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	var obstacles []r3.Vector
+	for _, p := range points {
+		if p.Z >= cfg.MinHeight && p.Z <= cfg.MaxHeight {
+			obstacles = append(obstacles, p)
+		}
+	}
+	if len(obstacles) == 0 && len(trajectory) == 0 {
+		return nil, errors.New("no points within the height band or trajectory to build an occupancy grid from")
+	}
+
+	grid, err := newGrid(obstacles, trajectory, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Free space is marked first, so that obstacles seen from elsewhere along the trajectory take precedence.
+	poses := grid.trajectoryCells(trajectory)
+	for i := 1; i < len(poses); i++ {
+		grid.traceLine(poses[i-1], poses[i], true)
+	}
+	if len(poses) == 1 {
+		grid.set(poses[0][0], poses[0][1], Free)
+	}
+	occupied := make([][2]int, 0, len(obstacles))
+	for _, p := range obstacles {
+		col, row, _ := grid.CellAt(p.X, p.Y)
+		cell := [2]int{col, row}
+		occupied = append(occupied, cell)
+		if len(poses) > 0 {
+			grid.traceLine(nearest(poses, cell), cell, false)
+		}
+	}
+	for _, cell := range occupied {
+		grid.set(cell[0], cell[1], Occupied)
+	}
+	grid.inflate(occupied, cfg.InflationRadius)
+	return grid, nil
+}
+
+// newGrid returns a grid of unknown cells covering the obstacles and trajectory, with room for inflation.
+func newGrid(obstacles, trajectory []r3.Vector, cfg Config) (*Grid, error) {
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, points := range [][]r3.Vector{obstacles, trajectory} {
+		for _, p := range points {
+			minX, minY = math.Min(minX, p.X), math.Min(minY, p.Y)
+			maxX, maxY = math.Max(maxX, p.X), math.Max(maxY, p.Y)
+		}
+	}
+	padding := math.Ceil(cfg.InflationRadius/cfg.Resolution) + 1
+	originCol := math.Floor(minX/cfg.Resolution) - padding
+	originRow := math.Floor(minY/cfg.Resolution) - padding
+	width := math.Floor(maxX/cfg.Resolution) + padding - originCol + 1
+	height := math.Floor(maxY/cfg.Resolution) + padding - originRow + 1
+	if width*height > maxCells {
+		return nil, errors.Errorf("occupancy grid of %v by %v cells is too large, use a coarser resolution", width, height)
+	}
+
+	grid := &Grid{
+		Resolution: cfg.Resolution,
+		OriginX:    originCol * cfg.Resolution,
+		OriginY:    originRow * cfg.Resolution,
+		Width:      int(width),
+		Height:     int(height),
+		Cells:      make([]int8, int(width*height)),
+	}
+	for i := range grid.Cells {
+		grid.Cells[i] = Unknown
+	}
+	return grid, nil
+}
+
+// trajectoryCells returns the cells of the trajectory, skipping repeats of the same cell.
+func (g *Grid) trajectoryCells(trajectory []r3.Vector) [][2]int {
+	var cells [][2]int
+	for _, p := range trajectory {
+		col, row, _ := g.CellAt(p.X, p.Y)
+		cell := [2]int{col, row}
+		if len(cells) == 0 || cells[len(cells)-1] != cell {
+			cells = append(cells, cell)
+		}
+	}
+	return cells
+}
+
+// nearest returns the cell of cells closest to cell.
+func nearest(cells [][2]int, cell [2]int) [2]int {
+	best, bestDistance := cells[0], math.MaxInt
+	for _, c := range cells {
+		dx, dy := c[0]-cell[0], c[1]-cell[1]
+		if d := dx*dx + dy*dy; d < bestDistance {
+			best, bestDistance = c, d
+		}
+	}
+	return best
+}
+
+// traceLine marks the cells on the line from one cell to another as free with Bresenham's algorithm. The last cell
+// is only marked if includeEnd is set. Occupied cells are left as they are.
+func (g *Grid) traceLine(from, to [2]int, includeEnd bool) {
+	x, y := from[0], from[1]
+	dx, dy := abs(to[0]-x), -abs(to[1]-y)
+	sx, sy := sign(to[0]-x), sign(to[1]-y)
+	e := dx + dy
+	for {
+		atEnd := x == to[0] && y == to[1]
+		if (!atEnd || includeEnd) && g.Cell(x, y) != Occupied {
+			g.set(x, y, Free)
+		}
+		if atEnd {
+			return
+		}
+		if e2 := 2 * e; e2 >= dy {
+			e += dy
+			x += sx
+		}
+		if e2 := 2 * e; e2 <= dx {
+			e += dx
+			y += sy
+		}
+	}
+}
+
+// inflate marks the cells within radius of the occupied cells as occupied.
+func (g *Grid) inflate(occupied [][2]int, radius float64) {
+	cells := int(math.Floor(radius / g.Resolution))
+	if cells == 0 {
+		return
+	}
+	var offsets [][2]int
+	for dy := -cells; dy <= cells; dy++ {
+		for dx := -cells; dx <= cells; dx++ {
+			if (dx != 0 || dy != 0) && dx*dx+dy*dy <= cells*cells {
+				offsets = append(offsets, [2]int{dx, dy})
+			}
+		}
+	}
+	for _, cell := range occupied {
+		for _, offset := range offsets {
+			// The grid is padded by the inflation radius, so the offsets stay within it.
+			g.set(cell[0]+offset[0], cell[1]+offset[1], Occupied)
+		}
+	}
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func sign(v int) int {
+	switch {
+	case v > 0:
+		return 1
+	case v < 0:
+		return -1
+	default:
+		return 0
+	}
+}
+
+// WritePGM writes the grid as a binary PGM image, in the convention of the ROS map_server: occupied cells are black,
+// free cells are white and unknown cells are grey. The first row of the image is the last row of the grid.
+func (g *Grid) WritePGM(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	if _, err := fmt.Fprintf(bw, "P5\n%d %d\n255\n", g.Width, g.Height); err != nil {
+		return err
+	}
+	row := make([]byte, g.Width)
+	for r := g.Height - 1; r >= 0; r-- {
+		for c := range row {
+			switch g.Cell(c, r) {
+			case Occupied:
+				row[c] = pgmOccupied
+			case Free:
+				row[c] = pgmFree
+			default:
+				row[c] = pgmUnknown
+			}
+		}
+		if _, err := bw.Write(row); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// WriteYAML writes the ROS map_server metadata of the grid, for the PGM image at imagePath.
+func (g *Grid) WriteYAML(w io.Writer, imagePath string) error {
+	_, err := fmt.Fprintf(w,
+		"image: %s\nresolution: %g\norigin: [%g, %g, 0.0]\nnegate: 0\noccupied_thresh: 0.65\nfree_thresh: 0.196\n",
+		imagePath, g.Resolution, g.OriginX, g.OriginY)
+	return err
+}
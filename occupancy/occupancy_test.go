@@ -0,0 +1,137 @@
+package occupancy
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/golang/geo/r3"
+	"go.viam.com/test"
+)
+
+// wallScene is a wall along y = 3.5 in front of a trajectory along y = 0.5, with a point above the wall.
+func wallScene() ([]r3.Vector, []r3.Vector) {
+	var points, trajectory []r3.Vector
+	for x := 0.5; x < 5; x++ {
+		points = append(points, r3.Vector{X: x, Y: 3.5, Z: 1})
+		trajectory = append(trajectory, r3.Vector{X: x, Y: 0.5})
+	}
+	points = append(points, r3.Vector{X: 2.5, Y: 1.5, Z: 5})
+	return points, trajectory
+}
+
+func cellValue(t *testing.T, grid *Grid, x, y float64) int8 {
+	t.Helper()
+	col, row, ok := grid.CellAt(x, y)
+	test.That(t, ok, test.ShouldBeTrue)
+	return grid.Cell(col, row)
+}
+
+func TestBuild(t *testing.T) {
+	points, trajectory := wallScene()
+
+	t.Run("Projects points within the height band and ray casts free space", func(t *testing.T) {
+		grid, err := Build(points, trajectory, Config{Resolution: 1, MinHeight: 0, MaxHeight: 2})
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, grid.OriginX, test.ShouldEqual, -1)
+		test.That(t, grid.OriginY, test.ShouldEqual, -1)
+		test.That(t, grid.Width, test.ShouldEqual, 7)
+		test.That(t, grid.Height, test.ShouldEqual, 6)
+		test.That(t, len(grid.Cells), test.ShouldEqual, 42)
+
+		for x := 0.5; x < 5; x++ {
+			test.That(t, cellValue(t, grid, x, 3.5), test.ShouldEqual, Occupied)
+			test.That(t, cellValue(t, grid, x, 0.5), test.ShouldEqual, Free)
+			test.That(t, cellValue(t, grid, x, 4.5), test.ShouldEqual, Unknown)
+		}
+		// The point above the height band is not an obstacle.
+		test.That(t, cellValue(t, grid, 2.5, 1.5), test.ShouldEqual, Free)
+		test.That(t, cellValue(t, grid, 2.5, 2.5), test.ShouldEqual, Free)
+		test.That(t, cellValue(t, grid, -0.5, -0.5), test.ShouldEqual, Unknown)
+	})
+
+	t.Run("Inflates obstacles", func(t *testing.T) {
+		grid, err := Build(points, trajectory, Config{Resolution: 1, MinHeight: 0, MaxHeight: 2, InflationRadius: 1})
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, grid.OriginX, test.ShouldEqual, -2)
+		test.That(t, cellValue(t, grid, 2.5, 2.5), test.ShouldEqual, Occupied)
+		test.That(t, cellValue(t, grid, 2.5, 4.5), test.ShouldEqual, Occupied)
+		test.That(t, cellValue(t, grid, -0.5, 3.5), test.ShouldEqual, Occupied)
+		test.That(t, cellValue(t, grid, -0.5, 2.5), test.ShouldEqual, Unknown)
+		test.That(t, cellValue(t, grid, 2.5, 1.5), test.ShouldEqual, Free)
+	})
+
+	t.Run("Builds without a trajectory", func(t *testing.T) {
+		grid, err := Build(points, nil, Config{Resolution: 0.5, MinHeight: 0, MaxHeight: 2})
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, cellValue(t, grid, 2.5, 3.5), test.ShouldEqual, Occupied)
+		test.That(t, cellValue(t, grid, 2.5, 3.25), test.ShouldEqual, Unknown)
+	})
+
+	t.Run("Fails without points or trajectory", func(t *testing.T) {
+		_, err := Build(points, nil, Config{Resolution: 1, MinHeight: 10, MaxHeight: 20})
+		test.That(t, err.Error(), test.ShouldContainSubstring, "no points within the height band")
+	})
+
+	t.Run("Fails with an invalid config", func(t *testing.T) {
+		_, err := Build(points, trajectory, Config{MaxHeight: 2})
+		test.That(t, err.Error(), test.ShouldEqual, "occupancy grid resolution must be greater than zero")
+		_, err = Build(points, trajectory, Config{Resolution: 1, MinHeight: 3, MaxHeight: 2})
+		test.That(t, err.Error(), test.ShouldEqual, "occupancy grid min_height must not be greater than max_height")
+		_, err = Build(points, trajectory, Config{Resolution: 1, MaxHeight: 2, InflationRadius: -1})
+		test.That(t, err.Error(), test.ShouldEqual, "occupancy grid inflation_radius must not be less than zero")
+	})
+
+	t.Run("Fails when the grid is too large", func(t *testing.T) {
+		_, err := Build(points, trajectory, Config{Resolution: 1e-4, MaxHeight: 2})
+		test.That(t, err.Error(), test.ShouldContainSubstring, "is too large")
+	})
+}
+
+func TestWrite(t *testing.T) {
+	grid := &Grid{
+		Resolution: 0.05,
+		OriginX:    -1.5,
+		OriginY:    2,
+		Width:      3,
+		Height:     2,
+		Cells:      []int8{Free, Occupied, Unknown, Unknown, Free, Free},
+	}
+
+	t.Run("PGM", func(t *testing.T) {
+		var buf bytes.Buffer
+		test.That(t, grid.WritePGM(&buf), test.ShouldBeNil)
+		header := "P5\n3 2\n255\n"
+		test.That(t, buf.String()[:len(header)], test.ShouldEqual, header)
+		test.That(t, buf.Bytes()[len(header):], test.ShouldResemble, []byte{205, 254, 254, 254, 0, 205})
+	})
+
+	t.Run("YAML", func(t *testing.T) {
+		var buf bytes.Buffer
+		test.That(t, grid.WriteYAML(&buf, "map.pgm"), test.ShouldBeNil)
+		test.That(t, buf.String(), test.ShouldEqual, "image: map.pgm\nresolution: 0.05\norigin: [-1.5, 2, 0.0]\n"+
+			"negate: 0\noccupied_thresh: 0.65\nfree_thresh: 0.196\n")
+	})
+}
+
+func TestTrajectory(t *testing.T) {
+	trajectory := NewTrajectory(3)
+	trajectory.Add(r3.Vector{}, 0.1)
+	trajectory.Add(r3.Vector{X: 0.05}, 0.1)
+	trajectory.Add(r3.Vector{X: 0.2}, 0.1)
+	test.That(t, trajectory.Positions(), test.ShouldResemble, []r3.Vector{{}, {X: 0.2}})
+
+	trajectory.Add(r3.Vector{X: 0.4}, 0.1)
+	trajectory.Add(r3.Vector{X: 0.6}, 0.1)
+	test.That(t, trajectory.Positions(), test.ShouldResemble, []r3.Vector{{X: 0.2}, {X: 0.4}, {X: 0.6}})
+	trajectory.Add(r3.Vector{X: 0.8}, 0.1)
+	trajectory.Add(r3.Vector{X: 1}, 0.1)
+	test.That(t, trajectory.Positions(), test.ShouldResemble, []r3.Vector{{X: 0.6}, {X: 0.8}, {X: 1}})
+	// The spacing is checked against the newest position, wherever it is in the buffer.
+	trajectory.Add(r3.Vector{X: 1.05}, 0.1)
+	test.That(t, trajectory.Positions(), test.ShouldResemble, []r3.Vector{{X: 0.6}, {X: 0.8}, {X: 1}})
+
+	trajectory.Reset()
+	test.That(t, trajectory.Positions(), test.ShouldBeEmpty)
+	trajectory.Add(r3.Vector{X: 2}, 0.1)
+	test.That(t, trajectory.Positions(), test.ShouldResemble, []r3.Vector{{X: 2}})
+}
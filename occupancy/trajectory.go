@@ -0,0 +1,57 @@
+package occupancy
+
+import (
+	"sync"
+
+	"github.com/golang/geo/r3"
+)
+
+// Trajectory records the positions a map was built from, to mark the space between them and the map points as
+// free. Positions closer than the minimum spacing to the last recorded one are skipped. It is safe for concurrent
+// use.
+type Trajectory struct {
+	mu           sync.Mutex
+	maxPositions int
+	// positions is used as a ring buffer once it holds maxPositions, with the oldest position at start.
+	positions []r3.Vector
+	start     int
+}
+
+// NewTrajectory returns a trajectory that keeps at most maxPositions of the latest positions, or all of them if
+// maxPositions is not positive.
+func NewTrajectory(maxPositions int) *Trajectory {
+	return &Trajectory{maxPositions: maxPositions}
+}
+
+// Add records a position, unless it is closer than minSpacing to the last recorded one. The spacing is given with
+// every position, since the scale of the positions may only be known once some have been recorded.
+func (t *Trajectory) Add(p r3.Vector, minSpacing float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if n := len(t.positions); n > 0 && t.positions[(t.start+n-1)%n].Sub(p).Norm() < minSpacing {
+		return
+	}
+	if t.maxPositions <= 0 || len(t.positions) < t.maxPositions {
+		t.positions = append(t.positions, p)
+		return
+	}
+	t.positions[t.start] = p
+	t.start = (t.start + 1) % len(t.positions)
+}
+
+// Positions returns a copy of the recorded positions, oldest first.
+func (t *Trajectory) Positions() []r3.Vector {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	positions := make([]r3.Vector, 0, len(t.positions))
+	positions = append(positions, t.positions[t.start:]...)
+	return append(positions, t.positions[:t.start]...)
+}
+
+// Reset removes all recorded positions.
+func (t *Trajectory) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.positions = nil
+	t.start = 0
+}
@@ -0,0 +1,170 @@
+package viamorbslam3
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/golang/geo/r3"
+	"github.com/pkg/errors"
+	goutils "go.viam.com/utils"
+
+	"github.com/viamrobotics/viam-orb-slam3/atomicfile"
+	orbSlamConfig "github.com/viamrobotics/viam-orb-slam3/config"
+	"github.com/viamrobotics/viam-orb-slam3/dataprocess"
+	"github.com/viamrobotics/viam-orb-slam3/gravity"
+	"github.com/viamrobotics/viam-orb-slam3/occupancy"
+	"github.com/viamrobotics/viam-orb-slam3/pcd"
+	orbSlamUtils "github.com/viamrobotics/viam-orb-slam3/utils"
+)
+
+const (
+	trajectoryIntervalMsec = 200
+	trajectorySpacingMm    = 10
+	maxTrajectoryPositions = 100000
+	// defaultMillimetersPerUnit converts SLAM units to mm while the scale of a monocular map is unknown. Monocular maps
+	// start out with a median scene depth of one unit, which is about a meter indoors.
+	defaultMillimetersPerUnit = 1000
+)
+
+// startTrajectoryRecording starts the background loop that records the path of the camera for occupancy grids,
+// whether or not clients ask for positions.
+func (orbSvc *orbslamService) startTrajectoryRecording(cancelCtx context.Context) {
+	orbSvc.activeBackgroundWorkers.Add(1)
+	goutils.PanicCapturingGo(func() {
+		defer orbSvc.activeBackgroundWorkers.Done()
+		for goutils.SelectContextOrWait(cancelCtx, trajectoryIntervalMsec*time.Millisecond) {
+			position, err := orbSvc.getSLAMPosition(cancelCtx)
+			if err != nil {
+				orbSvc.logger.Debugw("error getting SLAM position for the trajectory", "error", err)
+				continue
+			}
+			orbSvc.recordTrajectory(position)
+		}
+	})
+}
+
+// recordTrajectory adds the position reported by the slam library to the trajectory, while it is tracking.
+// Positions are kept in SLAM units, since the scale and gravity alignment of the map may change later.
+func (orbSvc *orbslamService) recordTrajectory(position slamPosition) {
+	switch position.trackingInfo.State {
+	case orbSlamUtils.TrackingStateOK, orbSlamUtils.TrackingStateUnknown:
+		mmPerUnit, ok := orbSvc.millimetersPerUnit()
+		if !ok {
+			mmPerUnit = defaultMillimetersPerUnit
+		}
+		orbSvc.trajectory.Add(position.pose.Point(), trajectorySpacingMm/mmPerUnit)
+	case orbSlamUtils.TrackingStateNotInitialized, orbSlamUtils.TrackingStateRecentlyLost, orbSlamUtils.TrackingStateLost:
+	}
+}
+
+// zUpRotation returns the smallest rotation that turns up in the frame poses are reported in into z. Up is that of the
+// camera when the map starts, or exactly opposite gravity once the map is aligned with it. In the optical convention
+// of the camera frame, with y down and z forward, the grid is then in the x-z plane.
+func (orbSvc *orbslamService) zUpRotation() gravity.Rotation {
+	return gravity.RotationBetween(orbSvc.poseTransform.DirectionFromCamera(cameraUp), r3.Vector{Z: 1})
+}
+
+// decodeOccupancyConfig returns the occupancy grid parameters of the config, with the ones given in args replacing
+// them.
+func decodeOccupancyConfig(base *orbSlamConfig.OccupancyConfig, args interface{}) (occupancy.Config, error) {
+	var cfg orbSlamConfig.OccupancyConfig
+	if base != nil {
+		cfg = *base
+	}
+	if argsMap, ok := args.(map[string]interface{}); ok && len(argsMap) > 0 {
+		data, err := json.Marshal(argsMap)
+		if err != nil {
+			return occupancy.Config{}, errors.Wrap(err, "invalid occupancy grid parameters")
+		}
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&cfg); err != nil {
+			return occupancy.Config{}, errors.Wrap(err, "invalid occupancy grid parameters")
+		}
+	}
+	grid := cfg.Grid()
+	return grid, grid.Validate()
+}
+
+// exportOccupancyGrid builds a 2D occupancy grid from the point cloud map as GetPointCloudMap returns it and the
+// trajectory recorded in the background, and writes it to the exports folder of the data directory as a PGM image
+// with its ROS map_server YAML.
+func (orbSvc *orbslamService) exportOccupancyGrid(ctx context.Context, args interface{}) (map[string]interface{}, error) {
+	argsMap, _ := args.(map[string]interface{})
+	filters, err := orbSvc.requestFilters(argsMap)
+	if err != nil {
+		return nil, err
+	}
+	gridArgs := make(map[string]interface{}, len(argsMap))
+	for k, v := range argsMap {
+		if k != "point_cloud_filters" {
+			gridArgs[k] = v
+		}
+	}
+	cfg, err := decodeOccupancyConfig(orbSvc.occupancyConfig, gridArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := orbSvc.processedPointCloudMapData(ctx, filters)
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting point cloud map")
+	}
+	points, err := pcd.Read(data)
+	if err != nil {
+		return nil, err
+	}
+	transform, err := orbSvc.pointCloudTransform()
+	if err != nil {
+		return nil, err
+	}
+	trajectory := processPoints(orbSvc.trajectory.Positions(), transform.fn(), nil)
+	zUp := orbSvc.zUpRotation()
+	for i := range points {
+		points[i] = zUp.Rotate(points[i])
+	}
+	for i := range trajectory {
+		trajectory[i] = zUp.Rotate(trajectory[i])
+	}
+
+	grid, err := occupancy.Build(points, trajectory, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Join(orbSvc.dataDirectory, exportsDirectoryName)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, errors.Wrap(err, "unable to create exports directory")
+	}
+	base := "occupancy_" + time.Now().UTC().Format(dataprocess.SlamTimeFormat)
+	pgmPath := filepath.Join(dir, base+".pgm")
+	yamlPath := filepath.Join(dir, base+".yaml")
+	var pgm, yaml bytes.Buffer
+	if err := grid.WritePGM(&pgm); err != nil {
+		return nil, err
+	}
+	// The map_server resolves the image relative to the YAML file.
+	if err := grid.WriteYAML(&yaml, filepath.Base(pgmPath)); err != nil {
+		return nil, err
+	}
+	if err := atomicfile.WriteFile(pgmPath, pgm.Bytes(), 0o644); err != nil {
+		return nil, errors.Wrap(err, "unable to write occupancy grid image")
+	}
+	if err := atomicfile.WriteFile(yamlPath, yaml.Bytes(), 0o644); err != nil {
+		return nil, errors.Wrap(err, "unable to write occupancy grid metadata")
+	}
+
+	return map[string]interface{}{
+		"pgm_path":             pgmPath,
+		"yaml_path":            yamlPath,
+		"width":                grid.Width,
+		"height":               grid.Height,
+		"resolution":           grid.Resolution,
+		"origin":               map[string]interface{}{"x": grid.OriginX, "y": grid.OriginY},
+		"trajectory_positions": len(trajectory),
+	}, nil
+}
@@ -4,20 +4,22 @@ package viamorbslam3
 
 import (
 	"context"
-	"io/fs"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/edaniels/golog"
 	"github.com/pkg/errors"
 	"go.viam.com/rdk/components/camera"
 	"go.viam.com/rdk/rimage/transform"
 	"go.viam.com/rdk/utils"
 	"gopkg.in/yaml.v2"
 
+	"github.com/viamrobotics/viam-orb-slam3/datadir"
 	"github.com/viamrobotics/viam-orb-slam3/dataprocess"
+	"github.com/viamrobotics/viam-orb-slam3/mapmeta"
 )
 
 const (
@@ -28,6 +30,17 @@ const (
 
 // orbCamMaker takes in the camera properties and config params for orbslam and constructs a ORBsettings struct to use with yaml.Marshal.
 func (orbSvc *orbslamService) orbCamMaker(camProperties *transform.PinholeCameraModel) (*ORBsettings, error) {
+	return NewORBSettings(camProperties, orbSvc.configParams, orbSvc.dataRateMs, orbSvc.logger)
+}
+
+// NewORBSettings constructs the ORBsettings of a camera from its properties, the config params for orbslam and the
+// rate at which its data is captured.
+func NewORBSettings(
+	camProperties *transform.PinholeCameraModel,
+	configParams map[string]string,
+	dataRateMs int,
+	logger golog.Logger,
+) (*ORBsettings, error) {
 	var err error
 
 	if camProperties.PinholeCameraIntrinsics == nil {
@@ -42,15 +55,15 @@ func (orbSvc *orbslamService) orbCamMaker(camProperties *transform.PinholeCamera
 		Fy:          intrinsics.Fy,
 		Ppx:         intrinsics.Ppx,
 		Ppy:         intrinsics.Ppy,
-		FPSCamera:   int16(orbSvc.dataRateMs),
+		FPSCamera:   int16(dataRateMs),
 		FileVersion: fileVersion,
 	}
-	if orbSvc.dataRateMs <= 0 {
+	if dataRateMs <= 0 {
 		// dataRateMs is always expected to be positive, since 0 gets reset to the default, and all other
 		// values lower than the default are rejected
-		return nil, errors.Errorf("orbslam yaml generation expected dataRateMs greater than 0, got %d", orbSvc.dataRateMs)
+		return nil, errors.Errorf("orbslam yaml generation expected dataRateMs greater than 0, got %d", dataRateMs)
 	}
-	orbslam.FPSCamera = int16(1000 / orbSvc.dataRateMs)
+	orbslam.FPSCamera = int16(1000 / dataRateMs)
 	if orbslam.FPSCamera == 0 {
 		orbslam.FPSCamera = 1
 	}
@@ -63,31 +76,31 @@ func (orbSvc *orbslamService) orbCamMaker(camProperties *transform.PinholeCamera
 	orbslam.RadialK3 = distortion.RadialK3
 	orbslam.TangentialP1 = distortion.TangentialP1
 	orbslam.TangentialP2 = distortion.TangentialP2
-	if orbslam.NFeatures, err = orbSvc.orbConfigToInt("orb_n_features", 1250); err != nil {
+	if orbslam.NFeatures, err = orbConfigToInt(configParams, logger, "orb_n_features", 1250); err != nil {
 		return nil, err
 	}
-	if orbslam.ScaleFactor, err = orbSvc.orbConfigToFloat("orb_scale_factor", 1.2); err != nil {
+	if orbslam.ScaleFactor, err = orbConfigToFloat(configParams, logger, "orb_scale_factor", 1.2); err != nil {
 		return nil, err
 	}
-	if orbslam.StereoThDepth, err = orbSvc.orbConfigToFloat("stereo_th_depth", 40); err != nil {
+	if orbslam.StereoThDepth, err = orbConfigToFloat(configParams, logger, "stereo_th_depth", 40); err != nil {
 		return nil, err
 	}
-	if orbslam.DepthMapFactor, err = orbSvc.orbConfigToFloat("depth_map_factor", 1000); err != nil {
+	if orbslam.DepthMapFactor, err = orbConfigToFloat(configParams, logger, "depth_map_factor", 1000); err != nil {
 		return nil, err
 	}
-	if orbslam.NLevels, err = orbSvc.orbConfigToInt("orb_n_levels", 8); err != nil {
+	if orbslam.NLevels, err = orbConfigToInt(configParams, logger, "orb_n_levels", 8); err != nil {
 		return nil, err
 	}
-	if orbslam.IniThFAST, err = orbSvc.orbConfigToInt("orb_n_ini_th_fast", 20); err != nil {
+	if orbslam.IniThFAST, err = orbConfigToInt(configParams, logger, "orb_n_ini_th_fast", 20); err != nil {
 		return nil, err
 	}
-	if orbslam.MinThFAST, err = orbSvc.orbConfigToInt("orb_n_min_th_fast", 7); err != nil {
+	if orbslam.MinThFAST, err = orbConfigToInt(configParams, logger, "orb_n_min_th_fast", 7); err != nil {
 		return nil, err
 	}
-	if orbslam.Stereob, err = orbSvc.orbConfigToFloat("stereo_b", 0.0745); err != nil {
+	if orbslam.Stereob, err = orbConfigToFloat(configParams, logger, "stereo_b", 0.0745); err != nil {
 		return nil, err
 	}
-	tmp, err := orbSvc.orbConfigToInt("rgb_flag", 0)
+	tmp, err := orbConfigToInt(configParams, logger, "rgb_flag", 0)
 	if err != nil {
 		return nil, err
 	}
@@ -151,12 +164,17 @@ func (orbSvc *orbslamService) orbGenYAML(ctx context.Context, cam camera.Camera)
 	if err != nil {
 		return err
 	}
+	orbSvc.setMapCamera(orbslam)
 
 	// Check for maps in the specified directory and add map to yaml config
 	loadMapTimeStamp, loadMapName, err := orbSvc.checkMaps()
 	if err != nil {
 		orbSvc.logger.Debugf("Error occurred while parsing %s for maps, building map from scratch", orbSvc.dataDirectory)
 	}
+	loadMapTimeStamp, loadMapName, err = orbSvc.resolveMapToLoad(ctx, loadMapTimeStamp, loadMapName)
+	if err != nil {
+		return err
+	}
 	if loadMapTimeStamp == "" {
 		loadMapTimeStamp = time.Now().UTC().Format(dataprocess.SlamTimeFormat)
 	} else {
@@ -166,16 +184,21 @@ func (orbSvc *orbslamService) orbGenYAML(ctx context.Context, cam camera.Camera)
 	// yamlFileName uses the timestamp from the loaded map if one was available
 	// this gives the option to load images into the map if they were generated at a later time
 	// orbslam also checks for the most recently generated yaml file to prevent any issues with timestamps here
-	yamlFileName := filepath.Join(orbSvc.dataDirectory, "config", orbSvc.primarySensorName+"_data_"+loadMapTimeStamp+".yaml")
+	yamlFileName := filepath.Join(orbSvc.dataDirectory, "config", orbSvc.primarySensorName+
+		dataprocess.TimestampSeparator+loadMapTimeStamp+".yaml")
 
-	// generate yaml file
-	yamlData, err := yaml.Marshal(&orbslam)
+	return WriteORBYAML(yamlFileName, orbslam)
+}
+
+// WriteORBYAML writes the settings to a .yaml file in the format orbslam reads.
+func WriteORBYAML(path string, settings *ORBsettings) error {
+	yamlData, err := yaml.Marshal(settings)
 	if err != nil {
 		return errors.Wrap(err, "Error while Marshaling YAML file")
 	}
 
 	//nolint:gosec
-	outfile, err := os.Create(yamlFileName)
+	outfile, err := os.Create(path)
 	if err != nil {
 		return err
 	}
@@ -190,10 +213,10 @@ func (orbSvc *orbslamService) orbGenYAML(ctx context.Context, cam camera.Camera)
 	return outfile.Close()
 }
 
-func (orbSvc *orbslamService) orbConfigToInt(key string, def int) (int, error) {
-	valStr, ok := orbSvc.configParams[key]
+func orbConfigToInt(configParams map[string]string, logger golog.Logger, key string, def int) (int, error) {
+	valStr, ok := configParams[key]
 	if !ok {
-		orbSvc.logger.Debugf("Parameter %s not found, using default value %d", key, def)
+		logger.Debugf("Parameter %s not found, using default value %d", key, def)
 		return def, nil
 	}
 
@@ -205,10 +228,10 @@ func (orbSvc *orbslamService) orbConfigToInt(key string, def int) (int, error) {
 	return val, nil
 }
 
-func (orbSvc *orbslamService) orbConfigToFloat(key string, def float64) (float64, error) {
-	valStr, ok := orbSvc.configParams[key]
+func orbConfigToFloat(configParams map[string]string, logger golog.Logger, key string, def float64) (float64, error) {
+	valStr, ok := configParams[key]
 	if !ok {
-		orbSvc.logger.Debugf("Parameter %s not found, using default value %f", key, def)
+		logger.Debugf("Parameter %s not found, using default value %f", key, def)
 		return def, nil
 	}
 
@@ -219,40 +242,58 @@ func (orbSvc *orbslamService) orbConfigToFloat(key string, def float64) (float64
 	return val, nil
 }
 
+// savedMap is a map saved in the map folder of the data directory.
+type savedMap struct {
+	// path is the path of the map without its extension, as ORB_SLAM3 expects it.
+	path      string
+	timestamp time.Time
+	// metadata is read from the sidecar of the map, or nil if it has none.
+	metadata *mapmeta.Metadata
+}
+
+// listMaps returns the maps in the map folder within the data directory that are named in our format, oldest first.
+func (orbSvc *orbslamService) listMaps() ([]savedMap, error) {
+	listed, err := datadir.ListMaps(orbSvc.dataDirectory)
+	if err != nil {
+		return nil, err
+	}
+	maps := make([]savedMap, 0, len(listed))
+	for _, m := range listed {
+		if m.MetadataErr != nil {
+			orbSvc.logger.Warnw("unable to read map metadata", "map", m.Path, "error", m.MetadataErr)
+		}
+		maps = append(maps, savedMap{
+			path:      strings.TrimSuffix(m.Path, mapmeta.MapExtension),
+			timestamp: m.Timestamp,
+			metadata:  m.Metadata,
+		})
+	}
+	return maps, nil
+}
+
 // checkMaps checks the map folder within the data directory for an existing map.
 // It will grab the most recently generated map if one exists.
 func (orbSvc *orbslamService) checkMaps() (string, string, error) {
-	root := filepath.Join(orbSvc.dataDirectory, "map")
-	mapExt := ".osa"
-	mapTimestamp := time.Time{}
-	var mapPath string
-
-	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
-		if !entry.IsDir() && filepath.Ext(path) == mapExt {
-			// check if the file uses our format and grab timestamp if it does
-			timestampLoc := strings.Index(entry.Name(), "_data_") + len("_data_")
-			if timestampLoc != -1+len("_data_") {
-				timestamp, err := time.Parse(dataprocess.SlamTimeFormat, entry.Name()[timestampLoc:strings.Index(entry.Name(), mapExt)])
-				if err != nil {
-					orbSvc.logger.Debugf("Unable to parse map %s, %v", path, err)
-					return nil
-				}
-				if timestamp.After(mapTimestamp) {
-					mapTimestamp = timestamp
-					mapPath = path[0:strings.Index(path, mapExt)]
-				}
-			}
-		}
-		return nil
-	})
+	maps, err := orbSvc.listMaps()
 	if err != nil {
 		return "", "", err
 	}
 	// do not error out here, instead orbslam will build a map from scratch
-	if mapTimestamp.IsZero() {
-		orbSvc.logger.Debugf("No maps found in directory %s", root)
+	if len(maps) == 0 {
+		orbSvc.logger.Debugf("No maps found in directory %s", filepath.Join(orbSvc.dataDirectory, "map"))
 		return "", "", nil
 	}
-	orbSvc.logger.Infof("Previous map found, using %v", mapPath)
-	return mapTimestamp.UTC().Format(dataprocess.SlamTimeFormat), mapPath, nil
+	latest := maps[len(maps)-1]
+	orbSvc.logger.Infof("Previous map found, using %v", latest.path)
+	if md := latest.metadata; md != nil {
+		orbSvc.logger.Infow("previous map metadata", "created_at", md.CreatedAt, "mode", md.Mode,
+			"keyframes", md.KeyFrames, "map_points", md.MapPoints, "module_version", md.ModuleVersion)
+	}
+	return latest.timestamp.UTC().Format(dataprocess.SlamTimeFormat), latest.path, nil
+}
+
+// hasMap returns whether the map folder within the data directory contains a map.
+func (orbSvc *orbslamService) hasMap() (bool, error) {
+	_, mapPath, err := orbSvc.checkMaps()
+	return mapPath != "", err
 }
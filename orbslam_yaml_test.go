@@ -22,6 +22,7 @@ import (
 	orbSlamConfig "github.com/viamrobotics/viam-orb-slam3/config"
 	"github.com/viamrobotics/viam-orb-slam3/dataprocess"
 	"github.com/viamrobotics/viam-orb-slam3/internal/testhelper"
+	"github.com/viamrobotics/viam-orb-slam3/mapmeta"
 )
 
 const yamlFilePrefixBytes = "%YAML:1.0\n"
@@ -177,6 +178,39 @@ func TestOrbslamYAMLNew(t *testing.T) {
 		test.That(t, orbslam.LoadMapLoc, test.ShouldEqual, "\""+fakeMap+"\"")
 	})
 
+	t.Run("New orbslamv3 service with an incompatible previous map", func(t *testing.T) {
+		test.That(t, mapmeta.Write(fakeMap+".osa", mapmeta.Metadata{Mode: "rgbd"}), test.ShouldBeNil)
+		defer func() {
+			test.That(t, os.Remove(mapmeta.SidecarPath(fakeMap+".osa")), test.ShouldBeNil)
+		}()
+
+		_, err := createSLAMService(t, attrCfgGood, logger, false, false, testExecutableName)
+		test.That(t, err.Error(), test.ShouldContainSubstring, "is incompatible with the current settings")
+		test.That(t, err.Error(), test.ShouldContainSubstring, "mode rgbd does not match mono")
+
+		grpcServer, port := setupTestGRPCServer(t)
+		attrCfgStartFresh := *attrCfgGood
+		attrCfgStartFresh.Port = "localhost:" + strconv.Itoa(port)
+		attrCfgStartFresh.MapCompatibility = orbSlamConfig.MapCompatibilityStartFresh
+		svc, err := createSLAMService(t, &attrCfgStartFresh, logger, false, true, testExecutableName)
+		test.That(t, err, test.ShouldBeNil)
+
+		grpcServer.Stop()
+		test.That(t, svc.Close(context.Background()), test.ShouldBeNil)
+
+		yamlFileTimeStamp, yamlFilePath, err := findLastYAML(name)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, yamlFileTimeStamp, test.ShouldNotEqual, fakeMapTimestamp)
+		yamlDataAll, err := os.ReadFile(yamlFilePath)
+		test.That(t, err, test.ShouldBeNil)
+		orbslam := viamorbslam3.ORBsettings{}
+		err = yaml.Unmarshal(bytes.Replace(yamlDataAll, []byte(yamlFilePrefixBytes), []byte(""), 1), &orbslam)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, orbslam.LoadMapLoc, test.ShouldEqual, "")
+		// Leave the yaml file of the previous map as the newest one for the following tests.
+		test.That(t, os.Remove(yamlFilePath), test.ShouldBeNil)
+	})
+
 	t.Run("New orbslamv3 service with high dataRateMs", func(t *testing.T) {
 		// Create slam service
 		grpcServer, port := setupTestGRPCServer(t)
@@ -0,0 +1,135 @@
+package pcd
+
+import (
+	"math"
+
+	"github.com/golang/geo/r3"
+)
+
+// Filter removes or replaces points of a point cloud.
+type Filter interface {
+	Apply(points []r3.Vector) []r3.Vector
+}
+
+// ApplyFilters applies filters to points in order.
+func ApplyFilters(points []r3.Vector, filters ...Filter) []r3.Vector {
+	for _, filter := range filters {
+		points = filter.Apply(points)
+	}
+	return points
+}
+
+// VoxelGrid downsamples a point cloud by replacing the points within every cube of LeafSize by their centroid.
+type VoxelGrid struct {
+	LeafSize float64
+}
+
+// Apply implements Filter. The returned points are in the order their voxels are first seen.
+func (filter VoxelGrid) Apply(points []r3.Vector) []r3.Vector {
+	if filter.LeafSize <= 0 {
+		return points
+	}
+	type voxel struct {
+		sum   r3.Vector
+		count int
+	}
+	index := map[[3]int64]int{}
+	var voxels []voxel
+	for _, p := range points {
+		key := [3]int64{
+			int64(math.Floor(p.X / filter.LeafSize)),
+			int64(math.Floor(p.Y / filter.LeafSize)),
+			int64(math.Floor(p.Z / filter.LeafSize)),
+		}
+		i, ok := index[key]
+		if !ok {
+			i = len(voxels)
+			index[key] = i
+			voxels = append(voxels, voxel{})
+		}
+		voxels[i].sum = voxels[i].sum.Add(p)
+		voxels[i].count++
+	}
+	filtered := make([]r3.Vector, len(voxels))
+	for i, v := range voxels {
+		filtered[i] = v.sum.Mul(1 / float64(v.count))
+	}
+	return filtered
+}
+
+// StatisticalOutlier removes points whose mean distance to their Neighbors nearest neighbors is more than
+// StdDevMultiplier standard deviations above the mean of that distance over the whole point cloud.
+type StatisticalOutlier struct {
+	Neighbors        int
+	StdDevMultiplier float64
+}
+
+// Apply implements Filter.
+func (filter StatisticalOutlier) Apply(points []r3.Vector) []r3.Vector {
+	if filter.Neighbors <= 0 || len(points) <= filter.Neighbors {
+		return points
+	}
+	tree := newKDTree(points)
+	meanDistances := make([]float64, len(points))
+	var sum, sumSquares float64
+	for i, p := range points {
+		var total float64
+		for _, d := range tree.nearestDistances(p, filter.Neighbors, i) {
+			total += math.Sqrt(d)
+		}
+		meanDistances[i] = total / float64(filter.Neighbors)
+		sum += meanDistances[i]
+		sumSquares += meanDistances[i] * meanDistances[i]
+	}
+	mean := sum / float64(len(points))
+	stdDev := math.Sqrt(math.Max(sumSquares/float64(len(points))-mean*mean, 0))
+	threshold := mean + filter.StdDevMultiplier*stdDev
+
+	filtered := make([]r3.Vector, 0, len(points))
+	for i, p := range points {
+		if meanDistances[i] <= threshold {
+			filtered = append(filtered, p)
+		}
+	}
+	return filtered
+}
+
+// RadiusOutlier removes points with fewer than MinNeighbors other points within Radius.
+type RadiusOutlier struct {
+	Radius       float64
+	MinNeighbors int
+}
+
+// Apply implements Filter.
+func (filter RadiusOutlier) Apply(points []r3.Vector) []r3.Vector {
+	if filter.Radius <= 0 || filter.MinNeighbors <= 0 {
+		return points
+	}
+	tree := newKDTree(points)
+	filtered := make([]r3.Vector, 0, len(points))
+	for i, p := range points {
+		if tree.countWithin(p, filter.Radius, i, filter.MinNeighbors) >= filter.MinNeighbors {
+			filtered = append(filtered, p)
+		}
+	}
+	return filtered
+}
+
+// Crop keeps the points within the axis aligned box from Min to Max, inclusive.
+type Crop struct {
+	Min r3.Vector
+	Max r3.Vector
+}
+
+// Apply implements Filter.
+func (filter Crop) Apply(points []r3.Vector) []r3.Vector {
+	filtered := make([]r3.Vector, 0, len(points))
+	for _, p := range points {
+		if p.X >= filter.Min.X && p.X <= filter.Max.X &&
+			p.Y >= filter.Min.Y && p.Y <= filter.Max.Y &&
+			p.Z >= filter.Min.Z && p.Z <= filter.Max.Z {
+			filtered = append(filtered, p)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,110 @@
+package pcd
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/golang/geo/r3"
+	"go.viam.com/test"
+)
+
+// cluster returns n points spread evenly on a cube of the given size around center.
+func cluster(center r3.Vector, size float64, n int) []r3.Vector {
+	rng := rand.New(rand.NewSource(1))
+	points := make([]r3.Vector, n)
+	for i := range points {
+		points[i] = center.Add(r3.Vector{X: rng.Float64() - 0.5, Y: rng.Float64() - 0.5, Z: rng.Float64() - 0.5}.Mul(size))
+	}
+	return points
+}
+
+func TestKDTree(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	points := make([]r3.Vector, 500)
+	for i := range points {
+		points[i] = r3.Vector{X: rng.Float64(), Y: rng.Float64(), Z: rng.Float64()}
+	}
+	tree := newKDTree(points)
+
+	for q := 0; q < 20; q++ {
+		query := points[q]
+		var distances []float64
+		for i, p := range points {
+			if i != q {
+				distances = append(distances, p.Sub(query).Norm2())
+			}
+		}
+		sort.Float64s(distances)
+
+		t.Run("Nearest distances match a brute force search", func(t *testing.T) {
+			nearest := tree.nearestDistances(query, 8, q)
+			sort.Float64s(nearest)
+			test.That(t, nearest, test.ShouldResemble, distances[:8])
+		})
+
+		t.Run("Count within radius matches a brute force search", func(t *testing.T) {
+			radius := 0.1
+			expected := sort.SearchFloat64s(distances, radius*radius+1e-12)
+			test.That(t, tree.countWithin(query, radius, q, len(points)), test.ShouldEqual, expected)
+			test.That(t, tree.countWithin(query, radius, q, 1), test.ShouldEqual, int(math.Min(1, float64(expected))))
+		})
+	}
+}
+
+func TestFilters(t *testing.T) {
+	outlier := r3.Vector{X: 10, Y: 10, Z: 10}
+	points := append(cluster(r3.Vector{}, 1, 200), outlier)
+
+	t.Run("Voxel grid", func(t *testing.T) {
+		filtered := VoxelGrid{LeafSize: 1}.Apply([]r3.Vector{
+			{X: 0.1, Y: 0.1, Z: 0.1},
+			{X: 0.3, Y: 0.5, Z: 0.7},
+			{X: 1.5, Y: 0.5, Z: 0.5},
+			{X: -0.5, Y: 0.5, Z: 0.5},
+		})
+		test.That(t, filtered, test.ShouldHaveLength, 3)
+		test.That(t, filtered[0].X, test.ShouldAlmostEqual, 0.2)
+		test.That(t, filtered[0].Y, test.ShouldAlmostEqual, 0.3)
+		test.That(t, filtered[0].Z, test.ShouldAlmostEqual, 0.4)
+		test.That(t, filtered[1], test.ShouldResemble, r3.Vector{X: 1.5, Y: 0.5, Z: 0.5})
+		test.That(t, filtered[2], test.ShouldResemble, r3.Vector{X: -0.5, Y: 0.5, Z: 0.5})
+
+		test.That(t, len(VoxelGrid{LeafSize: 0.5}.Apply(points)), test.ShouldBeLessThanOrEqualTo, 9)
+		test.That(t, VoxelGrid{}.Apply(points), test.ShouldResemble, points)
+	})
+
+	t.Run("Statistical outlier removal", func(t *testing.T) {
+		filtered := StatisticalOutlier{Neighbors: 8, StdDevMultiplier: 2}.Apply(points)
+		test.That(t, filtered, test.ShouldNotContain, outlier)
+		test.That(t, len(filtered), test.ShouldBeGreaterThan, 190)
+		test.That(t, StatisticalOutlier{Neighbors: len(points)}.Apply(points), test.ShouldResemble, points)
+	})
+
+	t.Run("Radius outlier removal", func(t *testing.T) {
+		filtered := RadiusOutlier{Radius: 0.5, MinNeighbors: 3}.Apply(points)
+		test.That(t, filtered, test.ShouldNotContain, outlier)
+		test.That(t, filtered, test.ShouldHaveLength, 200)
+		test.That(t, RadiusOutlier{Radius: 0.5}.Apply(points), test.ShouldResemble, points)
+	})
+
+	t.Run("Crop", func(t *testing.T) {
+		filtered := Crop{Min: r3.Vector{X: -1, Y: -1, Z: 0}, Max: r3.Vector{X: 1, Y: 1, Z: 1}}.Apply(points)
+		test.That(t, filtered, test.ShouldNotContain, outlier)
+		for _, p := range filtered {
+			test.That(t, p.Z, test.ShouldBeGreaterThanOrEqualTo, 0)
+		}
+		test.That(t, len(filtered), test.ShouldBeBetween, 50, 150)
+	})
+
+	t.Run("Filters are applied in order", func(t *testing.T) {
+		filtered := ApplyFilters(points,
+			Crop{Min: r3.Vector{X: -20, Y: -20, Z: -20}, Max: r3.Vector{X: 20, Y: 20, Z: 20}},
+			RadiusOutlier{Radius: 0.5, MinNeighbors: 3},
+			VoxelGrid{LeafSize: 100},
+		)
+		// The cluster around the origin spans one voxel per octant, and the outlier would have added another one.
+		test.That(t, filtered, test.ShouldHaveLength, 8)
+	})
+}
@@ -0,0 +1,125 @@
+package pcd
+
+import (
+	"container/heap"
+	"sort"
+
+	"github.com/golang/geo/r3"
+)
+
+// kdTree is a static 3 dimensional tree used for the neighbor searches of the outlier filters.
+type kdTree struct {
+	points []r3.Vector
+	// nodes holds the point indices in tree order: the median of every range is the node splitting it.
+	nodes []int
+}
+
+func newKDTree(points []r3.Vector) *kdTree {
+	tree := &kdTree{points: points, nodes: make([]int, len(points))}
+	for i := range tree.nodes {
+		tree.nodes[i] = i
+	}
+	tree.build(0, len(points), 0)
+	return tree
+}
+
+func axisValue(p r3.Vector, axis int) float64 {
+	switch axis {
+	case 0:
+		return p.X
+	case 1:
+		return p.Y
+	default:
+		return p.Z
+	}
+}
+
+func (tree *kdTree) build(lo, hi, axis int) {
+	if hi-lo <= 1 {
+		return
+	}
+	nodes := tree.nodes[lo:hi]
+	sort.Slice(nodes, func(i, j int) bool {
+		return axisValue(tree.points[nodes[i]], axis) < axisValue(tree.points[nodes[j]], axis)
+	})
+	mid := (lo + hi) / 2
+	tree.build(lo, mid, (axis+1)%3)
+	tree.build(mid+1, hi, (axis+1)%3)
+}
+
+// countWithin returns the number of points other than the point at index skip within radius of query, stopping once
+// limit is reached.
+func (tree *kdTree) countWithin(query r3.Vector, radius float64, skip, limit int) int {
+	count := 0
+	radius2 := radius * radius
+	var search func(lo, hi, axis int)
+	search = func(lo, hi, axis int) {
+		if lo >= hi || count >= limit {
+			return
+		}
+		mid := (lo + hi) / 2
+		index := tree.nodes[mid]
+		if index != skip && tree.points[index].Sub(query).Norm2() <= radius2 {
+			count++
+		}
+		diff := axisValue(query, axis) - axisValue(tree.points[index], axis)
+		next := (axis + 1) % 3
+		if diff <= radius {
+			search(lo, mid, next)
+		}
+		if diff >= -radius {
+			search(mid+1, hi, next)
+		}
+	}
+	search(0, len(tree.nodes), 0)
+	return count
+}
+
+// distanceHeap is a max heap of squared distances.
+type distanceHeap []float64
+
+func (h distanceHeap) Len() int            { return len(h) }
+func (h distanceHeap) Less(i, j int) bool  { return h[i] > h[j] }
+func (h distanceHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *distanceHeap) Push(x interface{}) { *h = append(*h, x.(float64)) }
+func (h *distanceHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// nearestDistances returns the squared distances from query to its k nearest points other than the point at index
+// skip, in no particular order.
+func (tree *kdTree) nearestDistances(query r3.Vector, k, skip int) []float64 {
+	nearest := make(distanceHeap, 0, k+1)
+	var search func(lo, hi, axis int)
+	search = func(lo, hi, axis int) {
+		if lo >= hi {
+			return
+		}
+		mid := (lo + hi) / 2
+		index := tree.nodes[mid]
+		if index != skip {
+			d := tree.points[index].Sub(query).Norm2()
+			if len(nearest) < k {
+				heap.Push(&nearest, d)
+			} else if d < nearest[0] {
+				nearest[0] = d
+				heap.Fix(&nearest, 0)
+			}
+		}
+		diff := axisValue(query, axis) - axisValue(tree.points[index], axis)
+		next := (axis + 1) % 3
+		near, far := [2]int{lo, mid}, [2]int{mid + 1, hi}
+		if diff > 0 {
+			near, far = far, near
+		}
+		search(near[0], near[1], next)
+		if len(nearest) < k || diff*diff < nearest[0] {
+			search(far[0], far[1], next)
+		}
+	}
+	search(0, len(tree.nodes), 0)
+	return nearest
+}
@@ -0,0 +1,226 @@
+// Package pcd reads and writes point clouds in the PCD format used by the SLAM algorithm.
+package pcd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/golang/geo/r3"
+	"github.com/pkg/errors"
+)
+
+// headerTemplate is the header written by the SLAM algorithm for a point cloud of x, y and z float32 fields.
+const headerTemplate = "VERSION .7\n" +
+	"FIELDS x y z\n" +
+	"SIZE 4 4 4\n" +
+	"TYPE F F F\n" +
+	"COUNT 1 1 1\n" +
+	"WIDTH %d\n" +
+	"HEIGHT 1\n" +
+	"VIEWPOINT 0 0 0 1 0 0 0\n" +
+	"POINTS %d\n" +
+	"DATA binary\n"
+
+// field is a single field of a PCD point.
+type field struct {
+	name   string
+	size   int
+	typ    string
+	count  int
+	offset int
+}
+
+// header is the parsed header of a PCD file.
+type header struct {
+	fields    []field
+	pointSize int
+	points    int
+	data      string
+}
+
+// Read parses a PCD file and returns the x, y and z coordinates of its points. Ascii and binary data are supported,
+// and fields other than x, y and z are ignored.
+func Read(data []byte) ([]r3.Vector, error) {
+	reader := bufio.NewReader(bytes.NewReader(data))
+	h, err := readHeader(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	var xyz [3]field
+	for i, name := range []string{"x", "y", "z"} {
+		found := false
+		for _, f := range h.fields {
+			if f.name == name {
+				xyz[i] = f
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, errors.Errorf("error reading pcd: field %v missing", name)
+		}
+		if xyz[i].typ != "F" || (xyz[i].size != 4 && xyz[i].size != 8) {
+			return nil, errors.Errorf("error reading pcd: field %v must be a float", name)
+		}
+	}
+
+	switch h.data {
+	case "binary":
+		return readBinary(reader, h, xyz)
+	case "ascii":
+		return readASCII(reader, h, xyz)
+	default:
+		return nil, errors.Errorf("error reading pcd: unsupported data format %v", h.data)
+	}
+}
+
+func readHeader(reader *bufio.Reader) (header, error) {
+	var h header
+	var sizes, types, counts []string
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return header{}, errors.Wrap(err, "error reading pcd header")
+		}
+		tokens := strings.Fields(line)
+		if len(tokens) == 0 || strings.HasPrefix(tokens[0], "#") {
+			continue
+		}
+		switch tokens[0] {
+		case "FIELDS":
+			for _, name := range tokens[1:] {
+				h.fields = append(h.fields, field{name: name, count: 1})
+			}
+		case "SIZE":
+			sizes = tokens[1:]
+		case "TYPE":
+			types = tokens[1:]
+		case "COUNT":
+			counts = tokens[1:]
+		case "POINTS":
+			if len(tokens) != 2 {
+				return header{}, errors.New("error reading pcd header: invalid POINTS")
+			}
+			if h.points, err = strconv.Atoi(tokens[1]); err != nil || h.points < 0 {
+				return header{}, errors.Errorf("error reading pcd header: invalid POINTS %v", tokens[1])
+			}
+		case "DATA":
+			if len(tokens) != 2 {
+				return header{}, errors.New("error reading pcd header: invalid DATA")
+			}
+			h.data = tokens[1]
+			return h, h.resolveFields(sizes, types, counts)
+		}
+	}
+}
+
+// resolveFields fills in the size, type, count and offset of every field.
+func (h *header) resolveFields(sizes, types, counts []string) error {
+	if len(sizes) != len(h.fields) || len(types) != len(h.fields) {
+		return errors.New("error reading pcd header: SIZE and TYPE must have one entry per field")
+	}
+	if counts != nil && len(counts) != len(h.fields) {
+		return errors.New("error reading pcd header: COUNT must have one entry per field")
+	}
+	offset := 0
+	for i := range h.fields {
+		size, err := strconv.Atoi(sizes[i])
+		if err != nil || size <= 0 {
+			return errors.Errorf("error reading pcd header: invalid SIZE %v", sizes[i])
+		}
+		h.fields[i].size = size
+		h.fields[i].typ = types[i]
+		if counts != nil {
+			count, err := strconv.Atoi(counts[i])
+			if err != nil || count <= 0 {
+				return errors.Errorf("error reading pcd header: invalid COUNT %v", counts[i])
+			}
+			h.fields[i].count = count
+		}
+		h.fields[i].offset = offset
+		offset += h.fields[i].size * h.fields[i].count
+	}
+	h.pointSize = offset
+	return nil
+}
+
+func readBinary(reader io.Reader, h header, xyz [3]field) ([]r3.Vector, error) {
+	body := make([]byte, h.points*h.pointSize)
+	if _, err := io.ReadFull(reader, body); err != nil {
+		return nil, errors.Wrapf(err, "error reading pcd: expected %d points", h.points)
+	}
+	readFloat := func(point []byte, f field) float64 {
+		if f.size == 8 {
+			return math.Float64frombits(binary.LittleEndian.Uint64(point[f.offset:]))
+		}
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(point[f.offset:])))
+	}
+	points := make([]r3.Vector, h.points)
+	for i := range points {
+		point := body[i*h.pointSize : (i+1)*h.pointSize]
+		points[i] = r3.Vector{X: readFloat(point, xyz[0]), Y: readFloat(point, xyz[1]), Z: readFloat(point, xyz[2])}
+	}
+	return points, nil
+}
+
+func readASCII(reader *bufio.Reader, h header, xyz [3]field) ([]r3.Vector, error) {
+	// In ascii data every value of a field with a count greater than one is its own column.
+	columns := map[string]int{}
+	column := 0
+	for _, f := range h.fields {
+		columns[f.name] = column
+		column += f.count
+	}
+
+	points := make([]r3.Vector, 0, h.points)
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() && len(points) < h.points {
+		values := strings.Fields(scanner.Text())
+		if len(values) == 0 {
+			continue
+		}
+		if len(values) < column {
+			return nil, errors.Errorf("error reading pcd: point %d has %d values, expected %d", len(points), len(values), column)
+		}
+		var coords [3]float64
+		for i, f := range xyz {
+			// Parse with the precision of the field, so that ascii and binary data read the same values.
+			v, err := strconv.ParseFloat(values[columns[f.name]], f.size*8)
+			if err != nil {
+				return nil, errors.Wrapf(err, "error reading pcd: point %d", len(points))
+			}
+			coords[i] = v
+		}
+		points = append(points, r3.Vector{X: coords[0], Y: coords[1], Z: coords[2]})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "error reading pcd")
+	}
+	if len(points) != h.points {
+		return nil, errors.Errorf("error reading pcd: expected %d points, got %d", h.points, len(points))
+	}
+	return points, nil
+}
+
+// Write encodes points as a binary PCD file with x, y and z float32 fields, in the same format as the SLAM
+// algorithm.
+func Write(points []r3.Vector) []byte {
+	var buf bytes.Buffer
+	buf.Grow(len(headerTemplate) + 3*4*len(points))
+	fmt.Fprintf(&buf, headerTemplate, len(points), len(points))
+	var value [4]byte
+	for _, p := range points {
+		for _, v := range []float64{p.X, p.Y, p.Z} {
+			binary.LittleEndian.PutUint32(value[:], math.Float32bits(float32(v)))
+			buf.Write(value[:])
+		}
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,52 @@
+package pcd
+
+import (
+	"testing"
+
+	"github.com/golang/geo/r3"
+	"go.viam.com/test"
+)
+
+func TestReadWrite(t *testing.T) {
+	points := []r3.Vector{{X: 1, Y: 2, Z: 3}, {X: -0.5, Y: 0.25, Z: 1e3}}
+
+	t.Run("Binary round trip", func(t *testing.T) {
+		data := Write(points)
+		test.That(t, string(data[:len("VERSION .7\nFIELDS x y z\n")]), test.ShouldEqual, "VERSION .7\nFIELDS x y z\n")
+		read, err := Read(data)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, read, test.ShouldResemble, points)
+	})
+
+	t.Run("Empty point cloud", func(t *testing.T) {
+		read, err := Read(Write(nil))
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, read, test.ShouldBeEmpty)
+	})
+
+	t.Run("Ascii with extra fields", func(t *testing.T) {
+		data := "# .PCD v0.7\nVERSION .7\nFIELDS rgb x y z\nSIZE 4 4 4 4\nTYPE U F F F\nCOUNT 1 1 1 1\n" +
+			"WIDTH 2\nHEIGHT 1\nPOINTS 2\nDATA ascii\n255 1 2 3\n0 -0.5 0.25 1000\n"
+		read, err := Read([]byte(data))
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, read, test.ShouldResemble, points)
+	})
+
+	t.Run("Truncated binary data", func(t *testing.T) {
+		data := Write(points)
+		_, err := Read(data[:len(data)-1])
+		test.That(t, err.Error(), test.ShouldContainSubstring, "expected 2 points")
+	})
+
+	t.Run("Missing field", func(t *testing.T) {
+		data := "VERSION .7\nFIELDS x y\nSIZE 4 4\nTYPE F F\nCOUNT 1 1\nPOINTS 0\nDATA binary\n"
+		_, err := Read([]byte(data))
+		test.That(t, err, test.ShouldBeError, "error reading pcd: field z missing")
+	})
+
+	t.Run("Unsupported data format", func(t *testing.T) {
+		data := "VERSION .7\nFIELDS x y z\nSIZE 4 4 4\nTYPE F F F\nCOUNT 1 1 1\nPOINTS 0\nDATA binary_compressed\n"
+		_, err := Read([]byte(data))
+		test.That(t, err, test.ShouldBeError, "error reading pcd: unsupported data format binary_compressed")
+	})
+}
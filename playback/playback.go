@@ -0,0 +1,87 @@
+// Package playback schedules the replay of recorded frames at the timing they were captured at.
+package playback
+
+import (
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/viamrobotics/viam-orb-slam3/datadir"
+)
+
+// ErrEnded is returned once a replay that does not loop has shown all of its frames.
+var ErrEnded = errors.New("replay reached the end of the recorded frames")
+
+// Options configures a Player.
+type Options struct {
+	// Speed scales the recorded timing, e.g. 2 replays the frames twice as fast. Defaults to 1.
+	Speed float64
+	// Loop starts the replay over once all frames were shown, instead of ending it.
+	Loop bool
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// Player picks the frame to show at the current time, given the time the replay started at. Every frame is shown for
+// the time until the next frame was captured, and the last one for the mean time between frames. A single frame is
+// shown indefinitely.
+type Player struct {
+	frames []datadir.Frame
+	// offsets are the times the frames were captured at, relative to the first frame.
+	offsets []time.Duration
+	// length is the time it takes to show all frames once at the recorded timing.
+	length time.Duration
+	start  time.Time
+	opts   Options
+}
+
+// New returns a player of the frames, oldest first, that started at start.
+func New(frames []datadir.Frame, start time.Time, opts Options) (*Player, error) {
+	if len(frames) == 0 {
+		return nil, errors.New("there are no frames to replay")
+	}
+	if opts.Speed < 0 {
+		return nil, errors.Errorf("replay speed must not be negative, got %v", opts.Speed)
+	}
+	if opts.Speed == 0 {
+		opts.Speed = 1
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	offsets := make([]time.Duration, len(frames))
+	for i, frame := range frames {
+		offsets[i] = frame.Timestamp.Sub(frames[0].Timestamp)
+	}
+	length := offsets[len(offsets)-1]
+	if len(frames) > 1 {
+		length += length / time.Duration(len(frames)-1)
+	}
+	return &Player{frames: frames, offsets: offsets, length: length, start: start, opts: opts}, nil
+}
+
+// Current returns the frame to show now, along with the number of times the replay has looped. It returns ErrEnded
+// once a replay that does not loop has shown all frames.
+func (p *Player) Current() (datadir.Frame, int, error) {
+	elapsed := time.Duration(float64(p.opts.Now().Sub(p.start)) * p.opts.Speed)
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	var loops int
+	if elapsed >= p.length && p.length > 0 {
+		if !p.opts.Loop {
+			return datadir.Frame{}, 0, ErrEnded
+		}
+		loops = int(elapsed / p.length)
+		elapsed %= p.length
+	}
+	// The frame shown is the last one captured at or before the elapsed time.
+	i := sort.Search(len(p.offsets), func(i int) bool { return p.offsets[i] > elapsed }) - 1
+	return p.frames[i], loops, nil
+}
+
+// Len returns the number of frames replayed.
+func (p *Player) Len() int {
+	return len(p.frames)
+}
@@ -0,0 +1,81 @@
+package playback
+
+import (
+	"testing"
+	"time"
+
+	"go.viam.com/test"
+
+	"github.com/viamrobotics/viam-orb-slam3/datadir"
+)
+
+func TestPlayer(t *testing.T) {
+	start := time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+	frames := []datadir.Frame{
+		{Path: "a", Timestamp: start.Add(-time.Hour)},
+		{Path: "b", Timestamp: start.Add(-time.Hour + 100*time.Millisecond)},
+		{Path: "c", Timestamp: start.Add(-time.Hour + 300*time.Millisecond)},
+	}
+	var now time.Time
+	clock := func() time.Time { return now }
+
+	t.Run("Invalid options", func(t *testing.T) {
+		_, err := New(nil, start, Options{})
+		test.That(t, err, test.ShouldNotBeNil)
+		_, err = New(frames, start, Options{Speed: -1})
+		test.That(t, err.Error(), test.ShouldContainSubstring, "must not be negative")
+	})
+
+	t.Run("Replay at the recorded timing", func(t *testing.T) {
+		player, err := New(frames, start, Options{Now: clock})
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, player.Len(), test.ShouldEqual, 3)
+		for _, tc := range []struct {
+			elapsed time.Duration
+			path    string
+		}{
+			{-time.Second, "a"},
+			{0, "a"},
+			{99 * time.Millisecond, "a"},
+			{100 * time.Millisecond, "b"},
+			{299 * time.Millisecond, "b"},
+			{300 * time.Millisecond, "c"},
+			// The last frame is shown for the mean time between frames.
+			{449 * time.Millisecond, "c"},
+		} {
+			now = start.Add(tc.elapsed)
+			frame, loops, err := player.Current()
+			test.That(t, err, test.ShouldBeNil)
+			test.That(t, frame.Path, test.ShouldEqual, tc.path)
+			test.That(t, loops, test.ShouldEqual, 0)
+		}
+		now = start.Add(450 * time.Millisecond)
+		_, _, err = player.Current()
+		test.That(t, err, test.ShouldBeError, ErrEnded)
+	})
+
+	t.Run("Replay faster and loop", func(t *testing.T) {
+		player, err := New(frames, start, Options{Speed: 2, Loop: true, Now: clock})
+		test.That(t, err, test.ShouldBeNil)
+		now = start.Add(160 * time.Millisecond)
+		frame, loops, err := player.Current()
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, frame.Path, test.ShouldEqual, "c")
+		test.That(t, loops, test.ShouldEqual, 0)
+
+		now = start.Add(450*time.Millisecond + 60*time.Millisecond)
+		frame, loops, err = player.Current()
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, frame.Path, test.ShouldEqual, "b")
+		test.That(t, loops, test.ShouldEqual, 2)
+	})
+
+	t.Run("Replay a single frame", func(t *testing.T) {
+		player, err := New(frames[:1], start, Options{Now: clock})
+		test.That(t, err, test.ShouldBeNil)
+		now = start.Add(time.Hour)
+		frame, _, err := player.Current()
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, frame.Path, test.ShouldEqual, "a")
+	})
+}
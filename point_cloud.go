@@ -0,0 +1,234 @@
+package viamorbslam3
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+
+	"github.com/golang/geo/r3"
+	"github.com/pkg/errors"
+	"go.viam.com/rdk/services/slam/grpchelper"
+
+	orbSlamConfig "github.com/viamrobotics/viam-orb-slam3/config"
+	"github.com/viamrobotics/viam-orb-slam3/frames"
+	"github.com/viamrobotics/viam-orb-slam3/gravity"
+	"github.com/viamrobotics/viam-orb-slam3/pcd"
+)
+
+const (
+	pointCloudChunkSizeBytes = 1024 * 1024
+	// pointCloudCacheTTLSec bounds how long a cached point cloud map is used, since the slam library refines the
+	// points of a map without changing its version.
+	pointCloudCacheTTLSec = 2
+	// processedMapCacheSize is the number of combinations of filters and transforms the processed point cloud
+	// map is cached for.
+	processedMapCacheSize = 8
+)
+
+// pointCloudKey identifies a version of the point cloud map of one run of the SLAM process.
+type pointCloudKey struct {
+	epoch   int64
+	version int64
+}
+
+// processedPointCloudKey identifies a version of the point cloud map along with the transform and filters applied to
+// it.
+type processedPointCloudKey struct {
+	pointCloudKey
+	transform pointTransform
+	// filters is the JSON encoding of the filters, or empty if none are applied.
+	filters string
+}
+
+// pointTransform is the scale, gravity alignment and frame applied to every point of the point cloud map, so that the
+// map matches the poses. It is comparable, to key cached maps by it.
+type pointTransform struct {
+	// mmPerUnit is the scale of the map, or zero if the points are not scaled.
+	mmPerUnit float64
+	aligned   bool
+	rotation  gravity.Rotation
+	// frame is the frame poses are reported in, or nil if they are reported in the camera frame.
+	frame *frames.Transform
+}
+
+// fn returns the function applying the transform to a point, or nil if the points are returned as they are.
+func (t pointTransform) fn() func(r3.Vector) r3.Vector {
+	if t.mmPerUnit == 0 && !t.aligned && t.frame == nil {
+		return nil
+	}
+	return func(p r3.Vector) r3.Vector {
+		if t.mmPerUnit != 0 {
+			p = p.Mul(t.mmPerUnit)
+		}
+		if t.aligned {
+			p = t.rotation.Rotate(p)
+		}
+		if t.frame != nil {
+			p = t.frame.ApplyToPoint(p)
+		}
+		return p
+	}
+}
+
+// pointCloudTransform returns the current transform of the point cloud map, which is applied in the same order as
+// the transforms of poses in GetPosition.
+func (orbSvc *orbslamService) pointCloudTransform() (pointTransform, error) {
+	mmPerUnit, err := orbSvc.outputScale()
+	if err != nil {
+		return pointTransform{}, err
+	}
+	t := pointTransform{mmPerUnit: mmPerUnit}
+	if alignment, ok := orbSvc.gravityAlignment(); ok {
+		t.aligned, t.rotation = true, alignment.Rotation
+	}
+	if !orbSvc.poseTransform.IsIdentity() {
+		t.frame = orbSvc.poseTransform
+	}
+	return t, nil
+}
+
+// requestFilters returns the filters given by point_cloud_filters in the args of a request, or the filters of the
+// point_cloud_filters attribute if the args give none. An empty point_cloud_filters disables filtering.
+func (orbSvc *orbslamService) requestFilters(args map[string]interface{}) (*orbSlamConfig.FiltersConfig, error) {
+	val, ok := args["point_cloud_filters"]
+	if !ok {
+		return orbSvc.filtersConfig, nil
+	}
+	return decodeFilters(val)
+}
+
+// processedPointCloudMapData returns the point cloud map as a PCD file, transformed to match the poses and filtered
+// with filters, if any. If the slam library reports the version of its map, the result is cached for a short time per
+// version, transform and filters, so that the filters are not run again for every request.
+func (orbSvc *orbslamService) processedPointCloudMapData(ctx context.Context, filters *orbSlamConfig.FiltersConfig,
+) ([]byte, error) {
+	transform, err := orbSvc.pointCloudTransform()
+	if err != nil {
+		return nil, err
+	}
+	key := processedPointCloudKey{transform: transform}
+	var filterList []pcd.Filter
+	if filters != nil {
+		filterList = filters.Filters()
+		encoded, err := json.Marshal(filters)
+		if err != nil {
+			return nil, err
+		}
+		key.filters = string(encoded)
+	}
+	process := func(data []byte) ([]byte, error) {
+		if transform.fn() == nil && len(filterList) == 0 {
+			return data, nil
+		}
+		return processPointCloud(data, transform.fn(), filterList)
+	}
+
+	position, err := orbSvc.getSLAMPosition(ctx)
+	if err != nil || position.mapVersion == nil {
+		data, err := orbSvc.requestPointCloudMapData(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return process(data)
+	}
+	key.pointCloudKey = pointCloudKey{epoch: orbSvc.slamEpoch.Load(), version: *position.mapVersion}
+	return orbSvc.processedMapCache.Do(ctx, key, func() ([]byte, error) {
+		sharedCtx, cancel := sharedRequestContext()
+		defer cancel()
+		data, err := orbSvc.cachedPointCloudMapData(sharedCtx, key.version)
+		if err != nil {
+			return nil, err
+		}
+		return process(data)
+	})
+}
+
+// processPointCloud applies transform, if any, to every point of a PCD file and then filters the points.
+func processPointCloud(data []byte, transform func(r3.Vector) r3.Vector, filters []pcd.Filter) ([]byte, error) {
+	points, err := pcd.Read(data)
+	if err != nil {
+		return nil, err
+	}
+	return pcd.Write(processPoints(points, transform, filters)), nil
+}
+
+// processPoints applies transform, if any, to every point and then filters the points.
+func processPoints(points []r3.Vector, transform func(r3.Vector) r3.Vector, filters []pcd.Filter) []r3.Vector {
+	if transform != nil {
+		for i := range points {
+			points[i] = transform(points[i])
+		}
+	}
+	return pcd.ApplyFilters(points, filters...)
+}
+
+// getPointCloudMapData returns the point cloud map of the slam library as a PCD file. If the slam library reports the
+// version of its map, the point cloud map is cached for a short time until the version changes and concurrent requests
+// for the same version share a single request.
+func (orbSvc *orbslamService) getPointCloudMapData(ctx context.Context) ([]byte, error) {
+	position, err := orbSvc.getSLAMPosition(ctx)
+	if err != nil || position.mapVersion == nil {
+		return orbSvc.requestPointCloudMapData(ctx)
+	}
+	return orbSvc.cachedPointCloudMapData(ctx, *position.mapVersion)
+}
+
+// cachedPointCloudMapData returns the point cloud map with the given version, requesting it from the slam library if
+// it is not cached.
+func (orbSvc *orbslamService) cachedPointCloudMapData(ctx context.Context, version int64) ([]byte, error) {
+	key := pointCloudKey{epoch: orbSvc.slamEpoch.Load(), version: version}
+	return orbSvc.pointCloudCache.Do(ctx, key, func() ([]byte, error) {
+		sharedCtx, cancel := sharedRequestContext()
+		defer cancel()
+		return orbSvc.requestPointCloudMapData(sharedCtx)
+	})
+}
+
+// startSLAMEpoch discards the state that depends on the versions of the map reported by the SLAM process, since a
+// newly started process reports versions from zero again.
+func (orbSvc *orbslamService) startSLAMEpoch() {
+	orbSvc.slamEpoch.Add(1)
+	orbSvc.pointCloudCache.Reset()
+	orbSvc.processedMapCache.Reset()
+	orbSvc.mapDeltas.Reset()
+}
+
+// requestPointCloudMapData requests the point cloud map from the slam library.
+func (orbSvc *orbslamService) requestPointCloudMapData(ctx context.Context) ([]byte, error) {
+	callback, err := grpchelper.GetPointCloudMapCallback(ctx, orbSvc.Name().ShortName(), orbSvc.clientAlgo)
+	if err != nil {
+		return nil, err
+	}
+	return readPointCloud(callback)
+}
+
+// readPointCloud reads all chunks of a point cloud callback.
+func readPointCloud(callback func() ([]byte, error)) ([]byte, error) {
+	var data []byte
+	for {
+		chunk, err := callback()
+		if errors.Is(err, io.EOF) {
+			return data, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, chunk...)
+	}
+}
+
+// chunkCallback returns a callback that returns data in chunks, and io.EOF once all of it has been returned.
+func chunkCallback(data []byte) func() ([]byte, error) {
+	return func() ([]byte, error) {
+		if len(data) == 0 {
+			return nil, io.EOF
+		}
+		n := pointCloudChunkSizeBytes
+		if n > len(data) {
+			n = len(data)
+		}
+		chunk := data[:n]
+		data = data[n:]
+		return chunk, nil
+	}
+}
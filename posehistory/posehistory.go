@@ -0,0 +1,162 @@
+// Package posehistory keeps a short history of timestamped poses and extrapolates them forward in time.
+package posehistory
+
+import (
+	"sync"
+	"time"
+
+	"github.com/golang/geo/r3"
+	"github.com/pkg/errors"
+	"go.viam.com/rdk/spatialmath"
+)
+
+// sameFrameTolerance is the largest difference between the frame times of two samples of the same frame. Frame times
+// are estimated from the time a pose is received and its age, so they vary with the latency of the requests.
+const sameFrameTolerance = 5 * time.Millisecond
+
+// Sample is a pose along with the time of the frame it was computed from.
+type Sample struct {
+	Time time.Time
+	Pose spatialmath.Pose
+}
+
+// Velocity is the velocity estimated from the pose history.
+type Velocity struct {
+	// Linear is the linear velocity in mm/s.
+	Linear r3.Vector
+	// Angular is the magnitude of the angular velocity in rad/s.
+	Angular float64
+}
+
+// Extrapolation is a pose extrapolated forward in time from the latest sample.
+type Extrapolation struct {
+	Pose spatialmath.Pose
+	// Latest is the sample the pose was extrapolated from.
+	Latest Sample
+	// Horizon is how far the pose was extrapolated past the latest sample.
+	Horizon time.Duration
+	// Limited is true if the horizon was cut short by the extrapolation limit.
+	Limited bool
+}
+
+// History is a fixed size history of poses. It is safe for concurrent use.
+type History struct {
+	mu      sync.Mutex
+	size    int
+	samples []Sample
+}
+
+// New returns a history that keeps the given number of samples. At least two samples are kept, since that many are
+// needed to estimate velocity.
+func New(size int) *History {
+	if size < 2 {
+		size = 2
+	}
+	return &History{size: size, samples: make([]Sample, 0, size)}
+}
+
+// Add adds a sample with the time of the frame its pose was computed from to the history. Samples older than the
+// latest one are ignored, and so are samples of the same frame as the latest one, which is polled again until the next
+// frame is processed. Those are recognized by their frame time or, if the frame time is only the time the pose was
+// received, by their pose being the same as the latest one.
+func (h *History) Add(t time.Time, pose spatialmath.Pose) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if n := len(h.samples); n > 0 {
+		latest := h.samples[n-1]
+		if t.Before(latest.Time) || t.Sub(latest.Time) <= sameFrameTolerance {
+			return
+		}
+		if spatialmath.PoseAlmostEqual(pose, latest.Pose) {
+			return
+		}
+	}
+	if len(h.samples) == h.size {
+		h.samples = append(h.samples[:0], h.samples[1:]...)
+	}
+	h.samples = append(h.samples, Sample{Time: t, Pose: pose})
+}
+
+// Reset removes all samples, e.g. after tracking was lost and the poses are no longer continuous.
+func (h *History) Reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.samples = h.samples[:0]
+}
+
+// Latest returns the most recent sample, if any.
+func (h *History) Latest() (Sample, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.samples) == 0 {
+		return Sample{}, false
+	}
+	return h.samples[len(h.samples)-1], true
+}
+
+// Velocity estimates the velocity over the whole history. It returns false if there are not enough samples.
+func (h *History) Velocity() (Velocity, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	oldest, latest, ok := h.endpoints()
+	if !ok {
+		return Velocity{}, false
+	}
+	return velocity(oldest, latest), true
+}
+
+// Extrapolate extrapolates the latest pose to the given time assuming constant velocity, extrapolating at most
+// limit past the latest sample. If there are not enough samples to estimate velocity, the latest pose is returned
+// with a zero horizon.
+func (h *History) Extrapolate(now time.Time, limit time.Duration) (Extrapolation, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.samples) == 0 {
+		return Extrapolation{}, errors.New("no poses to extrapolate from")
+	}
+	latest := h.samples[len(h.samples)-1]
+	oldest, _, ok := h.endpoints()
+	if !ok {
+		return Extrapolation{Pose: latest.Pose, Latest: latest}, nil
+	}
+
+	horizon := now.Sub(latest.Time)
+	if horizon < 0 {
+		horizon = 0
+	}
+	var limited bool
+	if horizon > limit {
+		horizon = limit
+		limited = true
+	}
+
+	// Interpolating past the latest sample extrapolates along the motion between the two samples.
+	span := latest.Time.Sub(oldest.Time)
+	by := 1 + float64(horizon)/float64(span)
+	return Extrapolation{
+		Pose:    spatialmath.Interpolate(oldest.Pose, latest.Pose, by),
+		Latest:  latest,
+		Horizon: horizon,
+		Limited: limited,
+	}, nil
+}
+
+// endpoints returns the oldest and latest samples, which are used to estimate velocity. Must be called with the
+// lock held.
+func (h *History) endpoints() (Sample, Sample, bool) {
+	if len(h.samples) < 2 {
+		return Sample{}, Sample{}, false
+	}
+	return h.samples[0], h.samples[len(h.samples)-1], true
+}
+
+func velocity(from, to Sample) Velocity {
+	dt := to.Time.Sub(from.Time).Seconds()
+	delta := spatialmath.PoseBetween(from.Pose, to.Pose)
+	return Velocity{
+		Linear:  to.Pose.Point().Sub(from.Pose.Point()).Mul(1 / dt),
+		Angular: delta.Orientation().AxisAngles().Theta / dt,
+	}
+}
@@ -0,0 +1,109 @@
+package posehistory
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/golang/geo/r3"
+	"go.viam.com/rdk/spatialmath"
+	"go.viam.com/test"
+)
+
+func TestHistory(t *testing.T) {
+	start := time.Date(2023, time.June, 13, 15, 4, 5, 0, time.UTC)
+	poseAt := func(x, thetaDeg float64) spatialmath.Pose {
+		return spatialmath.NewPose(r3.Vector{X: x}, &spatialmath.OrientationVectorDegrees{OZ: 1, Theta: thetaDeg})
+	}
+
+	t.Run("Empty history", func(t *testing.T) {
+		h := New(5)
+		_, ok := h.Latest()
+		test.That(t, ok, test.ShouldBeFalse)
+		_, ok = h.Velocity()
+		test.That(t, ok, test.ShouldBeFalse)
+		_, err := h.Extrapolate(start, time.Second)
+		test.That(t, err, test.ShouldBeError, "no poses to extrapolate from")
+	})
+
+	t.Run("Single sample is returned without extrapolating", func(t *testing.T) {
+		h := New(5)
+		h.Add(start, poseAt(10, 0))
+		extrapolation, err := h.Extrapolate(start.Add(time.Second), time.Second)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, extrapolation.Horizon, test.ShouldEqual, 0)
+		test.That(t, spatialmath.PoseAlmostEqual(extrapolation.Pose, poseAt(10, 0)), test.ShouldBeTrue)
+	})
+
+	t.Run("Constant velocity is extrapolated up to the limit", func(t *testing.T) {
+		h := New(3)
+		// 100 mm/s and 10 deg/s
+		for i := 0; i < 5; i++ {
+			h.Add(start.Add(time.Duration(i)*100*time.Millisecond), poseAt(10*float64(i), float64(i)))
+		}
+		latest, ok := h.Latest()
+		test.That(t, ok, test.ShouldBeTrue)
+		test.That(t, latest.Time, test.ShouldEqual, start.Add(400*time.Millisecond))
+
+		v, ok := h.Velocity()
+		test.That(t, ok, test.ShouldBeTrue)
+		test.That(t, v.Linear.X, test.ShouldAlmostEqual, 100)
+		test.That(t, v.Angular, test.ShouldAlmostEqual, 10*math.Pi/180)
+
+		extrapolation, err := h.Extrapolate(start.Add(600*time.Millisecond), time.Second)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, extrapolation.Horizon, test.ShouldEqual, 200*time.Millisecond)
+		test.That(t, extrapolation.Limited, test.ShouldBeFalse)
+		test.That(t, spatialmath.PoseAlmostEqual(extrapolation.Pose, poseAt(60, 6)), test.ShouldBeTrue)
+
+		extrapolation, err = h.Extrapolate(start.Add(10*time.Second), 300*time.Millisecond)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, extrapolation.Horizon, test.ShouldEqual, 300*time.Millisecond)
+		test.That(t, extrapolation.Limited, test.ShouldBeTrue)
+		test.That(t, spatialmath.PoseAlmostEqual(extrapolation.Pose, poseAt(70, 7)), test.ShouldBeTrue)
+	})
+
+	t.Run("Samples from the same frame or out of order are not added", func(t *testing.T) {
+		h := New(5)
+		h.Add(start, poseAt(0, 0))
+		h.Add(start.Add(time.Second), poseAt(10, 0))
+		h.Add(start.Add(time.Second+time.Millisecond), poseAt(20, 0))
+		h.Add(start.Add(time.Millisecond), poseAt(30, 0))
+		latest, _ := h.Latest()
+		test.That(t, latest.Time, test.ShouldEqual, start.Add(time.Second))
+		test.That(t, spatialmath.PoseAlmostEqual(latest.Pose, poseAt(10, 0)), test.ShouldBeTrue)
+		v, _ := h.Velocity()
+		test.That(t, v.Linear.X, test.ShouldAlmostEqual, 10)
+
+		h.Reset()
+		_, ok := h.Latest()
+		test.That(t, ok, test.ShouldBeFalse)
+	})
+
+	t.Run("Polling faster than the frame rate", func(t *testing.T) {
+		// Frames at 10 Hz of a camera moving at 100 mm/s, polled every 20 ms.
+		const framePeriod = 100 * time.Millisecond
+		const pollPeriod = 20 * time.Millisecond
+		frameAt := func(poll time.Time) (time.Time, spatialmath.Pose) {
+			frame := poll.Sub(start) / framePeriod
+			return start.Add(frame * framePeriod), poseAt(10*float64(frame), 0)
+		}
+
+		withAge, withoutAge := New(10), New(10)
+		for poll := start; poll.Before(start.Add(time.Second)); poll = poll.Add(pollPeriod) {
+			frameTime, pose := frameAt(poll)
+			// The frame time estimated from the pose age is off by the latency of the request.
+			latency := time.Duration(poll.Sub(start)/pollPeriod%3) * time.Millisecond
+			withAge.Add(frameTime.Add(latency), pose)
+			// Without the pose age, the frame time is the time the pose was received.
+			withoutAge.Add(poll, pose)
+		}
+		for _, h := range []*History{withAge, withoutAge} {
+			latest, _ := h.Latest()
+			test.That(t, spatialmath.PoseAlmostEqual(latest.Pose, poseAt(90, 0)), test.ShouldBeTrue)
+			v, ok := h.Velocity()
+			test.That(t, ok, test.ShouldBeTrue)
+			test.That(t, v.Linear.X, test.ShouldAlmostEqual, 100, 1)
+		}
+	})
+}
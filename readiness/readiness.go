@@ -0,0 +1,111 @@
+// Package readiness implements the startup handshake of the SLAM process. Once it is listening, the SLAM process
+// writes a JSON ready file reporting the address it listens on, its version, the modes it supports and whether it has
+// finished loading. The file is replaced atomically whenever the status changes.
+package readiness
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Status is the load status reported by the SLAM process.
+type Status string
+
+const (
+	// StatusLoading means the SLAM process is listening and loading its vocabulary and map.
+	StatusLoading Status = "loading"
+	// StatusReady means the SLAM process has finished loading and is processing data.
+	StatusReady Status = "ready"
+	// StatusFailed means the SLAM process failed to start. The reason is given in Info.Error.
+	StatusFailed Status = "failed"
+)
+
+// Info is the content of the ready file.
+type Info struct {
+	Address string `json:"address"`
+	Version string `json:"version"`
+	// ORBSLAMVersion is the version of the ORB_SLAM3 library, or empty if the SLAM process does not report it.
+	ORBSLAMVersion string   `json:"orbslam_version,omitempty"`
+	Modes          []string `json:"modes"`
+	Status         Status   `json:"status"`
+	MapLoaded      bool     `json:"map_loaded"`
+	Error          string   `json:"error,omitempty"`
+}
+
+// SupportsMode returns whether the SLAM process supports the given mode.
+func (info Info) SupportsMode(mode string) bool {
+	for _, m := range info.Modes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadFile reads and validates the ready file at path.
+func ReadFile(path string) (Info, error) {
+	//nolint:gosec
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Info{}, err
+	}
+	var info Info
+	if err := json.Unmarshal(data, &info); err != nil {
+		return Info{}, errors.Wrapf(err, "invalid ready file %v", path)
+	}
+	switch info.Status {
+	case StatusLoading, StatusReady, StatusFailed:
+	default:
+		return Info{}, errors.Errorf("invalid ready file %v: unknown status %q", path, info.Status)
+	}
+	if info.Status != StatusFailed && info.Address == "" {
+		return Info{}, errors.Errorf("invalid ready file %v: no address given", path)
+	}
+	return info, nil
+}
+
+// Remove removes the ready file at path, if there is one.
+func Remove(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Wait polls the ready file at path every interval until the SLAM process reports it is listening, and returns its
+// content. It returns an error if the SLAM process reports that it failed to start, or if it does not report anything
+// within timeout.
+func Wait(ctx context.Context, path string, timeout, interval time.Duration) (Info, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var lastErr error
+	for {
+		info, err := ReadFile(path)
+		switch {
+		case err == nil && info.Status == StatusFailed:
+			return Info{}, errors.Errorf("slam process failed to start: %v", info.Error)
+		case err == nil:
+			return info, nil
+		case !os.IsNotExist(err):
+			lastErr = err
+		}
+
+		select {
+		case <-ctx.Done():
+			if lastErr != nil {
+				return Info{}, errors.Wrapf(lastErr, "timed out after %v waiting for the slam process to become ready", timeout)
+			}
+			return Info{}, errors.Errorf("timed out after %v waiting for the slam process to write its ready file %v",
+				timeout, path)
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package readiness
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.viam.com/test"
+)
+
+func writeReadyFile(t *testing.T, path, content string) {
+	t.Helper()
+	test.That(t, os.WriteFile(path+".tmp", []byte(content), 0o600), test.ShouldBeNil)
+	test.That(t, os.Rename(path+".tmp", path), test.ShouldBeNil)
+}
+
+func TestReadFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "ready.json")
+
+	t.Run("Valid ready file", func(t *testing.T) {
+		writeReadyFile(t, path,
+			`{"address":"localhost:4000","version":"1.0.0","orbslam_version":"1.0","modes":["mono","rgbd"],`+
+				`"status":"ready","map_loaded":true}`)
+		info, err := ReadFile(path)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, info, test.ShouldResemble, Info{
+			Address:        "localhost:4000",
+			Version:        "1.0.0",
+			ORBSLAMVersion: "1.0",
+			Modes:          []string{"mono", "rgbd"},
+			Status:         StatusReady,
+			MapLoaded:      true,
+		})
+		test.That(t, info.SupportsMode("rgbd"), test.ShouldBeTrue)
+		test.That(t, info.SupportsMode("stereo"), test.ShouldBeFalse)
+	})
+
+	t.Run("Invalid ready files", func(t *testing.T) {
+		writeReadyFile(t, path, `{"address":`)
+		_, err := ReadFile(path)
+		test.That(t, err, test.ShouldNotBeNil)
+		test.That(t, err.Error(), test.ShouldContainSubstring, "invalid ready file")
+
+		writeReadyFile(t, path, `{"address":"localhost:4000","status":"sleeping"}`)
+		_, err = ReadFile(path)
+		test.That(t, err, test.ShouldBeError, errors.Errorf("invalid ready file %v: unknown status \"sleeping\"", path))
+
+		writeReadyFile(t, path, `{"status":"loading"}`)
+		_, err = ReadFile(path)
+		test.That(t, err, test.ShouldBeError, errors.Errorf("invalid ready file %v: no address given", path))
+	})
+
+	t.Run("Remove", func(t *testing.T) {
+		test.That(t, Remove(path), test.ShouldBeNil)
+		_, err := os.Stat(path)
+		test.That(t, os.IsNotExist(err), test.ShouldBeTrue)
+		test.That(t, Remove(path), test.ShouldBeNil)
+	})
+}
+
+func TestWait(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Times out without a ready file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "ready.json")
+		_, err := Wait(ctx, path, 50*time.Millisecond, 10*time.Millisecond)
+		test.That(t, err, test.ShouldBeError, errors.Errorf(
+			"timed out after 50ms waiting for the slam process to write its ready file %v", path))
+	})
+
+	t.Run("Times out with an invalid ready file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "ready.json")
+		writeReadyFile(t, path, `{"status":"loading"}`)
+		_, err := Wait(ctx, path, 50*time.Millisecond, 10*time.Millisecond)
+		test.That(t, err, test.ShouldNotBeNil)
+		test.That(t, err.Error(), test.ShouldContainSubstring, "timed out after 50ms waiting for the slam process to become ready")
+		test.That(t, err.Error(), test.ShouldContainSubstring, "no address given")
+	})
+
+	t.Run("Returns once the ready file is written", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "ready.json")
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			writeReadyFile(t, path, `{"address":"unix:/tmp/orbslam.sock","modes":["mono"],"status":"loading"}`)
+		}()
+		info, err := Wait(ctx, path, 5*time.Second, 10*time.Millisecond)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, info.Address, test.ShouldEqual, "unix:/tmp/orbslam.sock")
+		test.That(t, info.Status, test.ShouldEqual, StatusLoading)
+	})
+
+	t.Run("Reports a failed start", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "ready.json")
+		writeReadyFile(t, path, `{"status":"failed","error":"No vocabulary file found"}`)
+		_, err := Wait(ctx, path, 5*time.Second, 10*time.Millisecond)
+		test.That(t, err, test.ShouldBeError, errors.New("slam process failed to start: No vocabulary file found"))
+	})
+}
@@ -0,0 +1,189 @@
+package viamorbslam3
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/edaniels/golog"
+	"github.com/pkg/errors"
+	"go.opencensus.io/trace"
+	"go.viam.com/rdk/resource"
+	"go.viam.com/rdk/services/slam"
+
+	"github.com/viamrobotics/viam-orb-slam3/atomicfile"
+	orbSlamConfig "github.com/viamrobotics/viam-orb-slam3/config"
+	"github.com/viamrobotics/viam-orb-slam3/dataprocess"
+	"github.com/viamrobotics/viam-orb-slam3/internalstate"
+	"github.com/viamrobotics/viam-orb-slam3/mapmeta"
+	"github.com/viamrobotics/viam-orb-slam3/slamlog"
+)
+
+const (
+	defaultReplayName = "replay"
+
+	replayPoseFileName          = "pose.json"
+	replayPointCloudFileName    = "pointcloud.pcd"
+	replayInternalStateFileName = "internal_state" + mapmeta.MapExtension
+)
+
+// ReplayOptions configures Replay.
+type ReplayOptions struct {
+	// Name is the name of the SLAM service. Defaults to "replay".
+	Name string
+	// ExecutableName is the SLAM process to run. Defaults to DefaultExecutableName.
+	ExecutableName string
+	// OutputDirectory is the folder the results are written to. Defaults to a new folder in the exports folder of the
+	// data directory.
+	OutputDirectory string
+	// Timeout is the time the SLAM process is given to process all data, or zero to wait until ctx is done.
+	Timeout time.Duration
+}
+
+// ReplayResult holds the paths of the results of a replay.
+type ReplayResult struct {
+	PosePath            string `json:"pose_path"`
+	PointCloudPath      string `json:"point_cloud_path"`
+	InternalStatePath   string `json:"internal_state_path"`
+	InternalStateSHA256 string `json:"internal_state_sha256"`
+	Duration            string `json:"duration"`
+}
+
+// Replay runs the SLAM service without a robot, in offline mode, against the data recorded in the data directory of
+// the config. Once the SLAM process has processed all data, the final pose, point cloud map and internal state are
+// written to the output directory, so that runs with different parameters can be compared.
+func Replay(ctx context.Context, svcConfig *orbSlamConfig.Config, opts ReplayOptions, logger golog.Logger) (ReplayResult, error) {
+	ctx, span := trace.StartSpan(ctx, "viamorbslam3::Replay")
+	defer span.End()
+
+	if len(svcConfig.Sensors) > 0 || (svcConfig.UseLiveData != nil && *svcConfig.UseLiveData) {
+		return ReplayResult{}, errors.New("replay runs in offline mode, sensors must be empty and use_live_data false")
+	}
+	if svcConfig.SLAMServer != nil {
+		return ReplayResult{}, errors.New("replay starts its own slam process, slam_server must not be set")
+	}
+	if _, err := svcConfig.Validate("attributes"); err != nil {
+		return ReplayResult{}, err
+	}
+	if opts.Name == "" {
+		opts.Name = defaultReplayName
+	}
+	if opts.ExecutableName == "" {
+		opts.ExecutableName = DefaultExecutableName
+	}
+	if opts.OutputDirectory == "" {
+		opts.OutputDirectory = filepath.Join(svcConfig.DataDirectory, exportsDirectoryName,
+			"replay_"+time.Now().UTC().Format(dataprocess.SlamTimeFormat))
+	}
+	if err := os.MkdirAll(opts.OutputDirectory, os.ModePerm); err != nil {
+		return ReplayResult{}, errors.Wrap(err, "unable to create replay output directory")
+	}
+
+	start := time.Now()
+	c := resource.Config{Name: opts.Name, API: slam.API, Model: Model, ConvertedAttributes: svcConfig}
+	svc, err := New(ctx, resource.Dependencies{}, c, logger, false, opts.ExecutableName)
+	if err != nil {
+		return ReplayResult{}, err
+	}
+	orbSvc, ok := svc.(*orbslamService)
+	if !ok {
+		return ReplayResult{}, errors.Errorf("unexpected slam service type %T", svc)
+	}
+	defer func() {
+		if err := orbSvc.Close(context.Background()); err != nil {
+			logger.Errorw("error closing slam service after replay", "error", err)
+		}
+	}()
+
+	if err := orbSvc.waitForOfflineDone(ctx, opts.Timeout); err != nil {
+		return ReplayResult{}, err
+	}
+	logger.Infow("slam process finished processing the recorded data and saved the final map", "duration", time.Since(start))
+	result, err := orbSvc.writeReplayResults(ctx, opts.OutputDirectory)
+	if err != nil {
+		return ReplayResult{}, err
+	}
+	result.Duration = time.Since(start).String()
+	return result, nil
+}
+
+// handleOfflineDone records that the SLAM process has processed all data in offline mode and, if it saves maps,
+// saved the final map. Its internal state may change until the final map is saved.
+func (orbSvc *orbslamService) handleOfflineDone(event slamlog.Event) {
+	switch event.Type {
+	case slamlog.FinalMapSaved:
+	case slamlog.OfflineDone:
+		if orbSvc.mapRateSec > 0 {
+			return
+		}
+	default:
+		return
+	}
+	orbSvc.offlineDoneOnce.Do(func() { close(orbSvc.offlineDone) })
+}
+
+// waitForOfflineDone waits until the SLAM process has processed all data in offline mode and saved the final map,
+// for at most timeout unless it is zero.
+func (orbSvc *orbslamService) waitForOfflineDone(ctx context.Context, timeout time.Duration) error {
+	if timeout > 0 {
+		var cancel func()
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	select {
+	case <-orbSvc.offlineDone:
+		return nil
+	case <-ctx.Done():
+		return errors.Wrap(ctx.Err(), "slam process did not finish processing the recorded data")
+	}
+}
+
+// writeReplayResults writes the current pose, point cloud map and internal state to the output directory.
+func (orbSvc *orbslamService) writeReplayResults(ctx context.Context, outputDirectory string) (ReplayResult, error) {
+	result := ReplayResult{
+		PosePath:          filepath.Join(outputDirectory, replayPoseFileName),
+		PointCloudPath:    filepath.Join(outputDirectory, replayPointCloudFileName),
+		InternalStatePath: filepath.Join(outputDirectory, replayInternalStateFileName),
+	}
+
+	pose, componentReference, err := orbSvc.GetPosition(ctx)
+	if err != nil {
+		return ReplayResult{}, errors.Wrap(err, "error getting final pose")
+	}
+	poseData, err := json.MarshalIndent(map[string]interface{}{
+		"component_reference": componentReference,
+		"pose":                poseToMap(pose),
+	}, "", "  ")
+	if err != nil {
+		return ReplayResult{}, err
+	}
+	if err := atomicfile.WriteFile(result.PosePath, poseData, 0o644); err != nil {
+		return ReplayResult{}, errors.Wrap(err, "unable to write final pose")
+	}
+
+	callback, err := orbSvc.GetPointCloudMap(ctx)
+	if err != nil {
+		return ReplayResult{}, errors.Wrap(err, "error getting point cloud map")
+	}
+	pointCloud, err := readPointCloud(callback)
+	if err != nil {
+		return ReplayResult{}, errors.Wrap(err, "error getting point cloud map")
+	}
+	if err := atomicfile.WriteFile(result.PointCloudPath, pointCloud, 0o644); err != nil {
+		return ReplayResult{}, errors.Wrap(err, "unable to write point cloud map")
+	}
+
+	// A download left behind by a previous replay to the same folder is of a different internal state.
+	if err := os.Remove(result.InternalStatePath); err != nil && !os.IsNotExist(err) {
+		return ReplayResult{}, errors.Wrap(err, "unable to remove previous internal state")
+	}
+	internalState, err := internalstate.Download(ctx, orbSvc.clientAlgo, orbSvc.Name().ShortName(),
+		result.InternalStatePath, internalstate.Options{})
+	if err != nil {
+		return ReplayResult{}, errors.Wrap(err, "error downloading internal state")
+	}
+	result.InternalStateSHA256 = internalState.SHA256
+	return result, nil
+}
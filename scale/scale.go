@@ -0,0 +1,164 @@
+// Package scale estimates the metric scale of a monocular SLAM map from odometry.
+package scale
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/viamrobotics/viam-orb-slam3/atomicfile"
+)
+
+const (
+	// minSamples is the number of accepted displacements needed before a scale is estimated.
+	minSamples = 5
+	// outlierThreshold is the number of scaled median absolute deviations a ratio may be from the median before it
+	// is rejected as an outlier.
+	outlierThreshold = 3.
+	// madToStdDev scales the median absolute deviation to the standard deviation of normally distributed data.
+	madToStdDev = 1.4826
+)
+
+// Estimator estimates the ratio of odometry displacement to SLAM displacement. It is safe for concurrent use.
+type Estimator struct {
+	mu         sync.Mutex
+	maxSamples int
+	ratios     []float64
+	// lastSampleAt is the time the latest ratio was added.
+	lastSampleAt time.Time
+	// estimate caches the estimate made from ratios, or is nil until it is made again after they change.
+	estimate *Estimate
+	// initial is the estimate used until enough samples are accepted, e.g. one loaded from disk.
+	initial *Estimate
+}
+
+// Estimate is an estimated scale.
+type Estimate struct {
+	// Scale is the number of meters per SLAM unit.
+	Scale float64 `json:"scale"`
+	// Samples is the number of displacements used in the estimate, after outlier rejection.
+	Samples int `json:"samples"`
+	// UpdatedAt is the time the latest displacement used in the estimate was added.
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// MillimetersPerUnit returns the number of mm per SLAM unit, since poses and point clouds are in mm.
+func (estimate Estimate) MillimetersPerUnit() float64 {
+	return estimate.Scale * 1000
+}
+
+// NewEstimator returns an estimator that keeps at most maxSamples of the most recent displacements.
+func NewEstimator(maxSamples int) *Estimator {
+	if maxSamples < minSamples {
+		maxSamples = minSamples
+	}
+	return &Estimator{maxSamples: maxSamples}
+}
+
+// SetInitial sets the estimate returned until enough displacements have been added to estimate the scale.
+func (e *Estimator) SetInitial(estimate Estimate) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.initial = &estimate
+}
+
+// Reset removes all displacements and the initial estimate, e.g. when a new map is started.
+func (e *Estimator) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.ratios = nil
+	e.estimate = nil
+	e.initial = nil
+}
+
+// AddDisplacements adds a pair of displacements of the same motion, measured in meters by odometry and in SLAM
+// units by the SLAM algorithm. Pairs with displacements that are not positive and finite are ignored, and false is
+// returned.
+func (e *Estimator) AddDisplacements(odometry, slam float64) bool {
+	if !(odometry > 0) || !(slam > 0) || math.IsInf(odometry, 0) || math.IsInf(slam, 0) {
+		return false
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.ratios) == e.maxSamples {
+		e.ratios = append(e.ratios[:0], e.ratios[1:]...)
+	}
+	e.ratios = append(e.ratios, odometry/slam)
+	e.lastSampleAt = time.Now()
+	e.estimate = nil
+	return true
+}
+
+// Estimate returns the current estimate. Ratios further than a few median absolute deviations from the median ratio
+// are rejected as outliers, e.g. from wheel slip or a SLAM relocalization, and the median of the remaining ratios is
+// the scale. The estimate is only made again once displacements are added. False is returned if there are not enough
+// displacements and no initial estimate.
+func (e *Estimator) Estimate() (Estimate, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.ratios) < minSamples {
+		if e.initial == nil {
+			return Estimate{}, false
+		}
+		return *e.initial, true
+	}
+	if e.estimate != nil {
+		return *e.estimate, true
+	}
+
+	m := median(e.ratios)
+	deviations := make([]float64, len(e.ratios))
+	for i, r := range e.ratios {
+		deviations[i] = math.Abs(r - m)
+	}
+	mad := median(deviations) * madToStdDev
+
+	inliers := make([]float64, 0, len(e.ratios))
+	for _, r := range e.ratios {
+		if math.Abs(r-m) <= outlierThreshold*mad {
+			inliers = append(inliers, r)
+		}
+	}
+	e.estimate = &Estimate{Scale: median(inliers), Samples: len(inliers), UpdatedAt: e.lastSampleAt}
+	return *e.estimate, true
+}
+
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+// WriteFile saves the estimate to a file, replacing it atomically.
+func WriteFile(path string, estimate Estimate) error {
+	data, err := json.Marshal(estimate)
+	if err != nil {
+		return err
+	}
+	return errors.Wrap(atomicfile.WriteFile(path, data, 0o644), "error writing scale file")
+}
+
+// ReadFile loads an estimate saved with WriteFile.
+func ReadFile(path string) (Estimate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Estimate{}, err
+	}
+	var estimate Estimate
+	if err := json.Unmarshal(data, &estimate); err != nil {
+		return Estimate{}, errors.Wrapf(err, "error parsing scale file %v", path)
+	}
+	if estimate.Scale <= 0 {
+		return Estimate{}, errors.Errorf("invalid scale %v in %v", estimate.Scale, path)
+	}
+	return estimate, nil
+}
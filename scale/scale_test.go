@@ -0,0 +1,113 @@
+package scale
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.viam.com/test"
+)
+
+func TestEstimator(t *testing.T) {
+	t.Run("Not enough samples", func(t *testing.T) {
+		e := NewEstimator(10)
+		for i := 0; i < minSamples-1; i++ {
+			test.That(t, e.AddDisplacements(1, 2), test.ShouldBeTrue)
+		}
+		_, ok := e.Estimate()
+		test.That(t, ok, test.ShouldBeFalse)
+
+		e.SetInitial(Estimate{Scale: 3, Samples: 7})
+		estimate, ok := e.Estimate()
+		test.That(t, ok, test.ShouldBeTrue)
+		test.That(t, estimate.Scale, test.ShouldEqual, 3)
+	})
+
+	t.Run("Reset", func(t *testing.T) {
+		e := NewEstimator(10)
+		e.SetInitial(Estimate{Scale: 3, Samples: 7})
+		for i := 0; i < minSamples; i++ {
+			test.That(t, e.AddDisplacements(1, 2), test.ShouldBeTrue)
+		}
+		e.Reset()
+		_, ok := e.Estimate()
+		test.That(t, ok, test.ShouldBeFalse)
+	})
+
+	t.Run("Invalid displacements are ignored", func(t *testing.T) {
+		e := NewEstimator(10)
+		test.That(t, e.AddDisplacements(0, 1), test.ShouldBeFalse)
+		test.That(t, e.AddDisplacements(1, 0), test.ShouldBeFalse)
+		test.That(t, e.AddDisplacements(-1, 1), test.ShouldBeFalse)
+	})
+
+	t.Run("Outliers are rejected", func(t *testing.T) {
+		e := NewEstimator(10)
+		for _, odometry := range []float64{1.9, 2, 2.1, 2, 1.95, 2.05, 40, 0.01} {
+			e.AddDisplacements(odometry, 1)
+		}
+		estimate, ok := e.Estimate()
+		test.That(t, ok, test.ShouldBeTrue)
+		test.That(t, estimate.Scale, test.ShouldAlmostEqual, 2)
+		test.That(t, estimate.Samples, test.ShouldEqual, 6)
+	})
+
+	t.Run("Only the most recent samples are kept", func(t *testing.T) {
+		e := NewEstimator(minSamples)
+		for i := 0; i < minSamples; i++ {
+			e.AddDisplacements(1, 1)
+		}
+		for i := 0; i < minSamples; i++ {
+			e.AddDisplacements(4, 2)
+		}
+		estimate, _ := e.Estimate()
+		test.That(t, estimate.Scale, test.ShouldEqual, 2)
+		test.That(t, estimate.Samples, test.ShouldEqual, minSamples)
+	})
+
+	t.Run("Estimate is of the latest sample", func(t *testing.T) {
+		e := NewEstimator(10)
+		before := time.Now()
+		for i := 0; i < minSamples; i++ {
+			e.AddDisplacements(2, 1)
+		}
+		after := time.Now()
+		estimate, ok := e.Estimate()
+		test.That(t, ok, test.ShouldBeTrue)
+		test.That(t, estimate.UpdatedAt.Before(before), test.ShouldBeFalse)
+		test.That(t, estimate.UpdatedAt.After(after), test.ShouldBeFalse)
+
+		time.Sleep(time.Millisecond)
+		again, _ := e.Estimate()
+		test.That(t, again, test.ShouldResemble, estimate)
+
+		e.AddDisplacements(2, 1)
+		updated, _ := e.Estimate()
+		test.That(t, updated.UpdatedAt.After(estimate.UpdatedAt), test.ShouldBeTrue)
+		test.That(t, updated.Samples, test.ShouldEqual, minSamples+1)
+	})
+
+	t.Run("Scale in mm", func(t *testing.T) {
+		test.That(t, Estimate{Scale: 0.25}.MillimetersPerUnit(), test.ShouldEqual, 250)
+	})
+}
+
+func TestFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "scale.json")
+
+	t.Run("Round trip", func(t *testing.T) {
+		test.That(t, WriteFile(path, Estimate{Scale: 1.5, Samples: 9}), test.ShouldBeNil)
+		estimate, err := ReadFile(path)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, estimate.Scale, test.ShouldEqual, 1.5)
+		test.That(t, estimate.Samples, test.ShouldEqual, 9)
+	})
+
+	t.Run("Invalid scale", func(t *testing.T) {
+		test.That(t, os.WriteFile(path, []byte(`{"scale": 0}`), 0o600), test.ShouldBeNil)
+		_, err := ReadFile(path)
+		test.That(t, err.Error(), test.ShouldContainSubstring, "invalid scale")
+	})
+}
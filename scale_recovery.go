@@ -0,0 +1,189 @@
+package viamorbslam3
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/golang/geo/r3"
+	geo "github.com/kellydunn/golang-geo"
+	"github.com/pkg/errors"
+	"go.viam.com/rdk/components/movementsensor"
+	"go.viam.com/rdk/resource"
+	"go.viam.com/rdk/spatialmath"
+	goutils "go.viam.com/utils"
+
+	orbSlamConfig "github.com/viamrobotics/viam-orb-slam3/config"
+	"github.com/viamrobotics/viam-orb-slam3/scale"
+	orbSlamUtils "github.com/viamrobotics/viam-orb-slam3/utils"
+)
+
+const (
+	scaleSampleIntervalMsec       = 500
+	minOdometryDisplacementMeters = 0.5
+	scaleMaxSamples               = 100
+	scaleFilename                 = "scale.json"
+	// rgbdMillimetersPerUnit is the scale of RGBD maps, which are in meters.
+	rgbdMillimetersPerUnit = 1000
+)
+
+// configureOdometry returns the movement sensor used to recover the scale of monocular maps, if one is configured.
+func configureOdometry(
+	svcConfig *orbSlamConfig.Config,
+	subAlgo SubAlgo,
+	deps resource.Dependencies,
+) (movementsensor.MovementSensor, error) {
+	if svcConfig.OdometrySensor == "" {
+		return nil, nil
+	}
+	if subAlgo != Mono {
+		return nil, errors.Errorf("odometry_sensor is only supported in %v mode, since %v maps are metric", Mono, subAlgo)
+	}
+	odometry, err := movementsensor.FromDependencies(deps, svcConfig.OdometrySensor)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error getting movement sensor %v for slam service", svcConfig.OdometrySensor)
+	}
+	return odometry, nil
+}
+
+// scalePath is the path of the scale estimate saved along with the maps.
+func (orbSvc *orbslamService) scalePath() string {
+	return filepath.Join(orbSvc.dataDirectory, "map", scaleFilename)
+}
+
+// loadScale loads the scale estimated for the maps in the map directory, if one was saved and odometry is configured to
+// keep estimating it. A saved scale without a map to go with it is removed, since a new map will have a different scale.
+func (orbSvc *orbslamService) loadScale() error {
+	if orbSvc.odometry == nil {
+		return nil
+	}
+	estimate, err := scale.ReadFile(orbSvc.scalePath())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	hasMap, err := orbSvc.hasMap()
+	if err != nil {
+		return errors.Wrap(err, "error checking for maps while loading scale")
+	}
+	if !hasMap {
+		orbSvc.logger.Infof("removing %v, since no map exists for it", orbSvc.scalePath())
+		return os.Remove(orbSvc.scalePath())
+	}
+
+	orbSvc.logger.Infof("using saved map scale of %v meters per SLAM unit", estimate.Scale)
+	orbSvc.scaleEstimator.SetInitial(estimate)
+	return nil
+}
+
+// errScaleUnknown is returned for poses and maps of monocular maps with odometry until they can be reported in mm.
+var errScaleUnknown = errors.New("the scale of the map is not known yet, so poses and maps cannot be reported in mm; " +
+	"move the robot for odometry_sensor to estimate it")
+
+// millimetersPerUnit returns the number of mm per SLAM unit, or false if the scale of the map is not known. RGBD maps
+// are in meters, while monocular maps are in arbitrary units until their scale is estimated.
+func (orbSvc *orbslamService) millimetersPerUnit() (float64, bool) {
+	if orbSvc.subAlgo == Rgbd {
+		return rgbdMillimetersPerUnit, true
+	}
+	estimate, ok := orbSvc.scaleEstimator.Estimate()
+	if !ok {
+		return 0, false
+	}
+	return estimate.MillimetersPerUnit(), true
+}
+
+// outputScale returns the number of mm per SLAM unit poses and maps are reported with, or zero if they are reported in
+// SLAM units. The units are fixed by the config, so that distances given in them, such as those of point cloud
+// filters, keep their meaning: RGBD maps and monocular maps with odometry_sensor are reported in mm, and monocular maps
+// without it in SLAM units. Monocular maps with odometry are not reported until their scale is estimated.
+func (orbSvc *orbslamService) outputScale() (float64, error) {
+	if orbSvc.subAlgo == Mono && orbSvc.odometry == nil {
+		return 0, nil
+	}
+	mmPerUnit, ok := orbSvc.millimetersPerUnit()
+	if !ok {
+		return 0, errScaleUnknown
+	}
+	return mmPerUnit, nil
+}
+
+// scalePose scales the translation of a pose by mmPerUnit, unless it is zero.
+func scalePose(pose spatialmath.Pose, mmPerUnit float64) spatialmath.Pose {
+	if mmPerUnit == 0 {
+		return pose
+	}
+	return spatialmath.NewPose(pose.Point().Mul(mmPerUnit), pose.Orientation())
+}
+
+// scaleAnchor is a position measured by both odometry and the SLAM algorithm, that later positions are compared
+// against.
+type scaleAnchor struct {
+	odometry *geo.Point
+	altitude float64
+	slam     r3.Vector
+}
+
+// startScaleRecovery starts the background loop that compares the displacement measured by odometry with the one
+// measured by the SLAM algorithm, to estimate the scale of the map.
+func (orbSvc *orbslamService) startScaleRecovery(cancelCtx context.Context) {
+	if orbSvc.odometry == nil {
+		return
+	}
+
+	orbSvc.activeBackgroundWorkers.Add(1)
+	goutils.PanicCapturingGo(func() {
+		defer orbSvc.activeBackgroundWorkers.Done()
+		var anchor *scaleAnchor
+		for {
+			if !goutils.SelectContextOrWait(cancelCtx, scaleSampleIntervalMsec*time.Millisecond) {
+				return
+			}
+			anchor = orbSvc.sampleScale(cancelCtx, anchor)
+		}
+	})
+}
+
+// sampleScale measures the current position and, once it is far enough from the anchor, adds the displacements
+// since the anchor to the scale estimator. It returns the anchor to compare the next position against.
+func (orbSvc *orbslamService) sampleScale(ctx context.Context, anchor *scaleAnchor) *scaleAnchor {
+	point, altitude, err := orbSvc.odometry.Position(ctx, nil)
+	if err != nil {
+		orbSvc.logger.Debugw("error getting odometry position for scale recovery", "error", err)
+		return anchor
+	}
+	position, err := orbSvc.getSLAMPosition(ctx)
+	if err != nil {
+		orbSvc.logger.Debugw("error getting SLAM position for scale recovery", "error", err)
+		return anchor
+	}
+	state := position.trackingInfo.State
+	if state != orbSlamUtils.TrackingStateOK && state != orbSlamUtils.TrackingStateUnknown {
+		// The SLAM pose is not updated while tracking is lost, so restart from the next tracked position.
+		return nil
+	}
+
+	current := &scaleAnchor{odometry: point, altitude: altitude, slam: position.pose.Point()}
+	if anchor == nil {
+		return current
+	}
+	odometryDisplacement := math.Hypot(anchor.odometry.GreatCircleDistance(current.odometry)*1000,
+		current.altitude-anchor.altitude)
+	if odometryDisplacement < minOdometryDisplacementMeters {
+		return anchor
+	}
+
+	if orbSvc.scaleEstimator.AddDisplacements(odometryDisplacement, current.slam.Sub(anchor.slam).Norm()) {
+		if estimate, ok := orbSvc.scaleEstimator.Estimate(); ok {
+			if err := scale.WriteFile(orbSvc.scalePath(), estimate); err != nil {
+				orbSvc.logger.Warnw("error saving map scale", "error", err)
+			}
+		}
+	}
+	return current
+}
@@ -0,0 +1,183 @@
+// Package scene renders color and depth frames of a simple textured 3D scene from a camera moving along a scripted
+// trajectory, along with the exact pose of every frame, to test SLAM without hardware. The scene is ray cast on the
+// CPU. The world frame has z up, and distances are in meters.
+package scene
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/golang/geo/r3"
+	"github.com/pkg/errors"
+)
+
+// maxDepthMM is the largest depth a depth frame can hold, in mm.
+const maxDepthMM = math.MaxUint16
+
+// Box is an axis aligned box.
+type Box struct {
+	Min r3.Vector `json:"min"`
+	Max r3.Vector `json:"max"`
+}
+
+// Scene is a room with boxes in it. All surfaces are covered in a checkerboard of random colors, which gives the SLAM
+// algorithm corners to track.
+type Scene struct {
+	// Room is the room the camera moves in, which is seen from the inside.
+	Room Box
+	// Boxes are the boxes within the room, which are seen from the outside.
+	Boxes []Box
+	// CellSize is the size of the squares of the checkerboard.
+	CellSize float64
+}
+
+// DefaultScene returns a 6m by 6m room with a few boxes in it.
+func DefaultScene() Scene {
+	return Scene{
+		Room: Box{Min: r3.Vector{X: -3, Y: -3, Z: 0}, Max: r3.Vector{X: 3, Y: 3, Z: 2.5}},
+		Boxes: []Box{
+			{Min: r3.Vector{X: -0.4, Y: -0.4, Z: 0}, Max: r3.Vector{X: 0.4, Y: 0.4, Z: 0.8}},
+			{Min: r3.Vector{X: 1.8, Y: -2.5, Z: 0}, Max: r3.Vector{X: 2.6, Y: -1.5, Z: 1.2}},
+			{Min: r3.Vector{X: -2.6, Y: 1.6, Z: 0}, Max: r3.Vector{X: -1.4, Y: 2.4, Z: 0.6}},
+			{Min: r3.Vector{X: 1.2, Y: 2.2, Z: 0.9}, Max: r3.Vector{X: 2.2, Y: 2.9, Z: 1.5}},
+		},
+		CellSize: 0.2,
+	}
+}
+
+// Intrinsics are the pinhole intrinsics of the camera, which has no distortion.
+type Intrinsics struct {
+	Width  int
+	Height int
+	Fx     float64
+	Fy     float64
+	Ppx    float64
+	Ppy    float64
+}
+
+// Render returns the color frame and the depth frame in mm seen by a camera with the intrinsics at the pose. Depth is
+// measured along the optical axis, and is zero where nothing is hit.
+func (s Scene) Render(intrinsics Intrinsics, pose Pose) (*image.NRGBA, *image.Gray16, error) {
+	if intrinsics.Width <= 0 || intrinsics.Height <= 0 || intrinsics.Fx <= 0 || intrinsics.Fy <= 0 {
+		return nil, nil, errors.Errorf("invalid intrinsics %+v", intrinsics)
+	}
+	if s.CellSize <= 0 {
+		return nil, nil, errors.New("scene cell size must be greater than zero")
+	}
+	bounds := image.Rect(0, 0, intrinsics.Width, intrinsics.Height)
+	colorImg := image.NewNRGBA(bounds)
+	depthImg := image.NewGray16(bounds)
+	rotation := pose.rotation()
+	for v := 0; v < intrinsics.Height; v++ {
+		for u := 0; u < intrinsics.Width; u++ {
+			// The direction has a unit z component in the camera frame, so the distance along it is the depth.
+			dir := rotation.apply(r3.Vector{
+				X: (float64(u) - intrinsics.Ppx) / intrinsics.Fx,
+				Y: (float64(v) - intrinsics.Ppy) / intrinsics.Fy,
+				Z: 1,
+			})
+			depth, c, ok := s.cast(pose.Position, dir)
+			if !ok {
+				continue
+			}
+			colorImg.SetNRGBA(u, v, c)
+			depthImg.SetGray16(u, v, color.Gray16{Y: uint16(math.Min(math.Round(depth*1000), maxDepthMM))})
+		}
+	}
+	return colorImg, depthImg, nil
+}
+
+// cast returns the distance along dir to the nearest surface hit by the ray from origin, in multiples of dir, along
+// with the color of the surface there.
+func (s Scene) cast(origin, dir r3.Vector) (float64, color.NRGBA, bool) {
+	bestT, bestAxis, bestFace := math.Inf(1), 0, 0
+	// Inside the room, the ray hits the wall it exits through.
+	if _, tExit, _, exitAxis, ok := intersect(s.Room, origin, dir); ok && tExit > 0 {
+		bestT, bestAxis, bestFace = tExit, exitAxis, 0
+	}
+	for i, box := range s.Boxes {
+		if tEnter, _, enterAxis, _, ok := intersect(box, origin, dir); ok && tEnter > 0 && tEnter < bestT {
+			bestT, bestAxis, bestFace = tEnter, enterAxis, i+1
+		}
+	}
+	if math.IsInf(bestT, 1) {
+		return 0, color.NRGBA{}, false
+	}
+	// Each face of a box has its own texture, so faces are told apart by the direction the ray crosses them in.
+	face := bestFace*6 + bestAxis*2
+	if component(dir, bestAxis) > 0 {
+		face++
+	}
+	return bestT, s.texture(origin.Add(dir.Mul(bestT)), bestAxis, face), true
+}
+
+// intersect returns the distances along dir at which the ray from origin enters and exits the box, along with the axes
+// of the faces it crosses there.
+func intersect(box Box, origin, dir r3.Vector) (float64, float64, int, int, bool) {
+	tEnter, tExit := math.Inf(-1), math.Inf(1)
+	var enterAxis, exitAxis int
+	for axis := 0; axis < 3; axis++ {
+		o, d := component(origin, axis), component(dir, axis)
+		lo, hi := component(box.Min, axis), component(box.Max, axis)
+		if d == 0 {
+			if o < lo || o > hi {
+				return 0, 0, 0, 0, false
+			}
+			continue
+		}
+		t1, t2 := (lo-o)/d, (hi-o)/d
+		if t1 > t2 {
+			t1, t2 = t2, t1
+		}
+		if t1 > tEnter {
+			tEnter, enterAxis = t1, axis
+		}
+		if t2 < tExit {
+			tExit, exitAxis = t2, axis
+		}
+	}
+	if tEnter > tExit {
+		return 0, 0, 0, 0, false
+	}
+	return tEnter, tExit, enterAxis, exitAxis, true
+}
+
+// texture returns the color of the given face, whose normal is along axis, at point p.
+func (s Scene) texture(p r3.Vector, axis, face int) color.NRGBA {
+	a, b := component(p, (axis+1)%3), component(p, (axis+2)%3)
+	h := hash(int(math.Floor(a/s.CellSize)), int(math.Floor(b/s.CellSize)), face)
+	// Faces are shaded by their axis, so that edges between faces of the same colors remain visible.
+	shade := [3]uint32{255, 215, 175}[axis]
+	return color.NRGBA{
+		R: uint8((h & 0xff) * shade / 255),
+		G: uint8((h >> 8 & 0xff) * shade / 255),
+		B: uint8((h >> 16 & 0xff) * shade / 255),
+		A: 255,
+	}
+}
+
+// component returns the component of v along axis, 0 for x, 1 for y and 2 for z.
+func component(v r3.Vector, axis int) float64 {
+	switch axis {
+	case 0:
+		return v.X
+	case 1:
+		return v.Y
+	default:
+		return v.Z
+	}
+}
+
+// hash mixes integers into a pseudo random number, so that textures do not depend on a random seed.
+func hash(values ...int) uint32 {
+	h := uint32(2166136261)
+	for _, v := range values {
+		h ^= uint32(v)
+		h *= 16777619
+		h ^= h >> 15
+	}
+	h *= 2246822519
+	h ^= h >> 13
+	return h
+}
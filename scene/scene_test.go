@@ -0,0 +1,58 @@
+package scene
+
+import (
+	"math"
+	"testing"
+
+	"github.com/golang/geo/r3"
+	"go.viam.com/test"
+)
+
+func TestRender(t *testing.T) {
+	s := DefaultScene()
+	intrinsics := Intrinsics{Width: 64, Height: 48, Fx: 50, Fy: 50, Ppx: 32, Ppy: 24}
+	// Facing the box at the center of the room, whose near face is at y = -0.4.
+	pose := Pose{Position: r3.Vector{X: 0, Y: -2, Z: 0.4}, Yaw: math.Pi / 2}
+
+	t.Run("Invalid parameters", func(t *testing.T) {
+		_, _, err := s.Render(Intrinsics{Width: 64}, pose)
+		test.That(t, err, test.ShouldNotBeNil)
+		_, _, err = Scene{Room: s.Room}.Render(intrinsics, pose)
+		test.That(t, err, test.ShouldNotBeNil)
+	})
+
+	t.Run("Render color and depth", func(t *testing.T) {
+		colorImg, depthImg, err := s.Render(intrinsics, pose)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, colorImg.Bounds().Dx(), test.ShouldEqual, 64)
+		test.That(t, depthImg.Bounds().Dy(), test.ShouldEqual, 48)
+		test.That(t, depthImg.Gray16At(32, 24).Y, test.ShouldEqual, 1600)
+		// The top of the frame sees the ceiling over the box, at z = 2.5.
+		test.That(t, depthImg.Gray16At(32, 0).Y, test.ShouldEqual, 4375)
+		// The bottom of the frame sees the floor in front of the box.
+		test.That(t, depthImg.Gray16At(32, 47).Y, test.ShouldEqual, 870)
+		for v := 0; v < 48; v++ {
+			for u := 0; u < 64; u++ {
+				test.That(t, colorImg.NRGBAAt(u, v).A, test.ShouldEqual, 255)
+			}
+		}
+
+		colorImg2, depthImg2, err := s.Render(intrinsics, pose)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, colorImg2.Pix, test.ShouldResemble, colorImg.Pix)
+		test.That(t, depthImg2.Pix, test.ShouldResemble, depthImg.Pix)
+	})
+
+	t.Run("Render a textured surface", func(t *testing.T) {
+		colorImg, _, err := s.Render(intrinsics, pose)
+		test.That(t, err, test.ShouldBeNil)
+		colors := map[[3]uint8]bool{}
+		for v := 0; v < 48; v++ {
+			for u := 0; u < 64; u++ {
+				c := colorImg.NRGBAAt(u, v)
+				colors[[3]uint8{c.R, c.G, c.B}] = true
+			}
+		}
+		test.That(t, len(colors), test.ShouldBeGreaterThan, 20)
+	})
+}
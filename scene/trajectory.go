@@ -0,0 +1,174 @@
+package scene
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/golang/geo/r3"
+	"github.com/pkg/errors"
+)
+
+// TUMHeader describes the columns of a trajectory in the TUM RGB-D format, which WriteTUM writes.
+const TUMHeader = "# timestamp tx ty tz qx qy qz qw\n"
+
+// Pose is the pose of the camera in the world frame. The camera looks along its z axis, with its x axis to the right
+// and its y axis down.
+type Pose struct {
+	Position r3.Vector
+	// Yaw is the heading of the camera counterclockwise from the x axis, and Pitch its angle above the horizon, in
+	// radians.
+	Yaw   float64
+	Pitch float64
+}
+
+// rotation is a rotation matrix, by columns.
+type rotation [3]r3.Vector
+
+// apply rotates v.
+func (r rotation) apply(v r3.Vector) r3.Vector {
+	return r[0].Mul(v.X).Add(r[1].Mul(v.Y)).Add(r[2].Mul(v.Z))
+}
+
+// rotation returns the rotation from the camera frame to the world frame.
+func (p Pose) rotation() rotation {
+	forward := r3.Vector{
+		X: math.Cos(p.Yaw) * math.Cos(p.Pitch),
+		Y: math.Sin(p.Yaw) * math.Cos(p.Pitch),
+		Z: math.Sin(p.Pitch),
+	}
+	right := r3.Vector{X: math.Sin(p.Yaw), Y: -math.Cos(p.Yaw)}
+	down := forward.Cross(right)
+	return rotation{right, down, forward}
+}
+
+// Quaternion returns the rotation from the camera frame to the world frame as a unit quaternion with a non negative w.
+func (p Pose) Quaternion() (x, y, z, w float64) {
+	x, y, z, w = p.quaternion()
+	if w < 0 {
+		return -x, -y, -z, -w
+	}
+	return x, y, z, w
+}
+
+// quaternion returns the rotation from the camera frame to the world frame as a unit quaternion of either sign.
+func (p Pose) quaternion() (x, y, z, w float64) {
+	r := p.rotation()
+	// m[row][col]
+	m := [3][3]float64{
+		{r[0].X, r[1].X, r[2].X},
+		{r[0].Y, r[1].Y, r[2].Y},
+		{r[0].Z, r[1].Z, r[2].Z},
+	}
+	switch trace := m[0][0] + m[1][1] + m[2][2]; {
+	case trace > 0:
+		s := 2 * math.Sqrt(trace+1)
+		return (m[2][1] - m[1][2]) / s, (m[0][2] - m[2][0]) / s, (m[1][0] - m[0][1]) / s, s / 4
+	case m[0][0] > m[1][1] && m[0][0] > m[2][2]:
+		s := 2 * math.Sqrt(1+m[0][0]-m[1][1]-m[2][2])
+		return s / 4, (m[0][1] + m[1][0]) / s, (m[0][2] + m[2][0]) / s, (m[2][1] - m[1][2]) / s
+	case m[1][1] > m[2][2]:
+		s := 2 * math.Sqrt(1+m[1][1]-m[0][0]-m[2][2])
+		return (m[0][1] + m[1][0]) / s, s / 4, (m[1][2] + m[2][1]) / s, (m[0][2] - m[2][0]) / s
+	default:
+		s := 2 * math.Sqrt(1+m[2][2]-m[0][0]-m[1][1])
+		return (m[0][2] + m[2][0]) / s, (m[1][2] + m[2][1]) / s, s / 4, (m[1][0] - m[0][1]) / s
+	}
+}
+
+// Waypoint is a pose the camera passes through, with its angles in degrees.
+type Waypoint struct {
+	Position r3.Vector `json:"position"`
+	YawDeg   float64   `json:"yaw_deg"`
+	PitchDeg float64   `json:"pitch_deg"`
+}
+
+// CircleWaypoints returns n waypoints on a horizontal circle around center, facing the center.
+func CircleWaypoints(center r3.Vector, radius float64, n int) []Waypoint {
+	waypoints := make([]Waypoint, n)
+	for i := range waypoints {
+		angle := 2 * math.Pi * float64(i) / float64(n)
+		waypoints[i] = Waypoint{
+			Position: center.Add(r3.Vector{X: radius * math.Cos(angle), Y: radius * math.Sin(angle)}),
+			YawDeg:   angle*180/math.Pi + 180,
+		}
+	}
+	return waypoints
+}
+
+// Trajectory moves the camera through its waypoints at a constant speed and back to the first one, in a loop. The
+// orientation is interpolated between waypoints along the shortest turn.
+type Trajectory struct {
+	waypoints []Waypoint
+	// distances are the distances along the trajectory at which the waypoints are passed, followed by its length.
+	distances []float64
+	speed     float64
+}
+
+// NewTrajectory returns a trajectory through the waypoints at speed in m/s.
+func NewTrajectory(waypoints []Waypoint, speed float64) (*Trajectory, error) {
+	if len(waypoints) < 2 {
+		return nil, errors.Errorf("a trajectory needs at least 2 waypoints, got %d", len(waypoints))
+	}
+	if speed <= 0 {
+		return nil, errors.Errorf("trajectory speed must be greater than zero, got %v", speed)
+	}
+	distances := make([]float64, len(waypoints)+1)
+	for i := range waypoints {
+		next := waypoints[(i+1)%len(waypoints)]
+		distances[i+1] = distances[i] + next.Position.Sub(waypoints[i].Position).Norm()
+	}
+	if distances[len(waypoints)] == 0 {
+		return nil, errors.New("the waypoints of a trajectory must not all be at the same position")
+	}
+	return &Trajectory{waypoints: waypoints, distances: distances, speed: speed}, nil
+}
+
+// Period returns the time it takes to go through the trajectory once.
+func (t *Trajectory) Period() time.Duration {
+	return time.Duration(t.distances[len(t.waypoints)] / t.speed * float64(time.Second))
+}
+
+// Pose returns the pose of the camera after moving along the trajectory for elapsed.
+func (t *Trajectory) Pose(elapsed time.Duration) Pose {
+	length := t.distances[len(t.waypoints)]
+	distance := math.Mod(elapsed.Seconds()*t.speed, length)
+	if distance < 0 {
+		distance += length
+	}
+	i := 0
+	for i < len(t.waypoints)-1 && t.distances[i+1] <= distance {
+		i++
+	}
+	from, to := t.waypoints[i], t.waypoints[(i+1)%len(t.waypoints)]
+	var fraction float64
+	if segment := t.distances[i+1] - t.distances[i]; segment > 0 {
+		fraction = (distance - t.distances[i]) / segment
+	}
+	return Pose{
+		Position: from.Position.Add(to.Position.Sub(from.Position).Mul(fraction)),
+		Yaw:      interpolateAngle(from.YawDeg, to.YawDeg, fraction) * math.Pi / 180,
+		Pitch:    interpolateAngle(from.PitchDeg, to.PitchDeg, fraction) * math.Pi / 180,
+	}
+}
+
+// interpolateAngle interpolates between two angles in degrees along the shortest turn.
+func interpolateAngle(from, to, fraction float64) float64 {
+	delta := math.Mod(to-from, 360)
+	switch {
+	case delta > 180:
+		delta -= 360
+	case delta < -180:
+		delta += 360
+	}
+	return from + delta*fraction
+}
+
+// WriteTUM writes the pose at time t as a line of a trajectory in the TUM RGB-D format.
+func WriteTUM(w io.Writer, t time.Time, pose Pose) error {
+	qx, qy, qz, qw := pose.Quaternion()
+	_, err := fmt.Fprintf(w, "%.6f %.6f %.6f %.6f %.9f %.9f %.9f %.9f\n",
+		float64(t.UnixNano())/float64(time.Second), pose.Position.X, pose.Position.Y, pose.Position.Z, qx, qy, qz, qw)
+	return err
+}
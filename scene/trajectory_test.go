@@ -0,0 +1,100 @@
+package scene
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/golang/geo/r3"
+	"go.viam.com/test"
+)
+
+// rotateByQuaternion rotates v by the unit quaternion (x, y, z, w).
+func rotateByQuaternion(v r3.Vector, x, y, z, w float64) r3.Vector {
+	q := r3.Vector{X: x, Y: y, Z: z}
+	t := q.Cross(v).Mul(2)
+	return v.Add(t.Mul(w)).Add(q.Cross(t))
+}
+
+func TestPose(t *testing.T) {
+	t.Run("Camera axes", func(t *testing.T) {
+		r := Pose{}.rotation()
+		test.That(t, r.apply(r3.Vector{Z: 1}), test.ShouldResemble, r3.Vector{X: 1})
+		test.That(t, r.apply(r3.Vector{X: 1}).ApproxEqual(r3.Vector{Y: -1}), test.ShouldBeTrue)
+		test.That(t, r.apply(r3.Vector{Y: 1}).ApproxEqual(r3.Vector{Z: -1}), test.ShouldBeTrue)
+	})
+
+	t.Run("Quaternion matches the rotation", func(t *testing.T) {
+		for _, pose := range []Pose{
+			{},
+			{Yaw: math.Pi / 2},
+			{Yaw: math.Pi, Pitch: 0.3},
+			{Yaw: -2.5, Pitch: -0.7},
+			{Yaw: 1, Pitch: 1.2},
+		} {
+			x, y, z, w := pose.Quaternion()
+			test.That(t, x*x+y*y+z*z+w*w, test.ShouldAlmostEqual, 1)
+			r := pose.rotation()
+			for _, v := range []r3.Vector{{X: 1}, {Y: 1}, {Z: 1}, {X: 0.3, Y: -2, Z: 1}} {
+				test.That(t, rotateByQuaternion(v, x, y, z, w).Sub(r.apply(v)).Norm(), test.ShouldBeLessThan, 1e-9)
+			}
+		}
+	})
+}
+
+func TestTrajectory(t *testing.T) {
+	t.Run("Invalid trajectories", func(t *testing.T) {
+		_, err := NewTrajectory([]Waypoint{{}}, 1)
+		test.That(t, err, test.ShouldNotBeNil)
+		_, err = NewTrajectory([]Waypoint{{}, {Position: r3.Vector{X: 1}}}, 0)
+		test.That(t, err, test.ShouldNotBeNil)
+		_, err = NewTrajectory([]Waypoint{{}, {YawDeg: 90}}, 1)
+		test.That(t, err, test.ShouldNotBeNil)
+	})
+
+	t.Run("Move through the waypoints in a loop", func(t *testing.T) {
+		trajectory, err := NewTrajectory([]Waypoint{
+			{YawDeg: 350},
+			{Position: r3.Vector{X: 2}, YawDeg: 10, PitchDeg: 20},
+		}, 0.5)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, trajectory.Period(), test.ShouldEqual, 8*time.Second)
+
+		pose := trajectory.Pose(0)
+		test.That(t, pose.Position, test.ShouldResemble, r3.Vector{})
+		test.That(t, pose.Yaw, test.ShouldAlmostEqual, 350*math.Pi/180)
+
+		// Halfway to the second waypoint, turning through 0 degrees.
+		pose = trajectory.Pose(2 * time.Second)
+		test.That(t, pose.Position.X, test.ShouldAlmostEqual, 1)
+		test.That(t, pose.Yaw, test.ShouldAlmostEqual, 2*math.Pi)
+		test.That(t, pose.Pitch, test.ShouldAlmostEqual, 10*math.Pi/180)
+
+		// On the way back to the first waypoint.
+		pose = trajectory.Pose(7 * time.Second)
+		test.That(t, pose.Position.X, test.ShouldAlmostEqual, 0.5)
+
+		pose = trajectory.Pose(10 * time.Second)
+		test.That(t, pose.Position.X, test.ShouldAlmostEqual, 1)
+	})
+
+	t.Run("Face the center of a circle", func(t *testing.T) {
+		center := r3.Vector{X: 1, Y: 1, Z: 1}
+		waypoints := CircleWaypoints(center, 2, 8)
+		test.That(t, waypoints, test.ShouldHaveLength, 8)
+		for _, waypoint := range waypoints {
+			test.That(t, waypoint.Position.Sub(center).Norm(), test.ShouldAlmostEqual, 2)
+			pose := Pose{Position: waypoint.Position, Yaw: waypoint.YawDeg * math.Pi / 180}
+			forward := pose.rotation().apply(r3.Vector{Z: 1})
+			test.That(t, forward.Dot(center.Sub(waypoint.Position).Normalize()), test.ShouldAlmostEqual, 1)
+		}
+	})
+}
+
+func TestWriteTUM(t *testing.T) {
+	var buf bytes.Buffer
+	test.That(t, WriteTUM(&buf, time.Unix(1680000000, 500000000), Pose{Position: r3.Vector{X: 1, Y: 2, Z: 3}}), test.ShouldBeNil)
+	test.That(t, buf.String(), test.ShouldEqual,
+		"1680000000.500000 1.000000 2.000000 3.000000 -0.500000000 0.500000000 -0.500000000 0.500000000\n")
+}
@@ -0,0 +1,309 @@
+// Package replay implements a camera that replays the frames recorded in the data directory of a SLAM service at the
+// timing they were captured at, so that the SLAM service can run on live data without hardware.
+package replay
+
+import (
+	"context"
+	"image"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/edaniels/golog"
+	"github.com/pkg/errors"
+	"go.viam.com/rdk/components/camera"
+	"go.viam.com/rdk/resource"
+	"go.viam.com/rdk/rimage"
+	"go.viam.com/rdk/rimage/transform"
+	rdkutils "go.viam.com/rdk/utils"
+	"go.viam.com/utils"
+	"gopkg.in/yaml.v2"
+
+	"github.com/viamrobotics/viam-orb-slam3/datadir"
+	"github.com/viamrobotics/viam-orb-slam3/playback"
+)
+
+// Model is the model of the replay camera.
+var Model = resource.NewModel("viam", "camera", "orbslamv3-replay")
+
+// Streams of the data directory the camera replays.
+const (
+	ColorStream = "color"
+	DepthStream = "depth"
+)
+
+// yamlPrefix starts the ORB_SLAM3 configs generated by the SLAM service, and is not part of the YAML document.
+const yamlPrefix = "%YAML:1.0\n"
+
+func init() {
+	resource.RegisterComponent(camera.API, Model, resource.Registration[camera.Camera, *Config]{
+		Constructor: newCamera,
+	})
+}
+
+// Config describes how to configure the replay camera. The intrinsics and distortion of the camera default to those
+// of the newest ORB_SLAM3 config in the data directory, which the SLAM service generated from the recording camera.
+type Config struct {
+	DataDirectory string `json:"data_dir"`
+	// Stream is the stream replayed, ColorStream for data/rgb or DepthStream for data/depth. Defaults to ColorStream.
+	Stream string `json:"stream"`
+	// Sensor replays only the frames recorded from the camera of that name, if set.
+	Sensor string `json:"sensor"`
+	// Loop starts the replay over at the end, instead of failing to return frames.
+	Loop bool `json:"loop"`
+	// Speed scales the recorded timing, e.g. 2 replays twice as fast. Defaults to 1.
+	Speed            float64                            `json:"speed"`
+	IntrinsicParams  *transform.PinholeCameraIntrinsics `json:"intrinsic_parameters"`
+	DistortionParams *transform.BrownConrady            `json:"distortion_parameters"`
+}
+
+// Validate checks that the config is complete and in range.
+func (config *Config) Validate(path string) ([]string, error) {
+	if config.DataDirectory == "" {
+		return nil, utils.NewConfigValidationFieldRequiredError(path, "data_dir")
+	}
+	if config.Stream != "" && config.Stream != ColorStream && config.Stream != DepthStream {
+		return nil, errors.Errorf("stream must be %v or %v, got %v", ColorStream, DepthStream, config.Stream)
+	}
+	if config.Speed < 0 {
+		return nil, errors.New("cannot specify speed less than zero")
+	}
+	if config.IntrinsicParams != nil {
+		if err := config.IntrinsicParams.CheckValid(); err != nil {
+			return nil, err
+		}
+	}
+	if config.DistortionParams != nil {
+		if err := config.DistortionParams.CheckValid(); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// newCamera returns a replay camera. The frames it replays are listed once, so frames recorded afterwards are not
+// replayed. The data directory must not be the one of a SLAM service that deletes processed data.
+func newCamera(ctx context.Context, _ resource.Dependencies, conf resource.Config, logger golog.Logger) (camera.Camera, error) {
+	cfg, err := resource.NativeConfig[*Config](conf)
+	if err != nil {
+		return nil, err
+	}
+
+	folder, imageType := datadir.RGBDirectory, camera.ColorStream
+	if cfg.Stream == DepthStream {
+		folder, imageType = datadir.DepthDirectory, camera.DepthStream
+	}
+	frames, err := datadir.ListFrames(cfg.DataDirectory, folder)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to list frames in %v", filepath.Join(cfg.DataDirectory, folder))
+	}
+	if cfg.Sensor != "" {
+		sensorFrames := frames[:0]
+		for _, frame := range frames {
+			if frame.Sensor == cfg.Sensor {
+				sensorFrames = append(sensorFrames, frame)
+			}
+		}
+		frames = sensorFrames
+	}
+
+	cameraModel, err := cfg.cameraModel()
+	if err != nil {
+		return nil, err
+	}
+
+	start, releaseSession := startSession(cfg.sessionKey(), conf.ResourceName().String())
+	player, err := playback.New(frames, start, playback.Options{Speed: cfg.Speed, Loop: cfg.Loop})
+	if err != nil {
+		releaseSession()
+		return nil, errors.Wrapf(err, "unable to replay %v", filepath.Join(cfg.DataDirectory, folder))
+	}
+	logger.Infow("replaying recorded frames", "data_dir", cfg.DataDirectory, "stream", folder,
+		"frames", player.Len(), "loop", cfg.Loop)
+
+	reader := &frameReader{player: player, releaseSession: releaseSession, logger: logger}
+	src, err := camera.NewVideoSourceFromReader(ctx, reader, cameraModel, imageType)
+	if err != nil {
+		releaseSession()
+		return nil, err
+	}
+	return camera.FromVideoSource(conf.ResourceName(), src), nil
+}
+
+// cameraModel returns the intrinsics and distortion of the camera, read from the data directory unless configured.
+func (config *Config) cameraModel() (*transform.PinholeCameraModel, error) {
+	intrinsics, distortion := config.IntrinsicParams, config.DistortionParams
+	if intrinsics == nil {
+		path, err := datadir.LatestConfig(config.DataDirectory)
+		if err != nil {
+			return nil, err
+		}
+		if path == "" {
+			return nil, errors.Errorf("no intrinsic_parameters given and no ORB_SLAM3 config found in %v",
+				filepath.Join(config.DataDirectory, datadir.ConfigDirectory))
+		}
+		orbIntrinsics, orbDistortion, err := readORBCamera(path)
+		if err != nil {
+			return nil, err
+		}
+		intrinsics = orbIntrinsics
+		if distortion == nil {
+			distortion = orbDistortion
+		}
+	}
+	if distortion == nil {
+		distortion = &transform.BrownConrady{}
+	}
+	return &transform.PinholeCameraModel{PinholeCameraIntrinsics: intrinsics, Distortion: distortion}, nil
+}
+
+// orbCamera are the camera settings of an ORB_SLAM3 config.
+type orbCamera struct {
+	Width        int     `yaml:"Camera.width"`
+	Height       int     `yaml:"Camera.height"`
+	Fx           float64 `yaml:"Camera1.fx"`
+	Fy           float64 `yaml:"Camera1.fy"`
+	Ppx          float64 `yaml:"Camera1.cx"`
+	Ppy          float64 `yaml:"Camera1.cy"`
+	RadialK1     float64 `yaml:"Camera1.k1"`
+	RadialK2     float64 `yaml:"Camera1.k2"`
+	RadialK3     float64 `yaml:"Camera1.k3"`
+	TangentialP1 float64 `yaml:"Camera1.p1"`
+	TangentialP2 float64 `yaml:"Camera1.p2"`
+}
+
+// readORBCamera reads the intrinsics and distortion of the camera from the ORB_SLAM3 config at path.
+func readORBCamera(path string) (*transform.PinholeCameraIntrinsics, *transform.BrownConrady, error) {
+	//nolint:gosec
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "unable to read ORB_SLAM3 config")
+	}
+	var cam orbCamera
+	if err := yaml.Unmarshal([]byte(strings.TrimPrefix(string(data), yamlPrefix)), &cam); err != nil {
+		return nil, nil, errors.Wrapf(err, "invalid ORB_SLAM3 config %v", path)
+	}
+	intrinsics := &transform.PinholeCameraIntrinsics{
+		Width:  cam.Width,
+		Height: cam.Height,
+		Fx:     cam.Fx,
+		Fy:     cam.Fy,
+		Ppx:    cam.Ppx,
+		Ppy:    cam.Ppy,
+	}
+	if err := intrinsics.CheckValid(); err != nil {
+		return nil, nil, errors.Wrapf(err, "invalid intrinsics in ORB_SLAM3 config %v", path)
+	}
+	distortion := &transform.BrownConrady{
+		RadialK1:     cam.RadialK1,
+		RadialK2:     cam.RadialK2,
+		RadialK3:     cam.RadialK3,
+		TangentialP1: cam.TangentialP1,
+		TangentialP2: cam.TangentialP2,
+	}
+	return intrinsics, distortion, nil
+}
+
+// frameReader reads the frame the player shows at the time of the read.
+type frameReader struct {
+	player         *playback.Player
+	releaseSession func()
+	closeOnce      sync.Once
+	logger         golog.Logger
+
+	mu    sync.Mutex
+	path  string
+	data  []byte
+	loops int
+}
+
+// Read returns the current frame as the PNG it was recorded as.
+func (r *frameReader) Read(ctx context.Context) (image.Image, func(), error) {
+	frame, loops, err := r.player.Current()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if loops != r.loops {
+		r.loops = loops
+		r.logger.Debugw("replay started over", "loops", loops)
+	}
+	if frame.Path != r.path {
+		//nolint:gosec
+		data, err := os.ReadFile(frame.Path)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "unable to read recorded frame")
+		}
+		r.path, r.data = frame.Path, data
+	}
+	return rimage.NewLazyEncodedImage(r.data, rdkutils.MimeTypePNG), nil, nil
+}
+
+// Close ends the replay.
+func (r *frameReader) Close(ctx context.Context) error {
+	r.closeOnce.Do(r.releaseSession)
+	return nil
+}
+
+// sessionKey identifies the replays that cameras share, of the same data directory with the same timing.
+type sessionKey struct {
+	dataDirectory string
+	speed         float64
+	loop          bool
+}
+
+// sessionKey returns the key of the session the camera replays in.
+func (config *Config) sessionKey() sessionKey {
+	key := sessionKey{dataDirectory: filepath.Clean(config.DataDirectory), speed: config.Speed, loop: config.Loop}
+	if abs, err := filepath.Abs(key.dataDirectory); err == nil {
+		key.dataDirectory = abs
+	}
+	if key.speed == 0 {
+		key.speed = 1
+	}
+	return key
+}
+
+// session is a replay of a data directory, which the cameras replaying it with the same timing share.
+type session struct {
+	start time.Time
+	// cameras counts the cameras replaying in the session by name.
+	cameras map[string]int
+}
+
+var (
+	sessionsMu sync.Mutex
+	// sessions are the replays in progress. The cameras replaying the same data directory with the same timing start
+	// at the same time, so that the color and depth frames they return at any time were recorded together.
+	sessions = map[sessionKey]*session{}
+)
+
+// startSession returns the time the replay of the session started at, starting it now if no camera replays in it. A
+// camera that already replays in the session is being reconfigured, since the new camera is built before the old one
+// is closed, so it starts a new session that replays from the beginning, which cameras started later join. The
+// returned function must be called once the camera stops replaying.
+func startSession(key sessionKey, name string) (time.Time, func()) {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	s, ok := sessions[key]
+	if !ok || s.cameras[name] > 0 {
+		s = &session{start: time.Now(), cameras: map[string]int{}}
+		sessions[key] = s
+	}
+	s.cameras[name]++
+	return s.start, func() {
+		sessionsMu.Lock()
+		defer sessionsMu.Unlock()
+		s.cameras[name]--
+		if s.cameras[name] == 0 {
+			delete(s.cameras, name)
+		}
+		if len(s.cameras) == 0 && sessions[key] == s {
+			delete(sessions, key)
+		}
+	}
+}
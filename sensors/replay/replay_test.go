@@ -0,0 +1,197 @@
+package replay
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/edaniels/golog"
+	"go.viam.com/rdk/components/camera"
+	"go.viam.com/rdk/resource"
+	"go.viam.com/rdk/rimage"
+	"go.viam.com/rdk/rimage/transform"
+	"go.viam.com/test"
+
+	"github.com/viamrobotics/viam-orb-slam3/datadir"
+	"github.com/viamrobotics/viam-orb-slam3/dataprocess"
+)
+
+const orbConfig = yamlPrefix + `File.version: "1.0"
+Camera.type: PinHole
+Camera.width: 1280
+Camera.height: 720
+Camera1.fx: 900.5
+Camera1.fy: 900.8
+Camera1.cx: 648.9
+Camera1.cy: 367.7
+Camera1.k1: 0.158
+Camera1.k2: -0.485
+Camera1.k3: 0.435
+Camera1.p1: -0.001
+Camera1.p2: -0.0007
+Camera.fps: 5
+`
+
+// createDataDirectory returns a data directory with an ORB_SLAM3 config and the given number of color and depth
+// frames, recorded 100ms apart.
+func createDataDirectory(t *testing.T, frames int) string {
+	t.Helper()
+	dir := t.TempDir()
+	start := time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+	for _, folder := range []string{datadir.ConfigDirectory, datadir.RGBDirectory, datadir.DepthDirectory} {
+		test.That(t, os.MkdirAll(filepath.Join(dir, folder), 0o755), test.ShouldBeNil)
+	}
+	configPath := dataprocess.CreateTimestampFilename(filepath.Join(dir, datadir.ConfigDirectory), "cam", ".yaml", start)
+	test.That(t, os.WriteFile(configPath, []byte(orbConfig), 0o644), test.ShouldBeNil)
+	for i := 0; i < frames; i++ {
+		timestamp := start.Add(time.Duration(i) * 100 * time.Millisecond)
+		for _, folder := range []string{datadir.RGBDirectory, datadir.DepthDirectory} {
+			path := dataprocess.CreateTimestampFilename(filepath.Join(dir, folder), "cam", ".png", timestamp)
+			test.That(t, os.WriteFile(path, []byte(folder+filepath.Base(path)), 0o644), test.ShouldBeNil)
+		}
+	}
+	return dir
+}
+
+func TestValidate(t *testing.T) {
+	cfg := &Config{}
+	_, err := cfg.Validate("path")
+	test.That(t, err, test.ShouldNotBeNil)
+
+	cfg.DataDirectory = "data"
+	deps, err := cfg.Validate("path")
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, deps, test.ShouldBeEmpty)
+
+	cfg.Stream = "ir"
+	_, err = cfg.Validate("path")
+	test.That(t, err.Error(), test.ShouldContainSubstring, "stream must be color or depth")
+
+	cfg.Stream = DepthStream
+	cfg.Speed = -1
+	_, err = cfg.Validate("path")
+	test.That(t, err.Error(), test.ShouldContainSubstring, "speed less than zero")
+
+	cfg.Speed = 2
+	cfg.IntrinsicParams = &transform.PinholeCameraIntrinsics{Width: 0, Height: 720}
+	_, err = cfg.Validate("path")
+	test.That(t, err, test.ShouldNotBeNil)
+}
+
+func TestCameraModel(t *testing.T) {
+	t.Run("Camera model from the data directory", func(t *testing.T) {
+		cfg := &Config{DataDirectory: createDataDirectory(t, 1)}
+		model, err := cfg.cameraModel()
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, model.PinholeCameraIntrinsics, test.ShouldResemble, &transform.PinholeCameraIntrinsics{
+			Width: 1280, Height: 720, Fx: 900.5, Fy: 900.8, Ppx: 648.9, Ppy: 367.7,
+		})
+		test.That(t, model.Distortion, test.ShouldResemble, &transform.BrownConrady{
+			RadialK1: 0.158, RadialK2: -0.485, RadialK3: 0.435, TangentialP1: -0.001, TangentialP2: -0.0007,
+		})
+	})
+
+	t.Run("Configured camera model", func(t *testing.T) {
+		intrinsics := &transform.PinholeCameraIntrinsics{Width: 640, Height: 480, Fx: 500, Fy: 500, Ppx: 320, Ppy: 240}
+		cfg := &Config{DataDirectory: t.TempDir(), IntrinsicParams: intrinsics}
+		model, err := cfg.cameraModel()
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, model.PinholeCameraIntrinsics, test.ShouldEqual, intrinsics)
+		test.That(t, model.Distortion, test.ShouldResemble, &transform.BrownConrady{})
+	})
+
+	t.Run("Camera model without intrinsics", func(t *testing.T) {
+		cfg := &Config{DataDirectory: t.TempDir()}
+		_, err := cfg.cameraModel()
+		test.That(t, err.Error(), test.ShouldContainSubstring, "no intrinsic_parameters given")
+	})
+}
+
+func TestReplayCamera(t *testing.T) {
+	ctx := context.Background()
+	logger := golog.NewTestLogger(t)
+	dir := createDataDirectory(t, 3)
+
+	newReplayCamera := func(name string, cfg *Config) (camera.Camera, error) {
+		return newCamera(ctx, nil, resource.Config{
+			Name:                name,
+			API:                 camera.API,
+			Model:               Model,
+			ConvertedAttributes: cfg,
+		}, logger)
+	}
+
+	t.Run("Replay color and depth frames together", func(t *testing.T) {
+		colorCam, err := newReplayCamera("color", &Config{DataDirectory: dir, Loop: true})
+		test.That(t, err, test.ShouldBeNil)
+		depthCam, err := newReplayCamera("depth", &Config{DataDirectory: dir, Stream: DepthStream, Loop: true})
+		test.That(t, err, test.ShouldBeNil)
+
+		props, err := colorCam.Properties(ctx)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, props.IntrinsicParams.Width, test.ShouldEqual, 1280)
+		_, ok := props.DistortionParams.(*transform.BrownConrady)
+		test.That(t, ok, test.ShouldBeTrue)
+
+		colorImg, _, err := camera.ReadImage(ctx, colorCam)
+		test.That(t, err, test.ShouldBeNil)
+		depthImg, _, err := camera.ReadImage(ctx, depthCam)
+		test.That(t, err, test.ShouldBeNil)
+		colorData := colorImg.(*rimage.LazyEncodedImage).RawData()
+		depthData := depthImg.(*rimage.LazyEncodedImage).RawData()
+		test.That(t, string(colorData), test.ShouldStartWith, datadir.RGBDirectory)
+		test.That(t, string(depthData), test.ShouldStartWith, datadir.DepthDirectory)
+		test.That(t, string(colorData[len(datadir.RGBDirectory):]), test.ShouldEqual,
+			string(depthData[len(datadir.DepthDirectory):]))
+
+		test.That(t, colorCam.Close(ctx), test.ShouldBeNil)
+		test.That(t, depthCam.Close(ctx), test.ShouldBeNil)
+		test.That(t, sessions, test.ShouldBeEmpty)
+	})
+
+	t.Run("Replay stops at the end", func(t *testing.T) {
+		cam, err := newReplayCamera("color", &Config{DataDirectory: dir, Speed: 100})
+		test.That(t, err, test.ShouldBeNil)
+		time.Sleep(10 * time.Millisecond)
+		timeoutCtx, cancel := context.WithTimeout(ctx, time.Second)
+		defer cancel()
+		_, _, err = camera.ReadImage(timeoutCtx, cam)
+		test.That(t, err, test.ShouldNotBeNil)
+		test.That(t, cam.Close(ctx), test.ShouldBeNil)
+	})
+
+	t.Run("Reconfigured camera starts over", func(t *testing.T) {
+		cfg := &Config{DataDirectory: dir, Loop: true}
+		colorStart, releaseColor := startSession(cfg.sessionKey(), "color")
+		depthStart, releaseDepth := startSession(cfg.sessionKey(), "depth")
+		test.That(t, depthStart, test.ShouldEqual, colorStart)
+
+		// Other timing is replayed separately.
+		time.Sleep(time.Millisecond)
+		otherStart, releaseOther := startSession((&Config{DataDirectory: dir, Speed: 2, Loop: true}).sessionKey(), "other")
+		test.That(t, otherStart, test.ShouldHappenAfter, colorStart)
+		releaseOther()
+
+		// The reconfigured camera is built before the old one is closed.
+		reconfiguredStart, releaseReconfigured := startSession(cfg.sessionKey(), "color")
+		test.That(t, reconfiguredStart, test.ShouldHappenAfter, colorStart)
+		releaseColor()
+		releaseDepth()
+		test.That(t, sessions, test.ShouldHaveLength, 1)
+
+		// A camera started afterwards joins the replay of the reconfigured camera.
+		laterStart, releaseLater := startSession(cfg.sessionKey(), "depth")
+		test.That(t, laterStart, test.ShouldEqual, reconfiguredStart)
+		releaseReconfigured()
+		releaseLater()
+		test.That(t, sessions, test.ShouldBeEmpty)
+	})
+
+	t.Run("Replay without frames", func(t *testing.T) {
+		_, err := newReplayCamera("color", &Config{DataDirectory: dir, Sensor: "other"})
+		test.That(t, err.Error(), test.ShouldContainSubstring, "there are no frames to replay")
+		test.That(t, sessions, test.ShouldBeEmpty)
+	})
+}
@@ -0,0 +1,279 @@
+// Package synthetic implements a camera that renders a simple textured 3D scene from a scripted trajectory, and
+// records the exact pose of every frame, to test SLAM end to end without hardware.
+package synthetic
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/edaniels/golog"
+	"github.com/golang/geo/r3"
+	"github.com/pkg/errors"
+	"go.viam.com/rdk/components/camera"
+	"go.viam.com/rdk/resource"
+	"go.viam.com/rdk/rimage"
+	"go.viam.com/rdk/rimage/transform"
+	rdkutils "go.viam.com/rdk/utils"
+
+	"github.com/viamrobotics/viam-orb-slam3/scene"
+)
+
+// Model is the model of the synthetic camera.
+var Model = resource.NewModel("viam", "camera", "orbslamv3-synthetic")
+
+// Streams the camera renders.
+const (
+	ColorStream = "color"
+	DepthStream = "depth"
+)
+
+const (
+	defaultWidth     = 320
+	defaultHeight    = 240
+	defaultFrameRate = 10
+	defaultSpeed     = 0.2
+	// defaultFocalLengthRatio is the focal length relative to the width, for a horizontal field of view of about 64
+	// degrees.
+	defaultFocalLengthRatio = 0.8
+
+	// The default trajectory circles the center of the default scene, facing it.
+	defaultCircleRadius    = 1.8
+	defaultCircleHeight    = 1.2
+	defaultCirclePitchDeg  = -15
+	defaultCircleWaypoints = 12
+)
+
+func init() {
+	resource.RegisterComponent(camera.API, Model, resource.Registration[camera.Camera, *Config]{
+		Constructor: newCamera,
+	})
+}
+
+// Config describes how to configure the synthetic camera. A color and a depth camera with the same trajectory and
+// frame rate render the same frames, since the pose is a function of the time the frame is rendered for, and frames
+// are rendered for times on a grid of frame periods shared by all cameras.
+type Config struct {
+	// Stream is the stream rendered, ColorStream or DepthStream in mm. Defaults to ColorStream.
+	Stream string `json:"stream"`
+	// Width and Height are the size of the frames. Default to 320 by 240.
+	Width  int `json:"width_px"`
+	Height int `json:"height_px"`
+	// FocalLength defaults to 0.8 times the width.
+	FocalLength float64 `json:"focal_length_px"`
+	// FrameRate is the number of distinct frames per second. Defaults to 10.
+	FrameRate float64 `json:"frame_rate"`
+	// Speed is the speed of the camera along its trajectory in m/s. Defaults to 0.2.
+	Speed float64 `json:"speed_m_per_s"`
+	// Waypoints is the trajectory of the camera, which loops back to the first waypoint. Defaults to a circle around the
+	// center of the scene.
+	Waypoints []scene.Waypoint `json:"waypoints"`
+	// GroundTruthPath is the file the pose of every frame is appended to in the TUM RGB-D format, if set. Only a
+	// camera rendering ColorStream writes it.
+	GroundTruthPath string `json:"ground_truth_path"`
+}
+
+// Validate checks that the config is in range.
+func (config *Config) Validate(path string) ([]string, error) {
+	if config.Stream != "" && config.Stream != ColorStream && config.Stream != DepthStream {
+		return nil, errors.Errorf("stream must be %v or %v, got %v", ColorStream, DepthStream, config.Stream)
+	}
+	if config.Width < 0 || config.Height < 0 {
+		return nil, errors.New("cannot specify width_px or height_px less than zero")
+	}
+	if config.FocalLength < 0 {
+		return nil, errors.New("cannot specify focal_length_px less than zero")
+	}
+	if config.FrameRate < 0 {
+		return nil, errors.New("cannot specify frame_rate less than zero")
+	}
+	if config.Speed < 0 {
+		return nil, errors.New("cannot specify speed_m_per_s less than zero")
+	}
+	if len(config.Waypoints) == 1 {
+		return nil, errors.New("waypoints must hold at least 2 waypoints")
+	}
+	if config.GroundTruthPath != "" && config.Stream == DepthStream {
+		return nil, errors.Errorf("ground_truth_path is only written by a camera rendering the %v stream", ColorStream)
+	}
+	return nil, nil
+}
+
+// intrinsics returns the intrinsics of the camera, with the defaults applied.
+func (config *Config) intrinsics() scene.Intrinsics {
+	width, height := config.Width, config.Height
+	if width == 0 {
+		width = defaultWidth
+	}
+	if height == 0 {
+		height = defaultHeight
+	}
+	focalLength := config.FocalLength
+	if focalLength == 0 {
+		focalLength = defaultFocalLengthRatio * float64(width)
+	}
+	return scene.Intrinsics{
+		Width:  width,
+		Height: height,
+		Fx:     focalLength,
+		Fy:     focalLength,
+		Ppx:    float64(width) / 2,
+		Ppy:    float64(height) / 2,
+	}
+}
+
+// trajectory returns the trajectory of the camera, with the defaults applied.
+func (config *Config) trajectory() (*scene.Trajectory, error) {
+	waypoints := config.Waypoints
+	if len(waypoints) == 0 {
+		waypoints = scene.CircleWaypoints(r3.Vector{Z: defaultCircleHeight}, defaultCircleRadius, defaultCircleWaypoints)
+		for i := range waypoints {
+			waypoints[i].PitchDeg = defaultCirclePitchDeg
+		}
+	}
+	speed := config.Speed
+	if speed == 0 {
+		speed = defaultSpeed
+	}
+	return scene.NewTrajectory(waypoints, speed)
+}
+
+// newCamera returns a synthetic camera.
+func newCamera(ctx context.Context, _ resource.Dependencies, conf resource.Config, logger golog.Logger) (camera.Camera, error) {
+	cfg, err := resource.NativeConfig[*Config](conf)
+	if err != nil {
+		return nil, err
+	}
+	trajectory, err := cfg.trajectory()
+	if err != nil {
+		return nil, err
+	}
+	frameRate := cfg.FrameRate
+	if frameRate == 0 {
+		frameRate = defaultFrameRate
+	}
+	intrinsics := cfg.intrinsics()
+	reader := &frameReader{
+		scene:       scene.DefaultScene(),
+		intrinsics:  intrinsics,
+		trajectory:  trajectory,
+		framePeriod: time.Duration(float64(time.Second) / frameRate),
+		depth:       cfg.Stream == DepthStream,
+	}
+	if cfg.GroundTruthPath != "" {
+		if reader.groundTruth, err = openGroundTruth(cfg.GroundTruthPath); err != nil {
+			return nil, err
+		}
+		logger.Infow("writing the ground truth trajectory", "path", cfg.GroundTruthPath)
+	}
+
+	imageType := camera.ColorStream
+	if reader.depth {
+		imageType = camera.DepthStream
+	}
+	cameraModel := &transform.PinholeCameraModel{
+		PinholeCameraIntrinsics: &transform.PinholeCameraIntrinsics{
+			Width:  intrinsics.Width,
+			Height: intrinsics.Height,
+			Fx:     intrinsics.Fx,
+			Fy:     intrinsics.Fy,
+			Ppx:    intrinsics.Ppx,
+			Ppy:    intrinsics.Ppy,
+		},
+		Distortion: &transform.BrownConrady{},
+	}
+	src, err := camera.NewVideoSourceFromReader(ctx, reader, cameraModel, imageType)
+	if err != nil {
+		//nolint:errcheck
+		reader.Close(ctx)
+		return nil, err
+	}
+	return camera.FromVideoSource(conf.ResourceName(), src), nil
+}
+
+// openGroundTruth opens the ground truth trajectory at path to append to it, and writes its header if it is new.
+func openGroundTruth(path string) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return nil, errors.Wrap(err, "unable to create ground truth directory")
+	}
+	//nolint:gosec
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to open ground truth trajectory")
+	}
+	info, err := file.Stat()
+	if err == nil && info.Size() == 0 {
+		_, err = file.WriteString(scene.TUMHeader)
+	}
+	if err != nil {
+		//nolint:errcheck
+		file.Close()
+		return nil, errors.Wrap(err, "unable to write ground truth trajectory")
+	}
+	return file, nil
+}
+
+// frameReader renders the frame for the current time on the frame period grid, once per frame period.
+type frameReader struct {
+	scene       scene.Scene
+	intrinsics  scene.Intrinsics
+	trajectory  *scene.Trajectory
+	framePeriod time.Duration
+	depth       bool
+
+	mu sync.Mutex
+	// frameTime is the time on the grid the frame was rendered for, which its ground truth pose is written for.
+	frameTime   time.Time
+	frame       []byte
+	groundTruth *os.File
+}
+
+// Read returns the frame for the current time as a PNG. Frames are rendered for times on a grid, so that cameras with
+// the same frame rate render the same poses and the color and depth frames of a pair match. The SLAM service stamps a
+// frame with the time it reads it, which is up to one frame period after the time on the grid the frame and its
+// ground truth pose are for.
+func (r *frameReader) Read(ctx context.Context) (image.Image, func(), error) {
+	frameTime := time.Now().Truncate(r.framePeriod)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !frameTime.Equal(r.frameTime) {
+		pose := r.trajectory.Pose(time.Duration(frameTime.UnixNano()))
+		colorImg, depthImg, err := r.scene.Render(r.intrinsics, pose)
+		if err != nil {
+			return nil, nil, err
+		}
+		var img image.Image = colorImg
+		if r.depth {
+			img = depthImg
+		}
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, nil, errors.Wrap(err, "unable to encode frame")
+		}
+		r.frameTime, r.frame = frameTime, buf.Bytes()
+		if r.groundTruth != nil {
+			if err := scene.WriteTUM(r.groundTruth, frameTime, pose); err != nil {
+				return nil, nil, errors.Wrap(err, "unable to write ground truth trajectory")
+			}
+		}
+	}
+	return rimage.NewLazyEncodedImage(r.frame, rdkutils.MimeTypePNG), nil, nil
+}
+
+// Close closes the ground truth trajectory.
+func (r *frameReader) Close(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.groundTruth == nil {
+		return nil
+	}
+	err := r.groundTruth.Close()
+	r.groundTruth = nil
+	return err
+}
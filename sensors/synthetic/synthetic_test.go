@@ -0,0 +1,120 @@
+package synthetic
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/edaniels/golog"
+	"github.com/golang/geo/r3"
+	"go.viam.com/rdk/components/camera"
+	"go.viam.com/rdk/resource"
+	"go.viam.com/rdk/rimage"
+	"go.viam.com/test"
+
+	"github.com/viamrobotics/viam-orb-slam3/scene"
+)
+
+func TestValidate(t *testing.T) {
+	cfg := &Config{}
+	deps, err := cfg.Validate("path")
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, deps, test.ShouldBeEmpty)
+
+	cfg.Stream = "ir"
+	_, err = cfg.Validate("path")
+	test.That(t, err.Error(), test.ShouldContainSubstring, "stream must be color or depth")
+
+	cfg.Stream = DepthStream
+	cfg.FrameRate = -1
+	_, err = cfg.Validate("path")
+	test.That(t, err.Error(), test.ShouldContainSubstring, "frame_rate less than zero")
+
+	cfg.FrameRate = 0
+	cfg.Waypoints = []scene.Waypoint{{}}
+	_, err = cfg.Validate("path")
+	test.That(t, err.Error(), test.ShouldContainSubstring, "at least 2 waypoints")
+
+	cfg.Waypoints = nil
+	cfg.GroundTruthPath = "ground_truth.txt"
+	_, err = cfg.Validate("path")
+	test.That(t, err.Error(), test.ShouldContainSubstring, "ground_truth_path is only written")
+}
+
+func TestSyntheticCamera(t *testing.T) {
+	ctx := context.Background()
+	logger := golog.NewTestLogger(t)
+	newSyntheticCamera := func(cfg *Config) (camera.Camera, error) {
+		return newCamera(ctx, nil, resource.Config{
+			Name:                "synthetic",
+			API:                 camera.API,
+			Model:               Model,
+			ConvertedAttributes: cfg,
+		}, logger)
+	}
+
+	t.Run("Render color frames and their ground truth", func(t *testing.T) {
+		groundTruthPath := filepath.Join(t.TempDir(), "gt", "ground_truth.txt")
+		cam, err := newSyntheticCamera(&Config{Width: 64, Height: 48, FrameRate: 1000, GroundTruthPath: groundTruthPath})
+		test.That(t, err, test.ShouldBeNil)
+
+		props, err := cam.Properties(ctx)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, props.IntrinsicParams.Width, test.ShouldEqual, 64)
+		test.That(t, props.IntrinsicParams.Fx, test.ShouldAlmostEqual, 0.8*64)
+
+		start := time.Now()
+		for i := 0; i < 2; i++ {
+			img, _, err := camera.ReadImage(ctx, cam)
+			test.That(t, err, test.ShouldBeNil)
+			decoded, err := png.Decode(bytes.NewReader(img.(*rimage.LazyEncodedImage).RawData()))
+			test.That(t, err, test.ShouldBeNil)
+			test.That(t, decoded.Bounds(), test.ShouldResemble, image.Rect(0, 0, 64, 48))
+		}
+		end := time.Now()
+		test.That(t, cam.Close(ctx), test.ShouldBeNil)
+
+		data, err := os.ReadFile(groundTruthPath)
+		test.That(t, err, test.ShouldBeNil)
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		test.That(t, lines[0]+"\n", test.ShouldEqual, scene.TUMHeader)
+		test.That(t, len(lines), test.ShouldBeGreaterThanOrEqualTo, 2)
+		fields := strings.Fields(lines[1])
+		test.That(t, fields, test.ShouldHaveLength, 8)
+		// The ground truth is written for the time on the grid of 1ms frame periods the frame was rendered for, which is
+		// up to one frame period before it was read.
+		timestamp, err := strconv.ParseFloat(fields[0], 64)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, timestamp, test.ShouldBeBetweenOrEqual,
+			float64(start.Truncate(time.Millisecond).UnixMicro())/1e6-1e-6, float64(end.UnixMicro())/1e6+1e-6)
+	})
+
+	t.Run("Render depth frames", func(t *testing.T) {
+		cam, err := newSyntheticCamera(&Config{
+			Stream: DepthStream,
+			Width:  64,
+			Height: 48,
+			Waypoints: []scene.Waypoint{
+				{Position: r3.Vector{Y: -2, Z: 0.4}, YawDeg: 90},
+				{Position: r3.Vector{Y: -2.1, Z: 0.4}, YawDeg: 90},
+			},
+		})
+		test.That(t, err, test.ShouldBeNil)
+		img, _, err := camera.ReadImage(ctx, cam)
+		test.That(t, err, test.ShouldBeNil)
+		decoded, err := png.Decode(bytes.NewReader(img.(*rimage.LazyEncodedImage).RawData()))
+		test.That(t, err, test.ShouldBeNil)
+		depth, ok := decoded.(*image.Gray16)
+		test.That(t, ok, test.ShouldBeTrue)
+		// The box at the center of the scene is 1.6m to 1.7m ahead.
+		test.That(t, depth.Gray16At(32, 24).Y, test.ShouldBeBetweenOrEqual, 1600, 1700)
+		test.That(t, cam.Close(ctx), test.ShouldBeNil)
+	})
+}
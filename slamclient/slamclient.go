@@ -0,0 +1,361 @@
+// Package slamclient implements a managed client connection to the SLAM algorithm's gRPC server. The connection is
+// kept alive, health checked and reestablished when it is lost.
+package slamclient
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/edaniels/golog"
+	"github.com/pkg/errors"
+	"go.opencensus.io/trace"
+	commonpb "go.viam.com/api/common/v1"
+	pb "go.viam.com/api/service/slam/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/status"
+)
+
+// This increases the message size from 4MB to 32MB to match the RDK.
+// This is necessary for transmitting large pointclouds.
+const grpcMaxMessageSize = 32 * 1024 * 1024
+
+const (
+	defaultHealthCheckInterval = 5 * time.Second
+	defaultKeepaliveTime       = 30 * time.Second
+	keepaliveTimeout           = 10 * time.Second
+	redialInterval             = time.Second
+)
+
+// ErrUnavailable is matched by the errors returned by calls made while the SLAM backend is unavailable, so that
+// callers can check for it with errors.Is.
+var ErrUnavailable = errors.New("SLAM backend unavailable")
+
+// UnavailableError is returned by calls made while the connection to the SLAM backend is lost or being
+// reestablished.
+type UnavailableError struct {
+	Target string
+	Err    error
+}
+
+func (e *UnavailableError) Error() string {
+	if e.Err == nil {
+		return ErrUnavailable.Error() + " at " + e.Target
+	}
+	return ErrUnavailable.Error() + " at " + e.Target + ": " + e.Err.Error()
+}
+
+// Unwrap returns the error that made the backend unavailable.
+func (e *UnavailableError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is ErrUnavailable.
+func (e *UnavailableError) Is(target error) bool {
+	return target == ErrUnavailable //nolint:errorlint
+}
+
+// Options configures a Client.
+type Options struct {
+	// Target returns the address to dial. It is called for every dial, so that a restarted SLAM process can listen
+	// on a different address.
+	Target func() (string, error)
+	// DialTimeout is the time the initial dial and every redial may take.
+	DialTimeout time.Duration
+	// HealthCheckInterval is the time between health checks. Defaults to 5 seconds.
+	HealthCheckInterval time.Duration
+	// KeepaliveTime is the time without activity after which the connection is pinged. Defaults to 30 seconds.
+	KeepaliveTime time.Duration
+	// DialOptions are additional options used for every dial. Transport credentials default to insecure ones.
+	DialOptions []grpc.DialOption
+}
+
+// Client is a pb.SLAMServiceClient that holds a managed connection to the SLAM backend. It is safe for concurrent
+// use.
+type Client struct {
+	opts   Options
+	logger golog.Logger
+
+	mu     sync.Mutex
+	conn   *grpc.ClientConn
+	client pb.SLAMServiceClient
+	target string
+	// lastErr is the reason the backend is unavailable, or nil while it is available.
+	lastErr error
+
+	reconnect  chan struct{}
+	cancelFunc func()
+	workers    sync.WaitGroup
+}
+
+var _ pb.SLAMServiceClient = (*Client)(nil)
+
+// New dials the SLAM backend, blocking until it is connected or the dial timeout expires, and starts monitoring the
+// connection.
+func New(ctx context.Context, opts Options, logger golog.Logger) (*Client, error) {
+	ctx, span := trace.StartSpan(ctx, "slamclient::New")
+	defer span.End()
+
+	if opts.HealthCheckInterval == 0 {
+		opts.HealthCheckInterval = defaultHealthCheckInterval
+	}
+	if opts.KeepaliveTime == 0 {
+		opts.KeepaliveTime = defaultKeepaliveTime
+	}
+	c := &Client{opts: opts, logger: logger, reconnect: make(chan struct{}, 1)}
+	if err := c.dial(ctx); err != nil {
+		logger.Errorw("error connecting to slam process", "error", err)
+		return nil, err
+	}
+
+	cancelCtx, cancelFunc := context.WithCancel(context.Background())
+	c.cancelFunc = cancelFunc
+	c.workers.Add(1)
+	go func() {
+		defer c.workers.Done()
+		c.monitor(cancelCtx)
+	}()
+	return c, nil
+}
+
+// dial replaces the current connection with a new one to the current target.
+func (c *Client) dial(ctx context.Context) error {
+	target, err := c.opts.Target()
+	if err != nil {
+		return errors.Wrap(err, "error getting slam backend address")
+	}
+
+	dialOpts := []grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithBlock(),
+		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(grpcMaxMessageSize)),
+		grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                c.opts.KeepaliveTime,
+			Timeout:             keepaliveTimeout,
+			PermitWithoutStream: true,
+		}),
+	}
+	dialOpts = append(dialOpts, c.opts.DialOptions...)
+
+	ctx, timeoutCancel := context.WithTimeout(ctx, c.opts.DialTimeout)
+	defer timeoutCancel()
+	conn, err := grpc.DialContext(ctx, target, dialOpts...)
+	if err == nil {
+		if err = checkHealth(ctx, conn); err != nil {
+			if closeErr := conn.Close(); closeErr != nil {
+				c.logger.Debugw("error closing unhealthy slam backend connection", "error", closeErr)
+			}
+		}
+	}
+	if err != nil {
+		c.setUnavailable(target, err)
+		return err
+	}
+
+	c.mu.Lock()
+	oldConn := c.conn
+	c.conn = conn
+	c.client = pb.NewSLAMServiceClient(conn)
+	c.target = target
+	c.lastErr = nil
+	c.mu.Unlock()
+	if oldConn != nil {
+		if err := oldConn.Close(); err != nil {
+			c.logger.Debugw("error closing previous slam backend connection", "error", err)
+		}
+	}
+	return nil
+}
+
+// monitor health checks the connection and redials it whenever it is lost.
+func (c *Client) monitor(ctx context.Context) {
+	ticker := time.NewTicker(c.opts.HealthCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.checkHealth(ctx, c.opts.HealthCheckInterval); err != nil {
+				c.markUnavailable(err)
+			}
+		case <-c.reconnect:
+		}
+
+		for c.unavailable() {
+			if err := c.dial(ctx); err == nil {
+				c.logger.Info("reconnected to slam backend")
+				break
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(redialInterval):
+			}
+		}
+	}
+}
+
+// checkHealth checks the health of the current connection.
+func (c *Client) checkHealth(ctx context.Context, timeout time.Duration) error {
+	c.mu.Lock()
+	conn := c.conn
+	c.mu.Unlock()
+	if conn == nil {
+		return errors.New("not connected")
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return checkHealth(ctx, conn)
+}
+
+// checkHealth checks the health of the backend. Backends without a health service are healthy as long as they
+// respond.
+func checkHealth(ctx context.Context, conn *grpc.ClientConn) error {
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	if status.Code(err) == codes.Unimplemented {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		return errors.Errorf("slam backend is %v", resp.GetStatus())
+	}
+	return nil
+}
+
+func (c *Client) unavailable() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lastErr != nil
+}
+
+func (c *Client) setUnavailable(target string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.target = target
+	c.lastErr = err
+}
+
+// markUnavailable marks the backend as unavailable and wakes up the monitor to reconnect.
+func (c *Client) markUnavailable(err error) {
+	c.mu.Lock()
+	if c.lastErr == nil {
+		c.logger.Warnw("lost connection to slam backend, reconnecting", "target", c.target, "error", err)
+	}
+	c.lastErr = err
+	c.mu.Unlock()
+	select {
+	case c.reconnect <- struct{}{}:
+	default:
+	}
+}
+
+// current returns the client of the current connection, or an UnavailableError while the backend is unavailable.
+func (c *Client) current() (pb.SLAMServiceClient, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.lastErr != nil {
+		return nil, &UnavailableError{Target: c.target, Err: c.lastErr}
+	}
+	return c.client, nil
+}
+
+// checkCallError converts errors that mean the connection was lost into an UnavailableError and starts
+// reconnecting.
+func (c *Client) checkCallError(err error) error {
+	if status.Code(err) != codes.Unavailable {
+		return err
+	}
+	c.markUnavailable(err)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return &UnavailableError{Target: c.target, Err: err}
+}
+
+// GetPosition calls GetPosition on the SLAM backend.
+func (c *Client) GetPosition(ctx context.Context, in *pb.GetPositionRequest, opts ...grpc.CallOption,
+) (*pb.GetPositionResponse, error) {
+	client, err := c.current()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.GetPosition(ctx, in, opts...)
+	return resp, c.checkCallError(err)
+}
+
+// GetPointCloudMap calls GetPointCloudMap on the SLAM backend.
+func (c *Client) GetPointCloudMap(ctx context.Context, in *pb.GetPointCloudMapRequest, opts ...grpc.CallOption,
+) (pb.SLAMService_GetPointCloudMapClient, error) {
+	client, err := c.current()
+	if err != nil {
+		return nil, err
+	}
+	stream, err := client.GetPointCloudMap(ctx, in, opts...)
+	if err != nil {
+		return nil, c.checkCallError(err)
+	}
+	return &pointCloudMapStream{SLAMService_GetPointCloudMapClient: stream, client: c}, nil
+}
+
+// GetInternalState calls GetInternalState on the SLAM backend.
+func (c *Client) GetInternalState(ctx context.Context, in *pb.GetInternalStateRequest, opts ...grpc.CallOption,
+) (pb.SLAMService_GetInternalStateClient, error) {
+	client, err := c.current()
+	if err != nil {
+		return nil, err
+	}
+	stream, err := client.GetInternalState(ctx, in, opts...)
+	if err != nil {
+		return nil, c.checkCallError(err)
+	}
+	return &internalStateStream{SLAMService_GetInternalStateClient: stream, client: c}, nil
+}
+
+// DoCommand calls DoCommand on the SLAM backend.
+func (c *Client) DoCommand(ctx context.Context, in *commonpb.DoCommandRequest, opts ...grpc.CallOption,
+) (*commonpb.DoCommandResponse, error) {
+	client, err := c.current()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.DoCommand(ctx, in, opts...)
+	return resp, c.checkCallError(err)
+}
+
+// Close stops monitoring the connection and closes it.
+func (c *Client) Close() error {
+	c.cancelFunc()
+	c.workers.Wait()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		return nil
+	}
+	return c.conn.Close()
+}
+
+type pointCloudMapStream struct {
+	pb.SLAMService_GetPointCloudMapClient
+	client *Client
+}
+
+func (s *pointCloudMapStream) Recv() (*pb.GetPointCloudMapResponse, error) {
+	resp, err := s.SLAMService_GetPointCloudMapClient.Recv()
+	return resp, s.client.checkCallError(err)
+}
+
+type internalStateStream struct {
+	pb.SLAMService_GetInternalStateClient
+	client *Client
+}
+
+func (s *internalStateStream) Recv() (*pb.GetInternalStateResponse, error) {
+	resp, err := s.SLAMService_GetInternalStateClient.Recv()
+	return resp, s.client.checkCallError(err)
+}
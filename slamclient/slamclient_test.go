@@ -0,0 +1,171 @@
+package slamclient
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/edaniels/golog"
+	"github.com/pkg/errors"
+	commonpb "go.viam.com/api/common/v1"
+	pb "go.viam.com/api/service/slam/v1"
+	"go.viam.com/test"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+type positionServer struct {
+	pb.UnimplementedSLAMServiceServer
+}
+
+func (s *positionServer) GetPosition(ctx context.Context, req *pb.GetPositionRequest) (*pb.GetPositionResponse, error) {
+	return &pb.GetPositionResponse{ComponentReference: "cam"}, nil
+}
+
+type testServer struct {
+	server *grpc.Server
+	health *health.Server
+	addr   string
+}
+
+func startTestServer(t *testing.T, addr string) *testServer {
+	t.Helper()
+	return startTestServerOn(t, "tcp", addr)
+}
+
+func startTestServerOn(t *testing.T, network, addr string) *testServer {
+	t.Helper()
+	listener, err := net.Listen(network, addr)
+	test.That(t, err, test.ShouldBeNil)
+	s := &testServer{server: grpc.NewServer(), health: health.NewServer(), addr: listener.Addr().String()}
+	pb.RegisterSLAMServiceServer(s.server, &positionServer{})
+	healthpb.RegisterHealthServer(s.server, s.health)
+	go s.server.Serve(listener)
+	return s
+}
+
+// target is a concurrency safe address for Options.Target.
+type target struct {
+	mu   sync.Mutex
+	addr string
+}
+
+func (tg *target) set(addr string) {
+	tg.mu.Lock()
+	defer tg.mu.Unlock()
+	tg.addr = addr
+}
+
+func (tg *target) get() (string, error) {
+	tg.mu.Lock()
+	defer tg.mu.Unlock()
+	return tg.addr, nil
+}
+
+func waitForAvailable(t *testing.T, client *Client) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		if _, err := client.GetPosition(context.Background(), &pb.GetPositionRequest{}); err == nil {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatal("slam backend did not become available")
+}
+
+func TestClient(t *testing.T) {
+	logger := golog.NewTestLogger(t)
+	ctx := context.Background()
+
+	t.Run("Dial fails", func(t *testing.T) {
+		_, err := New(ctx, Options{
+			Target:      func() (string, error) { return "invalid_unused_port:0", nil },
+			DialTimeout: 100 * time.Millisecond,
+		}, logger)
+		test.That(t, err, test.ShouldBeError, errors.New("context deadline exceeded"))
+	})
+
+	t.Run("Reconnects after the backend restarts on a different address", func(t *testing.T) {
+		server := startTestServer(t, "localhost:0")
+		tg := &target{addr: server.addr}
+		client, err := New(ctx, Options{
+			Target:              tg.get,
+			DialTimeout:         time.Second,
+			HealthCheckInterval: 100 * time.Millisecond,
+		}, logger)
+		test.That(t, err, test.ShouldBeNil)
+		defer func() {
+			test.That(t, client.Close(), test.ShouldBeNil)
+		}()
+
+		resp, err := client.GetPosition(ctx, &pb.GetPositionRequest{})
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, resp.GetComponentReference(), test.ShouldEqual, "cam")
+
+		// Errors that do not mean the backend is unavailable are returned as they are.
+		_, err = client.DoCommand(ctx, &commonpb.DoCommandRequest{})
+		test.That(t, status.Code(err), test.ShouldEqual, codes.Unimplemented)
+
+		server.server.Stop()
+		_, err = client.GetPosition(ctx, &pb.GetPositionRequest{})
+		test.That(t, errors.Is(err, ErrUnavailable), test.ShouldBeTrue)
+		var unavailableErr *UnavailableError
+		test.That(t, errors.As(err, &unavailableErr), test.ShouldBeTrue)
+		test.That(t, unavailableErr.Target, test.ShouldEqual, server.addr)
+
+		restarted := startTestServer(t, "localhost:0")
+		defer restarted.server.Stop()
+		tg.set(restarted.addr)
+		waitForAvailable(t, client)
+	})
+
+	t.Run("Backend that is not serving is unavailable", func(t *testing.T) {
+		server := startTestServer(t, "localhost:0")
+		defer server.server.Stop()
+		client, err := New(ctx, Options{
+			Target:              func() (string, error) { return server.addr, nil },
+			DialTimeout:         time.Second,
+			HealthCheckInterval: 50 * time.Millisecond,
+		}, logger)
+		test.That(t, err, test.ShouldBeNil)
+		defer func() {
+			test.That(t, client.Close(), test.ShouldBeNil)
+		}()
+
+		server.health.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+		deadline := time.Now().Add(5 * time.Second)
+		for !client.unavailable() && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		_, err = client.GetPosition(ctx, &pb.GetPositionRequest{})
+		test.That(t, errors.Is(err, ErrUnavailable), test.ShouldBeTrue)
+
+		server.health.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+		waitForAvailable(t, client)
+	})
+
+	t.Run("Dials a unix socket", func(t *testing.T) {
+		socketPath := filepath.Join(t.TempDir(), "orbslam.sock")
+		server := startTestServerOn(t, "unix", socketPath)
+		defer server.server.Stop()
+		client, err := New(ctx, Options{
+			Target:      func() (string, error) { return "unix:" + socketPath, nil },
+			DialTimeout: time.Second,
+		}, logger)
+		test.That(t, err, test.ShouldBeNil)
+		defer func() {
+			test.That(t, client.Close(), test.ShouldBeNil)
+		}()
+
+		resp, err := client.GetPosition(ctx, &pb.GetPositionRequest{})
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, resp.GetComponentReference(), test.ShouldEqual, "cam")
+	})
+}
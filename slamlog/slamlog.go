@@ -0,0 +1,270 @@
+// Package slamlog parses the logs of the SLAM process into structured events.
+package slamlog
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/edaniels/golog"
+	"go.uber.org/zap/zapcore"
+)
+
+// boostTimeFormat is the timestamp format of the default BOOST_LOG_TRIVIAL sink.
+const boostTimeFormat = "2006-01-02 15:04:05.000000"
+
+// boostLinePattern matches lines written by the default BOOST_LOG_TRIVIAL sink, e.g.
+// "[2023-06-13 15:04:05.123456] [0x00007f8b2c1a2740] [info]    Server listening on 45001".
+var boostLinePattern = regexp.MustCompile(`^\[(\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}\.\d+)\] \[0x[0-9a-fA-F]+\] \[(\w+)\]\s*(.*)$`)
+
+// Severity is the severity of a BOOST_LOG_TRIVIAL log line.
+type Severity string
+
+const (
+	// SeverityTrace is the BOOST trace severity.
+	SeverityTrace Severity = "trace"
+	// SeverityDebug is the BOOST debug severity.
+	SeverityDebug Severity = "debug"
+	// SeverityInfo is the BOOST info severity. Lines not written through BOOST_LOG, such as the
+	// ones ORB_SLAM3 writes to stdout, are given this severity.
+	SeverityInfo Severity = "info"
+	// SeverityWarning is the BOOST warning severity.
+	SeverityWarning Severity = "warning"
+	// SeverityError is the BOOST error severity.
+	SeverityError Severity = "error"
+	// SeverityFatal is the BOOST fatal severity.
+	SeverityFatal Severity = "fatal"
+)
+
+// Level maps the severity onto a golog level. Fatal lines are logged as errors, since the SLAM
+// process exiting must not bring down the module.
+func (s Severity) Level() zapcore.Level {
+	switch s {
+	case SeverityTrace, SeverityDebug:
+		return zapcore.DebugLevel
+	case SeverityWarning:
+		return zapcore.WarnLevel
+	case SeverityError, SeverityFatal:
+		return zapcore.ErrorLevel
+	case SeverityInfo:
+		return zapcore.InfoLevel
+	default:
+		return zapcore.InfoLevel
+	}
+}
+
+// Line is a single parsed log line.
+type Line struct {
+	// Time is the timestamp of the line, or the zero time if the line has none.
+	Time     time.Time
+	Severity Severity
+	Message  string
+}
+
+// ParseLine parses a single log line of the SLAM process.
+func ParseLine(raw string) Line {
+	raw = strings.TrimRight(raw, "\r\n")
+	matches := boostLinePattern.FindStringSubmatch(raw)
+	if matches == nil {
+		return Line{Severity: SeverityInfo, Message: raw}
+	}
+	line := Line{Severity: Severity(matches[2]), Message: matches[3]}
+	if t, err := time.ParseInLocation(boostTimeFormat, matches[1], time.Local); err == nil {
+		line.Time = t
+	}
+	return line
+}
+
+// EventType is the type of a structured SLAM event.
+type EventType string
+
+const (
+	// TrackingLost is emitted when ORB_SLAM3 loses tracking.
+	TrackingLost EventType = "tracking_lost"
+	// MapReset is emitted when ORB_SLAM3 resets the active map.
+	MapReset EventType = "map_reset"
+	// MapMerge is emitted when ORB_SLAM3 merges two maps of the atlas.
+	MapMerge EventType = "map_merge"
+	// MapSaved is emitted when the SLAM process saves the atlas to the map directory.
+	MapSaved EventType = "map_saved"
+	// FinalMapSaved is emitted in offline mode once the SLAM process has saved the atlas for the last time, after it
+	// finished processing all offline images. The save itself is reported by a MapSaved event.
+	FinalMapSaved EventType = "final_map_saved"
+	// OfflineDone is emitted when the SLAM process finished processing all offline images.
+	OfflineDone EventType = "offline_done"
+)
+
+// Event is a structured event parsed from a known log line.
+type Event struct {
+	Type EventType
+	Line Line
+	// MapPath is the path of the saved map for MapSaved events, if the log line contains it.
+	MapPath string
+	// KeyFrames and MapPoints are the sizes of the saved map for MapSaved events, or zero if the log line does not
+	// contain them.
+	KeyFrames int
+	MapPoints int
+}
+
+const mapSavedPrefix = "Saved map to "
+
+// maxPartialLineBytes is the size at which output without a newline is handled as a line, so that a process writing
+// without newlines cannot grow the buffer without bound.
+const maxPartialLineBytes = 64 * 1024
+
+// mapSizePattern matches the sizes of a saved map in a MapSaved log line, e.g. "(keyframes=3, map_points=120)".
+var mapSizePattern = regexp.MustCompile(`\(keyframes=(\d+)(?:, map_points=(\d+))?\)`)
+
+// eventMatchers maps lowercase substrings of known log lines onto the event they represent.
+var eventMatchers = []struct {
+	substring string
+	eventType EventType
+}{
+	{"tracking lost", TrackingLost},
+	{"fail to track local map", TrackingLost},
+	{"reseting active map", MapReset},
+	{"resetting active map", MapReset},
+	{"merge finished", MapMerge},
+	{strings.ToLower(mapSavedPrefix), MapSaved},
+	{"finished saving final map", FinalMapSaved},
+	{"finished processing offline images", OfflineDone},
+}
+
+// ParseEvent returns the event represented by the line, if the line is a known one.
+func ParseEvent(line Line) (Event, bool) {
+	lowered := strings.ToLower(line.Message)
+	for _, matcher := range eventMatchers {
+		if !strings.Contains(lowered, matcher.substring) {
+			continue
+		}
+		event := Event{Type: matcher.eventType, Line: line}
+		if idx := strings.Index(line.Message, mapSavedPrefix); matcher.eventType == MapSaved && idx != -1 {
+			event.MapPath = strings.Fields(line.Message[idx+len(mapSavedPrefix):] + " ")[0]
+			if match := mapSizePattern.FindStringSubmatch(line.Message); match != nil {
+				event.KeyFrames, _ = strconv.Atoi(match[1])
+				event.MapPoints, _ = strconv.Atoi(match[2])
+			}
+		}
+		return event, true
+	}
+	return Event{}, false
+}
+
+// Parser is an io.Writer that receives the output of the SLAM process, logs every line at the
+// golog level matching its BOOST severity and publishes known lines as events to its subscribers.
+// Output can additionally be forwarded to a downstream writer.
+type Parser struct {
+	logger golog.Logger
+
+	mu          sync.Mutex
+	partial     []byte
+	downstream  io.Writer
+	subscribers []subscriber
+	nextID      int
+}
+
+type subscriber struct {
+	id int
+	fn func(Event)
+}
+
+// NewParser returns a parser that logs the lines it receives to the given logger.
+func NewParser(logger golog.Logger) *Parser {
+	return &Parser{logger: logger}
+}
+
+// SetDownstream sets a writer that all received output is forwarded to unchanged. A nil writer
+// disables forwarding. If the downstream writer returns an error it is detached, so that a closed
+// downstream pipe does not stop logs from being parsed.
+func (p *Parser) SetDownstream(w io.Writer) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.downstream = w
+}
+
+// Subscribe registers a function that is called for every event. Subscribers are called
+// synchronously, in the order they subscribed, while the output of the SLAM process is being
+// handled, so they must not block. The returned function removes the subscription.
+func (p *Parser) Subscribe(fn func(Event)) func() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	id := p.nextID
+	p.nextID++
+	p.subscribers = append(p.subscribers, subscriber{id: id, fn: fn})
+	return func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		for i, sub := range p.subscribers {
+			if sub.id == id {
+				p.subscribers = append(p.subscribers[:i:i], p.subscribers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Write handles output of the SLAM process. It never returns an error.
+func (p *Parser) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	downstream := p.downstream
+	p.mu.Unlock()
+	// Writing to the downstream writer may block, e.g. on an unread pipe, so do not hold the lock.
+	if downstream != nil {
+		if _, err := downstream.Write(b); err != nil {
+			p.logger.Debugw("detaching slam process log downstream writer", "error", err)
+			p.mu.Lock()
+			if p.downstream == downstream {
+				p.downstream = nil
+			}
+			p.mu.Unlock()
+		}
+	}
+
+	p.mu.Lock()
+	p.partial = append(p.partial, b...)
+	var rawLines []string
+	for {
+		idx := bytes.IndexByte(p.partial, '\n')
+		if idx == -1 {
+			break
+		}
+		rawLines = append(rawLines, string(p.partial[:idx]))
+		p.partial = p.partial[idx+1:]
+	}
+	if len(p.partial) >= maxPartialLineBytes {
+		rawLines = append(rawLines, string(p.partial))
+		p.partial = nil
+	}
+	subscribers := make([]func(Event), 0, len(p.subscribers))
+	for _, sub := range p.subscribers {
+		subscribers = append(subscribers, sub.fn)
+	}
+	p.mu.Unlock()
+
+	for _, raw := range rawLines {
+		p.handleLine(raw, subscribers)
+	}
+	return len(b), nil
+}
+
+func (p *Parser) handleLine(raw string, subscribers []func(Event)) {
+	line := ParseLine(raw)
+	if strings.TrimSpace(line.Message) == "" {
+		return
+	}
+	if entry := p.logger.Desugar().Check(line.Severity.Level(), line.Message); entry != nil {
+		entry.Write()
+	}
+
+	event, ok := ParseEvent(line)
+	if !ok {
+		return
+	}
+	for _, fn := range subscribers {
+		fn(event)
+	}
+}
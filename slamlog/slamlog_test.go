@@ -0,0 +1,183 @@
+package slamlog
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/edaniels/golog"
+	"go.uber.org/zap/zapcore"
+	"go.viam.com/test"
+)
+
+func TestParseLine(t *testing.T) {
+	t.Run("Parse BOOST log line", func(t *testing.T) {
+		line := ParseLine("[2023-06-13 15:04:05.123456] [0x00007f8b2c1a2740] [warning]   Tracking lost\n")
+		test.That(t, line.Severity, test.ShouldEqual, SeverityWarning)
+		test.That(t, line.Message, test.ShouldEqual, "Tracking lost")
+		expectedTime := time.Date(2023, time.June, 13, 15, 4, 5, 123456000, time.Local)
+		test.That(t, line.Time.Equal(expectedTime), test.ShouldBeTrue)
+	})
+
+	t.Run("Parse line not written through BOOST_LOG", func(t *testing.T) {
+		line := ParseLine("Fail to track local map!")
+		test.That(t, line.Severity, test.ShouldEqual, SeverityInfo)
+		test.That(t, line.Message, test.ShouldEqual, "Fail to track local map!")
+		test.That(t, line.Time.IsZero(), test.ShouldBeTrue)
+	})
+}
+
+func TestSeverityLevel(t *testing.T) {
+	for severity, level := range map[Severity]zapcore.Level{
+		SeverityTrace:   zapcore.DebugLevel,
+		SeverityDebug:   zapcore.DebugLevel,
+		SeverityInfo:    zapcore.InfoLevel,
+		SeverityWarning: zapcore.WarnLevel,
+		SeverityError:   zapcore.ErrorLevel,
+		SeverityFatal:   zapcore.ErrorLevel,
+		"unknown":       zapcore.InfoLevel,
+	} {
+		test.That(t, severity.Level(), test.ShouldEqual, level)
+	}
+}
+
+func TestParseEvent(t *testing.T) {
+	cases := []struct {
+		message   string
+		eventType EventType
+		mapPath   string
+	}{
+		{"Tracking lost", TrackingLost, ""},
+		{"Fail to track local map!", TrackingLost, ""},
+		{"SYSTEM-> Reseting active map in monocular case", MapReset, ""},
+		{"Merge finished!", MapMerge, ""},
+		{"Saved map to /data/map/cam_data_2023-06-13T15:04:05.0000Z.osa (keyframes=3)", MapSaved,
+			"/data/map/cam_data_2023-06-13T15:04:05.0000Z.osa"},
+		{"Finished saving final map", FinalMapSaved, ""},
+		{"Finished processing offline images", OfflineDone, ""},
+	}
+	for _, c := range cases {
+		t.Run(c.message, func(t *testing.T) {
+			event, ok := ParseEvent(Line{Severity: SeverityInfo, Message: c.message})
+			test.That(t, ok, test.ShouldBeTrue)
+			test.That(t, event.Type, test.ShouldEqual, c.eventType)
+			test.That(t, event.MapPath, test.ShouldEqual, c.mapPath)
+		})
+	}
+
+	t.Run("Saved map sizes", func(t *testing.T) {
+		event, ok := ParseEvent(Line{
+			Severity: SeverityInfo,
+			Message:  "Saved map to /data/map/cam_data_2023-06-13T15:04:05.0000Z.osa (keyframes=3, map_points=120)",
+		})
+		test.That(t, ok, test.ShouldBeTrue)
+		test.That(t, event.KeyFrames, test.ShouldEqual, 3)
+		test.That(t, event.MapPoints, test.ShouldEqual, 120)
+
+		event, ok = ParseEvent(Line{Severity: SeverityInfo, Message: "Saved map to /data/map/cam_data.osa"})
+		test.That(t, ok, test.ShouldBeTrue)
+		test.That(t, event.KeyFrames, test.ShouldEqual, 0)
+		test.That(t, event.MapPoints, test.ShouldEqual, 0)
+	})
+
+	t.Run("Unknown line", func(t *testing.T) {
+		_, ok := ParseEvent(Line{Severity: SeverityInfo, Message: "Server listening on 45001"})
+		test.That(t, ok, test.ShouldBeFalse)
+	})
+}
+
+type failingWriter struct {
+	writes int
+}
+
+func (w *failingWriter) Write(b []byte) (int, error) {
+	w.writes++
+	return 0, io.ErrClosedPipe
+}
+
+func TestParser(t *testing.T) {
+	t.Run("Lines are logged at their level and events are published", func(t *testing.T) {
+		logger, obs := golog.NewObservedTestLogger(t)
+		parser := NewParser(logger)
+
+		var events []Event
+		unsubscribe := parser.Subscribe(func(e Event) { events = append(events, e) })
+
+		// Lines may be split across writes.
+		n, err := parser.Write([]byte("[2023-06-13 15:04:05.123456] [0x00007f8b2c1a2740] [error]   Failed to load frame\n" +
+			"[2023-06-13 15:04:05.123456] [0x00007f8b2c1a2740] [info]    Finished processing "))
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, n, test.ShouldBeGreaterThan, 0)
+		test.That(t, events, test.ShouldBeEmpty)
+		_, err = parser.Write([]byte("offline images\n"))
+		test.That(t, err, test.ShouldBeNil)
+
+		test.That(t, events, test.ShouldHaveLength, 1)
+		test.That(t, events[0].Type, test.ShouldEqual, OfflineDone)
+
+		entries := obs.All()
+		test.That(t, entries, test.ShouldHaveLength, 2)
+		test.That(t, entries[0].Level, test.ShouldEqual, zapcore.ErrorLevel)
+		test.That(t, entries[0].Message, test.ShouldEqual, "Failed to load frame")
+		test.That(t, entries[1].Level, test.ShouldEqual, zapcore.InfoLevel)
+
+		unsubscribe()
+		_, err = parser.Write([]byte("Merge finished!\n"))
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, events, test.ShouldHaveLength, 1)
+	})
+
+	t.Run("Subscribers are called in subscription order", func(t *testing.T) {
+		parser := NewParser(golog.NewTestLogger(t))
+		var order []int
+		unsubscribes := make([]func(), 0, 5)
+		for i := 0; i < 5; i++ {
+			i := i
+			unsubscribes = append(unsubscribes, parser.Subscribe(func(Event) { order = append(order, i) }))
+		}
+		unsubscribes[1]()
+		_, err := parser.Write([]byte("Tracking lost\n"))
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, order, test.ShouldResemble, []int{0, 2, 3, 4})
+	})
+
+	t.Run("Output is forwarded downstream", func(t *testing.T) {
+		parser := NewParser(golog.NewTestLogger(t))
+		var buf bytes.Buffer
+		parser.SetDownstream(&buf)
+		_, err := parser.Write([]byte("Server listening on 45001\n"))
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, buf.String(), test.ShouldEqual, "Server listening on 45001\n")
+	})
+
+	t.Run("Failing downstream writer is detached", func(t *testing.T) {
+		logger := golog.NewTestLogger(t)
+		parser := NewParser(logger)
+		w := &failingWriter{}
+		parser.SetDownstream(w)
+
+		var events []Event
+		parser.Subscribe(func(e Event) { events = append(events, e) })
+		for i := 0; i < 3; i++ {
+			_, err := parser.Write([]byte("Tracking lost\n"))
+			test.That(t, err, test.ShouldBeNil)
+		}
+		test.That(t, w.writes, test.ShouldEqual, 1)
+		test.That(t, events, test.ShouldHaveLength, 3)
+	})
+
+	t.Run("Output without newlines is flushed as a line", func(t *testing.T) {
+		logger, obs := golog.NewObservedTestLogger(t)
+		parser := NewParser(logger)
+		chunk := bytes.Repeat([]byte("x"), maxPartialLineBytes/4)
+		for i := 0; i < 4; i++ {
+			_, err := parser.Write(chunk)
+			test.That(t, err, test.ShouldBeNil)
+		}
+		test.That(t, parser.partial, test.ShouldBeEmpty)
+		entries := obs.All()
+		test.That(t, entries, test.ShouldHaveLength, 1)
+		test.That(t, entries[0].Message, test.ShouldHaveLength, maxPartialLineBytes)
+	})
+}
@@ -0,0 +1,45 @@
+package viamorbslam3
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// TransportTCP connects to the SLAM process over a TCP port.
+	TransportTCP = "tcp"
+	// TransportUnix connects to the SLAM process over a unix socket in the data directory.
+	TransportUnix = "unix"
+
+	socketFileName = "orbslam.sock"
+	// maxUnixSocketPathLength is the longest socket path that fits in sockaddr_un on Linux, leaving room for the
+	// terminating null byte.
+	maxUnixSocketPathLength = 107
+)
+
+// unixSocketPath returns the absolute path of the unix socket the SLAM process listens on.
+func unixSocketPath(dataDirectory string) (string, error) {
+	dir, err := filepath.Abs(dataDirectory)
+	if err != nil {
+		return "", errors.Wrap(err, "unable to resolve the data directory")
+	}
+	path := filepath.Join(dir, socketFileName)
+	if len(path) > maxUnixSocketPathLength {
+		return "", errors.Errorf("unix socket path %v is longer than %d characters, use a shorter data_dir",
+			path, maxUnixSocketPathLength)
+	}
+	return path, nil
+}
+
+// removeSocket removes the unix socket of the SLAM process, if there is one.
+func (orbSvc *orbslamService) removeSocket() error {
+	if orbSvc.socketPath == "" {
+		return nil
+	}
+	if err := os.Remove(orbSvc.socketPath); err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "unable to remove unix socket")
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package utils
 
 import (
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"go.viam.com/rdk/spatialmath"
@@ -44,3 +45,84 @@ func CheckQuaternionFromClientAlgo(pose spatialmath.Pose, componentReference str
 	}
 	return nil, "", errors.Errorf("error getting SLAM position: quaternion not given, %v", returnedExt)
 }
+
+// TrackingState is the tracking state of the internal SLAM algorithm.
+type TrackingState string
+
+const (
+	// TrackingStateUnknown is used when the internal SLAM algorithm did not report a tracking state.
+	TrackingStateUnknown TrackingState = ""
+	// TrackingStateNotInitialized means no map has been initialized yet.
+	TrackingStateNotInitialized TrackingState = "not_initialized"
+	// TrackingStateOK means the pose is being updated with every processed frame.
+	TrackingStateOK TrackingState = "ok"
+	// TrackingStateRecentlyLost means tracking was lost recently and the algorithm is trying to relocalize.
+	TrackingStateRecentlyLost TrackingState = "recently_lost"
+	// TrackingStateLost means tracking was lost and the pose is no longer being updated.
+	TrackingStateLost TrackingState = "lost"
+)
+
+// TrackingInfo describes the tracking state of the internal SLAM algorithm and how stale its pose is.
+type TrackingInfo struct {
+	State TrackingState
+	// PoseAge is the time since the frame the pose was computed from was captured, if known.
+	PoseAge *time.Duration
+	// TimeSinceLastOK is the time since tracking was last OK, if tracking has ever been OK.
+	TimeSinceLastOK *time.Duration
+}
+
+// Map converts the tracking info into a map that can be returned from DoCommand.
+func (info TrackingInfo) Map() map[string]interface{} {
+	m := map[string]interface{}{"tracking_state": string(info.State)}
+	if info.PoseAge != nil {
+		m["pose_age_sec"] = info.PoseAge.Seconds()
+	}
+	if info.TimeSinceLastOK != nil {
+		m["time_since_last_ok_sec"] = info.TimeSinceLastOK.Seconds()
+	}
+	return m
+}
+
+// CheckTrackingInfoFromClientAlgo reads the tracking state, pose age and time since tracking was last OK sent by
+// the internal SLAM algorithm. All fields are optional, but an error is returned if one is given in an invalid format.
+func CheckTrackingInfoFromClientAlgo(returnedExt map[string]interface{}) (TrackingInfo, error) {
+	var info TrackingInfo
+	if val, ok := returnedExt["tracking_state"]; ok {
+		state, ok := val.(string)
+		if !ok {
+			return TrackingInfo{}, errors.Errorf("error getting SLAM tracking state: invalid format detected, %v", val)
+		}
+		info.State = TrackingState(state)
+	}
+
+	for key, dst := range map[string]**time.Duration{
+		"pose_age_sec":           &info.PoseAge,
+		"time_since_last_ok_sec": &info.TimeSinceLastOK,
+	} {
+		val, ok := returnedExt[key]
+		if !ok {
+			continue
+		}
+		sec, ok := val.(float64)
+		if !ok {
+			return TrackingInfo{}, errors.Errorf("error getting SLAM tracking state: %v given, but invalid format detected, %v", key, val)
+		}
+		d := time.Duration(sec * float64(time.Second))
+		*dst = &d
+	}
+	return info, nil
+}
+
+// CheckMapVersionFromClientAlgo reads the version of the point cloud map sent by the internal SLAM algorithm, which
+// changes every time the map does. The version is optional, so false is returned if it was not sent.
+func CheckMapVersionFromClientAlgo(returnedExt map[string]interface{}) (int64, bool, error) {
+	val, ok := returnedExt["map_version"]
+	if !ok {
+		return 0, false, nil
+	}
+	version, ok := val.(float64)
+	if !ok {
+		return 0, false, errors.Errorf("error getting SLAM map version: invalid format detected, %v", val)
+	}
+	return int64(version), true, nil
+}
@@ -5,6 +5,7 @@ import (
 	"sort"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/golang/geo/r3"
 	"go.viam.com/rdk/spatialmath"
@@ -87,3 +88,62 @@ func TestBuiltinQuaternion(t *testing.T) {
 		test.That(t, componentRef, test.ShouldBeEmpty)
 	})
 }
+
+func TestTrackingInfo(t *testing.T) {
+	t.Run("test tracking info with all fields given", func(t *testing.T) {
+		info, err := CheckTrackingInfoFromClientAlgo(map[string]interface{}{
+			"tracking_state":         "recently_lost",
+			"pose_age_sec":           1.5,
+			"time_since_last_ok_sec": 0.25,
+		})
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, info.State, test.ShouldEqual, TrackingStateRecentlyLost)
+		test.That(t, *info.PoseAge, test.ShouldEqual, 1500*time.Millisecond)
+		test.That(t, *info.TimeSinceLastOK, test.ShouldEqual, 250*time.Millisecond)
+		test.That(t, info.Map(), test.ShouldResemble, map[string]interface{}{
+			"tracking_state":         "recently_lost",
+			"pose_age_sec":           1.5,
+			"time_since_last_ok_sec": 0.25,
+		})
+	})
+
+	t.Run("test tracking info fields are optional", func(t *testing.T) {
+		info, err := CheckTrackingInfoFromClientAlgo(map[string]interface{}{"tracking_state": "not_initialized"})
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, info.State, test.ShouldEqual, TrackingStateNotInitialized)
+		test.That(t, info.PoseAge, test.ShouldBeNil)
+		test.That(t, info.TimeSinceLastOK, test.ShouldBeNil)
+
+		info, err = CheckTrackingInfoFromClientAlgo(map[string]interface{}{})
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, info.State, test.ShouldEqual, TrackingStateUnknown)
+	})
+
+	t.Run("test failure due to invalid tracking info format", func(t *testing.T) {
+		_, err := CheckTrackingInfoFromClientAlgo(map[string]interface{}{"tracking_state": 2.})
+		test.That(t, err.Error(), test.ShouldContainSubstring, "error getting SLAM tracking state: invalid format detected")
+
+		_, err = CheckTrackingInfoFromClientAlgo(map[string]interface{}{"pose_age_sec": "old"})
+		test.That(t, err.Error(), test.ShouldContainSubstring, "pose_age_sec given, but invalid format detected")
+	})
+}
+
+func TestMapVersion(t *testing.T) {
+	t.Run("test map version given", func(t *testing.T) {
+		version, ok, err := CheckMapVersionFromClientAlgo(map[string]interface{}{"map_version": 12.})
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, ok, test.ShouldBeTrue)
+		test.That(t, version, test.ShouldEqual, 12)
+	})
+
+	t.Run("test map version not given", func(t *testing.T) {
+		_, ok, err := CheckMapVersionFromClientAlgo(map[string]interface{}{})
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, ok, test.ShouldBeFalse)
+	})
+
+	t.Run("test failure due to invalid map version format", func(t *testing.T) {
+		_, _, err := CheckMapVersionFromClientAlgo(map[string]interface{}{"map_version": "12"})
+		test.That(t, err.Error(), test.ShouldContainSubstring, "error getting SLAM map version: invalid format detected")
+	})
+}
@@ -11,6 +11,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/edaniels/golog"
@@ -18,6 +19,7 @@ import (
 	"go.opencensus.io/trace"
 	pb "go.viam.com/api/service/slam/v1"
 	"go.viam.com/rdk/components/camera"
+	"go.viam.com/rdk/components/movementsensor"
 	"go.viam.com/rdk/resource"
 	"go.viam.com/rdk/rimage/transform"
 	"go.viam.com/rdk/services/slam"
@@ -26,10 +28,24 @@ import (
 	goutils "go.viam.com/utils"
 	"go.viam.com/utils/pexec"
 	"golang.org/x/exp/slices"
+	"google.golang.org/grpc"
 
 	orbSlamConfig "github.com/viamrobotics/viam-orb-slam3/config"
 	"github.com/viamrobotics/viam-orb-slam3/dataprocess"
+	"github.com/viamrobotics/viam-orb-slam3/flight"
+	"github.com/viamrobotics/viam-orb-slam3/frames"
+	"github.com/viamrobotics/viam-orb-slam3/gravity"
+	"github.com/viamrobotics/viam-orb-slam3/mapdelta"
+	"github.com/viamrobotics/viam-orb-slam3/mapmeta"
+	"github.com/viamrobotics/viam-orb-slam3/mapupload"
+	"github.com/viamrobotics/viam-orb-slam3/metrics"
+	"github.com/viamrobotics/viam-orb-slam3/occupancy"
+	"github.com/viamrobotics/viam-orb-slam3/posehistory"
+	"github.com/viamrobotics/viam-orb-slam3/readiness"
+	"github.com/viamrobotics/viam-orb-slam3/scale"
 	orbSlamSensorUtils "github.com/viamrobotics/viam-orb-slam3/sensors/utils"
+	"github.com/viamrobotics/viam-orb-slam3/slamclient"
+	"github.com/viamrobotics/viam-orb-slam3/slamlog"
 	orbSlamUtils "github.com/viamrobotics/viam-orb-slam3/utils"
 )
 
@@ -45,10 +61,17 @@ const (
 	defaultDataRateMsec         = 200
 	defaultMapRateSec           = 60
 	cameraValidationIntervalSec = 1.
-	parsePortMaxTimeoutSec      = 60
+	readyMaxTimeoutSec          = 60
+	readyPollInterval           = 100 * time.Millisecond
+	defaultExtrapolationLimitMs = 500
+	defaultPositionCacheTTLMs   = 20
+	sharedRequestTimeoutSec     = 30
+	poseHistorySize             = 5
+	defaultBaseFrame            = "base"
 	// time format for the slam service.
 	opTimeoutErrorMessage = "bad scan: OpTimeout"
 	localhost0            = "localhost:0"
+	readyFileName         = "orbslam_ready.json"
 	// DefaultExecutableName is what this program expects to call to start the grpc server.
 	DefaultExecutableName = "orb_grpc_server"
 )
@@ -151,25 +174,120 @@ type orbslamService struct {
 	clientAlgo        pb.SLAMServiceClient
 	clientAlgoClose   func() error
 
+	sensors             []string
 	configParams        map[string]string
 	dataDirectory       string
 	deleteProcessedData bool
 	useLiveData         bool
 
+	// port is the address the SLAM process is told to listen on. The port it picks for localhost:0 is only known from
+	// its ready file.
 	port       string
 	dataRateMs int
 	mapRateSec int
-
+	// remoteSLAMServer is set when port is the address of an externally managed SLAM server.
+	remoteSLAMServer bool
+	// socketPath is the unix socket the SLAM process listens on, if it does not use a TCP port.
+	socketPath string
+	// readyFilePath is the file the SLAM process reports its address in, or empty for an externally managed server.
+	readyFilePath string
+
+	// closeCtx is canceled by cancelFunc once the service is closed.
+	closeCtx                context.Context
 	cancelFunc              func()
 	logger                  golog.Logger
 	activeBackgroundWorkers sync.WaitGroup
-
+	stats                   *metrics.Stats
+
+	trackingMu   sync.Mutex
+	trackingInfo orbSlamUtils.TrackingInfo
+
+	positionCache   *flight.Group[struct{}, slamPosition]
+	pointCloudCache *flight.Group[pointCloudKey, []byte]
+	// processedMapCache caches the point cloud map once transformed and filtered.
+	processedMapCache *flight.Group[processedPointCloudKey, []byte]
+	mapDeltas         *mapdelta.History[pointTransform]
+	// slamEpoch counts the starts of the SLAM process, whose map versions restart from zero every time.
+	slamEpoch atomic.Int64
+
+	// slamProcessMu guards slamProcess, which is replaced when the SLAM process is restarted with an uploaded map.
+	slamProcessMu sync.Mutex
+	// primaryCamera is the camera the .yaml config is generated from, or nil if no camera is used.
+	primaryCamera     camera.Camera
+	uploadMu          sync.Mutex
+	mapUpload         *mapupload.Upload
+	mapUploadMetadata *mapmeta.Metadata
+
+	// filtersConfig holds the filters of the point_cloud_filters attribute, which requests may replace.
+	filtersConfig *orbSlamConfig.FiltersConfig
+
+	occupancyConfig *orbSlamConfig.OccupancyConfig
+	// trajectory is the path of the camera in the map frame, in SLAM units.
+	trajectory *occupancy.Trajectory
+
+	poseHistory          *posehistory.History
+	extrapolationLimitMs int
+
+	odometry       movementsensor.MovementSensor
+	scaleEstimator *scale.Estimator
+
+	gravityAlignmentMode string
+	imu                  movementsensor.MovementSensor
+	gravityMu            sync.Mutex
+	gravity              *gravity.Alignment
+
+	poseTransform *frames.Transform
+	// poseFrame is the frame poses are reported in, or empty if they are reported in the camera frame.
+	poseFrame string
+
+	// metadataMu guards what is recorded in the metadata of saved maps.
+	metadataMu       sync.Mutex
+	mapCamera        *mapmeta.Camera
+	vocabularySHA256 string
+	vocabularyErr    error
+	orbslamVersion   string
+	mapCompatibility string
+
+	// vocabularyMu serializes checks of the vocabulary and guards the file the last check was of.
+	vocabularyMu       sync.Mutex
+	vocabularyChecked  bool
+	vocabularyFileInfo vocabularyFileInfo
+
+	// vocabularyFile is the configured vocabulary, or empty to use the one in the data directory.
+	vocabularyFile      string
+	expectedVocabSHA256 string
+
+	// offlineDone is closed once the SLAM process has processed all data in the data directory in offline mode and, if
+	// it saves maps, saved the final map.
+	offlineDone     chan struct{}
+	offlineDoneOnce sync.Once
+
+	logParser                    *slamlog.Parser
 	bufferSLAMProcessLogs        bool
 	slamProcessLogReader         io.ReadCloser
 	slamProcessLogWriter         io.WriteCloser
 	slamProcessBufferedLogReader bufio.Reader
 }
 
+// configurePoseTransform returns the transform from the camera frame to the frame poses are reported in, along with
+// the name of that frame. The name is empty if poses are reported in the camera frame.
+func configurePoseTransform(svcConfig *orbSlamConfig.Config) (*frames.Transform, string, error) {
+	var extrinsic spatialmath.Pose
+	var poseFrame string
+	if svcConfig.CameraExtrinsic != nil {
+		extrinsic = svcConfig.CameraExtrinsic.Pose()
+		poseFrame = svcConfig.BaseFrame
+		if poseFrame == "" {
+			poseFrame = defaultBaseFrame
+		}
+	}
+	transform, err := frames.NewTransform(extrinsic, frames.AxisConvention(svcConfig.AxisConvention))
+	if err != nil {
+		return nil, "", errors.Wrap(err, "error configuring pose transform")
+	}
+	return transform, poseFrame, nil
+}
+
 // configureCameras will check the config to see if any cameras are desired and if so, grab the cameras from
 // the robot. We assume there are at most two cameras and that we only require intrinsics from the first one.
 // Returns the name of the first camera.
@@ -242,17 +360,128 @@ func (orbSvc *orbslamService) GetPosition(ctx context.Context) (spatialmath.Pose
 	ctx, span := trace.StartSpan(ctx, "viamorbslam3::orbslamService::GetPosition")
 	defer span.End()
 
+	start := time.Now()
+	defer func() {
+		orbSvc.stats.RecordGetPositionLatency(ctx, time.Since(start))
+	}()
+
+	position, err := orbSvc.getSLAMPosition(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	// The config only allows a camera extrinsic translation, which is in mm, for poses reported in mm.
+	mmPerUnit, err := orbSvc.outputScale()
+	if err != nil {
+		return nil, "", err
+	}
+	pose := orbSvc.poseTransform.Apply(orbSvc.applyGravityAlignment(scalePose(position.pose, mmPerUnit)))
+	componentReference := position.componentReference
+	if orbSvc.poseFrame != "" {
+		componentReference = orbSvc.poseFrame
+	}
+	orbSvc.recordPose(position.receivedAt, pose, position.trackingInfo)
+	return pose, componentReference, nil
+}
+
+// slamPosition is a response of the slam library's GetPosition endpoint.
+type slamPosition struct {
+	// pose is in the units of the slam library and in the camera frame.
+	pose               spatialmath.Pose
+	componentReference string
+	trackingInfo       orbSlamUtils.TrackingInfo
+	// mapVersion is the version of the point cloud map, or nil if the slam library does not report it.
+	mapVersion *int64
+	receivedAt time.Time
+}
+
+// getSLAMPosition returns the position reported by the slam library. Concurrent calls share a single request, and
+// its response is reused for a short time.
+func (orbSvc *orbslamService) getSLAMPosition(ctx context.Context) (slamPosition, error) {
+	return orbSvc.positionCache.Do(ctx, struct{}{}, func() (slamPosition, error) {
+		sharedCtx, cancel := sharedRequestContext()
+		defer cancel()
+		return orbSvc.requestSLAMPosition(sharedCtx)
+	})
+}
+
+// sharedRequestContext returns the context of a request shared by concurrent calls, which must not be canceled when
+// the call that happened to start it gives up.
+func sharedRequestContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), sharedRequestTimeoutSec*time.Second)
+}
+
+// requestSLAMPosition requests the position from the slam library.
+func (orbSvc *orbslamService) requestSLAMPosition(ctx context.Context) (slamPosition, error) {
 	req := &pb.GetPositionRequest{Name: orbSvc.Name().ShortName()}
 
 	resp, err := orbSvc.clientAlgo.GetPosition(ctx, req)
 	if err != nil {
-		return nil, "", errors.Wrap(err, "error getting SLAM position")
+		return slamPosition{}, errors.Wrap(err, "error getting SLAM position")
 	}
 	pose := spatialmath.NewPoseFromProtobuf(resp.GetPose())
 	componentReference := resp.GetComponentReference()
 	returnedExt := resp.Extra.AsMap()
+	position := slamPosition{receivedAt: time.Now()}
+
+	// The tracking info and map version are optional, so malformed ones must not prevent the pose from being
+	// returned.
+	info, err := orbSlamUtils.CheckTrackingInfoFromClientAlgo(returnedExt)
+	if err != nil {
+		orbSvc.logger.Debugw("error reading SLAM tracking state", "error", err)
+	} else {
+		orbSvc.updateTrackingInfo(info)
+		position.trackingInfo = info
+	}
+	if version, ok, err := orbSlamUtils.CheckMapVersionFromClientAlgo(returnedExt); err != nil {
+		orbSvc.logger.Debugw("error reading SLAM map version", "error", err)
+	} else if ok {
+		position.mapVersion = &version
+	}
+
+	position.pose, position.componentReference, err = orbSlamUtils.CheckQuaternionFromClientAlgo(pose,
+		componentReference, returnedExt)
+	if err != nil {
+		return slamPosition{}, err
+	}
+	return position, nil
+}
+
+// recordPose adds the pose to the pose history, timestamped with the time of the frame it was computed from.
+func (orbSvc *orbslamService) recordPose(receivedAt time.Time, pose spatialmath.Pose, info orbSlamUtils.TrackingInfo) {
+	switch info.State {
+	case orbSlamUtils.TrackingStateOK, orbSlamUtils.TrackingStateUnknown:
+	case orbSlamUtils.TrackingStateNotInitialized, orbSlamUtils.TrackingStateRecentlyLost, orbSlamUtils.TrackingStateLost:
+		// The pose is not being updated, so the velocity between it and the next pose would be meaningless.
+		orbSvc.poseHistory.Reset()
+		return
+	}
+	frameTime := receivedAt
+	if info.PoseAge != nil {
+		frameTime = receivedAt.Add(-*info.PoseAge)
+	}
+	orbSvc.poseHistory.Add(frameTime, pose)
+}
 
-	return orbSlamUtils.CheckQuaternionFromClientAlgo(pose, componentReference, returnedExt)
+// updateTrackingInfo stores the latest tracking info and warns when tracking is lost, since the returned pose
+// then stops being updated.
+func (orbSvc *orbslamService) updateTrackingInfo(info orbSlamUtils.TrackingInfo) {
+	orbSvc.trackingMu.Lock()
+	prev := orbSvc.trackingInfo
+	orbSvc.trackingInfo = info
+	orbSvc.trackingMu.Unlock()
+
+	if prev.State == info.State {
+		return
+	}
+	switch info.State {
+	case orbSlamUtils.TrackingStateRecentlyLost, orbSlamUtils.TrackingStateLost:
+		orbSvc.logger.Warnw("SLAM tracking lost, the returned pose is stale", "tracking_state", info.State)
+	case orbSlamUtils.TrackingStateOK:
+		if prev.State != orbSlamUtils.TrackingStateUnknown {
+			orbSvc.logger.Infow("SLAM tracking is OK", "previous_tracking_state", prev.State)
+		}
+	case orbSlamUtils.TrackingStateUnknown, orbSlamUtils.TrackingStateNotInitialized:
+	}
 }
 
 // GetPointCloudMap creates a request, calls the slam algorithms GetPointCloudMap endpoint and returns a callback
@@ -261,7 +490,29 @@ func (orbSvc *orbslamService) GetPointCloudMap(ctx context.Context) (func() ([]b
 	ctx, span := trace.StartSpan(ctx, "viamorbslam3::orbslamService::GetPointCloudMap")
 	defer span.End()
 
-	return grpchelper.GetPointCloudMapCallback(ctx, orbSvc.Name().ShortName(), orbSvc.clientAlgo)
+	start := time.Now()
+
+	// The point cloud map is only requested on the first call of the callback, so that errors are returned from the
+	// callback like they are when it is streamed directly.
+	var callback func() ([]byte, error)
+	// The latency covers the whole stream, so it is recorded once the stream ends.
+	var recorded bool
+	return func() ([]byte, error) {
+		if callback == nil {
+			data, err := orbSvc.processedPointCloudMapData(ctx, orbSvc.filtersConfig)
+			if err != nil {
+				callback = func() ([]byte, error) { return nil, err }
+			} else {
+				callback = chunkCallback(data)
+			}
+		}
+		chunk, err := callback()
+		if err != nil && !recorded {
+			recorded = true
+			orbSvc.stats.RecordGetPointCloudMapLatency(ctx, time.Since(start))
+		}
+		return chunk, err
+	}, nil
 }
 
 // GetInternalState creates a request, calls the slam algorithms GetInternalState endpoint and returns a callback
@@ -289,6 +540,10 @@ func New(ctx context.Context,
 		return nil, err
 	}
 
+	if err := metrics.RegisterViews(); err != nil {
+		return nil, errors.Wrap(err, "unable to register slam service stats")
+	}
+
 	primarySensorName, cams, err := configureCameras(ctx, svcConfig, deps, logger)
 	if err != nil {
 		return nil, errors.Wrap(err, "configuring camera error")
@@ -331,6 +586,55 @@ func New(ctx context.Context,
 		return nil, err
 	}
 
+	var socketPath string
+	if svcConfig.Transport == TransportUnix {
+		if socketPath, err = unixSocketPath(svcConfig.DataDirectory); err != nil {
+			return nil, err
+		}
+		port = "unix:" + socketPath
+	}
+
+	var dialOptions []grpc.DialOption
+	if svcConfig.SLAMServer != nil {
+		port = svcConfig.SLAMServer.Address
+		if dialOptions, err = svcConfig.SLAMServer.DialOptions(); err != nil {
+			return nil, errors.Wrap(err, "error configuring slam_server credentials")
+		}
+	}
+
+	// The SLAM process reports the address it listens on, its version and the modes it supports in a ready file.
+	var readyFilePath string
+	if svcConfig.SLAMServer == nil {
+		readyFilePath = filepath.Join(svcConfig.DataDirectory, readyFileName)
+	}
+
+	positionCacheTTLMs := svcConfig.PositionCacheTTLMsec
+	if positionCacheTTLMs == 0 {
+		positionCacheTTLMs = defaultPositionCacheTTLMs
+		logger.Debugf("no position_cache_ttl_msec given, setting to default value of %d", defaultPositionCacheTTLMs)
+	}
+
+	extrapolationLimitMs := svcConfig.ExtrapolationLimitMsec
+	if extrapolationLimitMs == 0 {
+		extrapolationLimitMs = defaultExtrapolationLimitMs
+		logger.Debugf("no extrapolation_limit_msec given, setting to default value of %d", defaultExtrapolationLimitMs)
+	}
+
+	poseTransform, poseFrame, err := configurePoseTransform(svcConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	odometry, err := configureOdometry(svcConfig, subAlgo, deps)
+	if err != nil {
+		return nil, err
+	}
+
+	imu, err := configureIMU(svcConfig, deps)
+	if err != nil {
+		return nil, err
+	}
+
 	// 'ctx' is the Context of a gRPC call, so use a new Context for anything that will outlive the gRPC call.
 	cancelCtx, cancelFunc := context.WithCancel(context.Background())
 
@@ -341,16 +645,46 @@ func New(ctx context.Context,
 		subAlgo:               subAlgo,
 		executableName:        executableName,
 		slamProcess:           pexec.NewProcessManager(logger),
+		sensors:               svcConfig.Sensors,
+		mapCompatibility:      svcConfig.MapCompatibility,
+		vocabularyFile:        svcConfig.VocabularyPath,
+		expectedVocabSHA256:   svcConfig.VocabularySHA256,
 		configParams:          svcConfig.ConfigParams,
 		dataDirectory:         svcConfig.DataDirectory,
 		useLiveData:           useLiveData,
 		deleteProcessedData:   deleteProcessedData,
 		port:                  port,
+		remoteSLAMServer:      svcConfig.SLAMServer != nil,
+		socketPath:            socketPath,
+		readyFilePath:         readyFilePath,
+		filtersConfig:         svcConfig.PointCloudFilters,
+		occupancyConfig:       svcConfig.OccupancyGrid,
+		trajectory:            occupancy.NewTrajectory(maxTrajectoryPositions),
 		dataRateMs:            dataRateMsec,
 		mapRateSec:            mapRateSec,
 		cancelFunc:            cancelFunc,
+		closeCtx:              cancelCtx,
 		logger:                logger,
+		logParser:             slamlog.NewParser(logger),
+		offlineDone:           make(chan struct{}),
 		bufferSLAMProcessLogs: bufferSLAMProcessLogs,
+		stats:                 metrics.NewStats(),
+		positionCache:         flight.NewGroup[struct{}, slamPosition](time.Duration(positionCacheTTLMs)*time.Millisecond, 1),
+		pointCloudCache:       flight.NewGroup[pointCloudKey, []byte](pointCloudCacheTTLSec*time.Second, 1),
+		processedMapCache:     flight.NewGroup[processedPointCloudKey, []byte](pointCloudCacheTTLSec*time.Second, processedMapCacheSize),
+		mapDeltas:             mapdelta.NewHistory[pointTransform](mapDeltaHistorySize),
+		poseHistory:           posehistory.New(poseHistorySize),
+		extrapolationLimitMs:  extrapolationLimitMs,
+		poseTransform:         poseTransform,
+		poseFrame:             poseFrame,
+		odometry:              odometry,
+		scaleEstimator:        scale.NewEstimator(scaleMaxSamples),
+		gravityAlignmentMode:  svcConfig.GravityAlignment,
+		imu:                   imu,
+	}
+
+	if len(cams) > 0 {
+		orbSvc.primaryCamera = cams[0]
 	}
 
 	var success bool
@@ -362,22 +696,55 @@ func New(ctx context.Context,
 		}
 	}()
 
-	if err := runtimeServiceValidation(cancelCtx, cams, orbSvc); err != nil {
-		return nil, errors.Wrap(err, "runtime slam service error")
+	orbSvc.logParser.Subscribe(orbSvc.handleSLAMEvent)
+	orbSvc.logParser.Subscribe(orbSvc.handleOfflineDone)
+	orbSvc.startVocabularyChecksum()
+
+	if err := orbSvc.loadScale(); err != nil {
+		return nil, err
+	}
+
+	if err := orbSvc.loadGravityAlignment(); err != nil {
+		return nil, err
+	}
+
+	if orbSvc.imu != nil {
+		if err := orbSvc.alignWithIMU(ctx); err != nil {
+			return nil, errors.Wrap(err, "error aligning map with gravity")
+		}
 	}
 
-	orbSvc.StartDataProcess(cancelCtx, cams, nil)
+	// An externally managed SLAM server may run on another machine, so it reads the frames, .yaml config and maps in
+	// its own data directory rather than the ones of this service.
+	if orbSvc.remoteSLAMServer {
+		logger.Infof("using the SLAM server at %v instead of starting one", orbSvc.port)
+	} else {
+		if err := runtimeServiceValidation(cancelCtx, cams, orbSvc); err != nil {
+			return nil, errors.Wrap(err, "runtime slam service error")
+		}
+
+		orbSvc.StartDataProcess(cancelCtx, cams, nil)
 
-	if err := orbSvc.StartSLAMProcess(ctx); err != nil {
-		return nil, errors.Wrap(err, "error with slam service slam process")
+		if err := orbSvc.StartSLAMProcess(ctx); err != nil {
+			return nil, errors.Wrap(err, "error with slam service slam process")
+		}
 	}
 
-	client, clientClose, err := orbSlamConfig.SetupGRPCConnection(ctx, orbSvc.port, dialMaxTimeoutSec, logger)
+	// The client reconnects on its own if the connection to the SLAM process is lost, e.g. when it restarts.
+	client, err := slamclient.New(ctx, slamclient.Options{
+		Target:      orbSvc.slamTarget,
+		DialTimeout: time.Duration(dialMaxTimeoutSec) * time.Second,
+		DialOptions: dialOptions,
+	}, logger)
 	if err != nil {
 		return nil, errors.Wrap(err, "error with initial grpc client to slam algorithm")
 	}
 	orbSvc.clientAlgo = client
-	orbSvc.clientAlgoClose = clientClose
+	orbSvc.clientAlgoClose = client.Close
+
+	orbSvc.startScaleRecovery(cancelCtx)
+	orbSvc.startFloorPlaneAlignment(cancelCtx)
+	orbSvc.startTrajectoryRecording(cancelCtx)
 
 	success = true
 	return orbSvc, nil
@@ -392,6 +759,7 @@ func (orbSvc *orbslamService) Close(ctx context.Context) error {
 	}()
 	orbSvc.cancelFunc()
 	if orbSvc.bufferSLAMProcessLogs {
+		orbSvc.logParser.SetDownstream(nil)
 		if orbSvc.slamProcessLogReader != nil {
 			if err := orbSvc.slamProcessLogReader.Close(); err != nil {
 				return errors.Wrap(err, "error occurred during closeout of slam log reader")
@@ -403,9 +771,20 @@ func (orbSvc *orbslamService) Close(ctx context.Context) error {
 			}
 		}
 	}
+	orbSvc.uploadMu.Lock()
+	orbSvc.abortMapUpload()
+	orbSvc.uploadMu.Unlock()
 	if err := orbSvc.StopSLAMProcess(); err != nil {
 		return errors.Wrap(err, "error occurred during closeout of process")
 	}
+	if err := orbSvc.removeSocket(); err != nil {
+		return errors.Wrap(err, "error occurred during closeout of slam process socket")
+	}
+	if orbSvc.readyFilePath != "" {
+		if err := readiness.Remove(orbSvc.readyFilePath); err != nil {
+			return errors.Wrap(err, "error occurred during closeout of slam process ready file")
+		}
+	}
 	orbSvc.activeBackgroundWorkers.Wait()
 	return nil
 }
@@ -454,8 +833,10 @@ func (orbSvc *orbslamService) StartDataProcess(
 					orbSvc.activeBackgroundWorkers.Done()
 					return
 				}
+				orbSvc.stats.GoroutineStarted(cancelCtx)
 				goutils.PanicCapturingGo(func() {
 					defer orbSvc.activeBackgroundWorkers.Done()
+					defer orbSvc.stats.GoroutineFinished(cancelCtx)
 					if _, err := orbSvc.getAndSaveData(cancelCtx, cams); err != nil {
 						orbSvc.logger.Warn(err)
 					}
@@ -480,6 +861,12 @@ func (orbSvc *orbslamService) GetSLAMProcessConfig() pexec.ProcessConfig {
 	args = append(args, "-delete_processed_data="+strconv.FormatBool(orbSvc.deleteProcessedData))
 	args = append(args, "-use_live_data="+strconv.FormatBool(orbSvc.useLiveData))
 	args = append(args, "-port="+orbSvc.port)
+	if orbSvc.vocabularyFile != "" {
+		args = append(args, "-vocab_path="+orbSvc.vocabularyFile)
+	}
+	if orbSvc.readyFilePath != "" {
+		args = append(args, "-ready_file="+orbSvc.readyFilePath)
+	}
 	args = append(args, "--aix-auto-update")
 
 	target := orbSvc.executableName
@@ -496,12 +883,20 @@ func (orbSvc *orbslamService) GetSLAMProcessConfig() pexec.ProcessConfig {
 		ID:   "slam_orbslamv3",
 		Name: target,
 		Args: args,
-		Log:  true,
+		// The log parser logs every line of the process at the level matching its severity.
+		Log:       false,
+		LogWriter: orbSvc.logParser,
 		// In appimage this is set to the appimage
 		// squashfs mount location (/tmp/.mountXXXXX)
 		// Otherwise, it is an empty string
 		CWD:     os.Getenv("APPRUN_RUNTIME"),
 		OneShot: false,
+		OnUnexpectedExit: func(exitCode int) bool {
+			orbSvc.logger.Warnw("slam process exited unexpectedly, restarting", "exit_code", exitCode)
+			orbSvc.stats.RecordSLAMProcessRestart(context.Background())
+			orbSvc.startSLAMEpoch()
+			return true
+		},
 	}
 }
 
@@ -514,15 +909,26 @@ func (orbSvc *orbslamService) StartSLAMProcess(ctx context.Context) error {
 	ctx, span := trace.StartSpan(ctx, "viamorbslam3::orbslamService::StartSLAMProcess")
 	defer span.End()
 
+	if err := orbSvc.validateVocabulary(); err != nil {
+		return err
+	}
+
 	processConfig := orbSvc.GetSLAMProcessConfig()
 
+	if orbSvc.readyFilePath != "" {
+		// A ready file left behind by a previous process would report a stale address.
+		if err := readiness.Remove(orbSvc.readyFilePath); err != nil {
+			return errors.Wrap(err, "problem removing stale slam process ready file")
+		}
+	}
+
 	var logReader io.ReadCloser
 	var logWriter io.WriteCloser
 	var bufferedLogReader bufio.Reader
-	if orbSvc.port == localhost0 || orbSvc.bufferSLAMProcessLogs {
+	if orbSvc.bufferSLAMProcessLogs {
 		logReader, logWriter = io.Pipe()
 		bufferedLogReader = *bufio.NewReader(logReader)
-		processConfig.LogWriter = logWriter
+		orbSvc.logParser.SetDownstream(logWriter)
 	}
 
 	_, err := orbSvc.slamProcess.AddProcessFromConfig(ctx, processConfig)
@@ -531,47 +937,26 @@ func (orbSvc *orbslamService) StartSLAMProcess(ctx context.Context) error {
 	}
 
 	orbSvc.logger.Debug("starting slam process")
+	orbSvc.startSLAMEpoch()
 
 	if err = orbSvc.slamProcess.Start(ctx); err != nil {
 		return errors.Wrap(err, "problem starting slam process")
 	}
 
-	if orbSvc.port == localhost0 {
-		timeoutCtx, timeoutCancel := context.WithTimeout(ctx, parsePortMaxTimeoutSec*time.Second)
-		defer timeoutCancel()
-
-		if !orbSvc.bufferSLAMProcessLogs {
-			defer func(logger golog.Logger) {
-				if err := logReader.Close(); err != nil {
-					logger.Debugw("Closing logReader returned an error", "error", err)
-				}
-			}(orbSvc.logger)
-			defer func(logger golog.Logger) {
-				if err := logWriter.Close(); err != nil {
-					logger.Debugw("Closing logReader returned an error", "error", err)
-				}
-			}(orbSvc.logger)
+	if orbSvc.readyFilePath != "" {
+		info, err := readiness.Wait(ctx, orbSvc.readyFilePath, readyMaxTimeoutSec*time.Second, readyPollInterval)
+		if err != nil {
+			return errors.Wrap(err, "error getting address from slam process")
 		}
-
-		for {
-			if err := timeoutCtx.Err(); err != nil {
-				return errors.Wrapf(err, "error getting port from slam process")
-			}
-
-			line, err := bufferedLogReader.ReadString('\n')
-			if err != nil {
-				return errors.Wrapf(err, "error getting port from slam process")
-			}
-			portLogLinePrefix := "Server listening on "
-			if strings.Contains(line, portLogLinePrefix) {
-				linePieces := strings.Split(line, portLogLinePrefix)
-				if len(linePieces) != 2 {
-					return errors.Errorf("failed to parse port from slam process log line: %v", line)
-				}
-				orbSvc.port = "localhost:" + strings.TrimRight(linePieces[1], "\n")
-				break
-			}
+		if !info.SupportsMode(string(orbSvc.subAlgo)) {
+			return errors.Errorf("slam process version %v does not support mode %v, supported modes are %v",
+				info.Version, orbSvc.subAlgo, info.Modes)
 		}
+		orbSvc.logger.Infow("slam process is listening",
+			"address", info.Address, "version", info.Version, "status", info.Status, "map_loaded", info.MapLoaded)
+		orbSvc.metadataMu.Lock()
+		orbSvc.orbslamVersion = info.ORBSLAMVersion
+		orbSvc.metadataMu.Unlock()
 	}
 
 	if orbSvc.bufferSLAMProcessLogs {
@@ -583,8 +968,23 @@ func (orbSvc *orbslamService) StartSLAMProcess(ctx context.Context) error {
 	return nil
 }
 
+// slamTarget returns the address of the SLAM process. When the SLAM process reports its address in a ready file, the
+// file is read every time, since a restarted process may listen on a different address.
+func (orbSvc *orbslamService) slamTarget() (string, error) {
+	if orbSvc.readyFilePath == "" {
+		return orbSvc.port, nil
+	}
+	info, err := readiness.ReadFile(orbSvc.readyFilePath)
+	if err != nil {
+		return "", errors.Wrap(err, "error reading slam process ready file")
+	}
+	return info.Address, nil
+}
+
 // StopSLAMProcess uses the process manager to stop the created slam process from running.
 func (orbSvc *orbslamService) StopSLAMProcess() error {
+	orbSvc.slamProcessMu.Lock()
+	defer orbSvc.slamProcessMu.Unlock()
 	if err := orbSvc.slamProcess.Stop(); err != nil {
 		return errors.Wrap(err, "problem stopping slam process")
 	}
@@ -606,24 +1006,34 @@ func (orbSvc *orbslamService) getAndSaveData(
 			return nil, errors.Errorf("expected 1 camera for mono slam, found %v", len(cams))
 		}
 
+		encodeStart := time.Now()
 		image, release, err := orbSlamSensorUtils.GetPNGImage(ctx, cams[0])
+		encodeLatency := time.Since(encodeStart)
 		if release != nil {
 			defer release()
 		}
 		if err != nil {
 			if err.Error() == opTimeoutErrorMessage {
 				orbSvc.logger.Warnw("Skipping this scan due to error", "error", err)
+				orbSvc.stats.RecordFrameSkipped(ctx)
 				return nil, nil
 			}
+			orbSvc.stats.RecordFrameFailed(ctx)
 			return nil, err
 		}
+		orbSvc.stats.RecordPNGEncodeLatency(ctx, encodeLatency)
 		filenames, err := createTimestampFilenames(orbSvc.dataDirectory, orbSvc.primarySensorName, ".png", orbSvc.subAlgo)
 		if err != nil {
+			orbSvc.stats.RecordFrameFailed(ctx)
 			return nil, err
 		}
 
 		filename := filenames[0]
-		return []string{filename}, dataprocess.WriteBytesToFile(image, filename)
+		if err := orbSvc.writeImage(ctx, image, filename); err != nil {
+			return []string{filename}, err
+		}
+		orbSvc.stats.RecordFrameCaptured(ctx)
+		return []string{filename}, nil
 	case Rgbd:
 		if len(cams) != 2 {
 			return nil, errors.Errorf("expected 2 cameras for Rgbd slam, found %v", len(cams))
@@ -638,26 +1048,42 @@ func (orbSvc *orbslamService) getAndSaveData(
 		if err != nil {
 			if err.Error() == opTimeoutErrorMessage {
 				orbSvc.logger.Warnw("Skipping this scan due to error", "error", err)
+				orbSvc.stats.RecordFrameSkipped(ctx)
 				return nil, nil
 			}
+			orbSvc.stats.RecordFrameFailed(ctx)
 			return nil, err
 		}
 
 		filenames, err := createTimestampFilenames(orbSvc.dataDirectory, orbSvc.primarySensorName, ".png", orbSvc.subAlgo)
 		if err != nil {
+			orbSvc.stats.RecordFrameFailed(ctx)
 			return nil, err
 		}
 		for i, filename := range filenames {
-			if err = dataprocess.WriteBytesToFile(images[i], filename); err != nil {
+			if err = orbSvc.writeImage(ctx, images[i], filename); err != nil {
 				return filenames, err
 			}
 		}
+		orbSvc.stats.RecordFrameCaptured(ctx)
 		return filenames, nil
 	default:
 		return nil, errors.Errorf("invalid subAlgo %v specified", orbSvc.subAlgo)
 	}
 }
 
+// writeImage writes an image to the given filename, recording the write latency and counting
+// the frame as failed if the write does not succeed.
+func (orbSvc *orbslamService) writeImage(ctx context.Context, image []byte, filename string) error {
+	writeStart := time.Now()
+	err := dataprocess.WriteBytesToFile(image, filename)
+	orbSvc.stats.RecordPNGWriteLatency(ctx, time.Since(writeStart))
+	if err != nil {
+		orbSvc.stats.RecordFrameFailed(ctx)
+	}
+	return err
+}
+
 // getSimultaneousColorAndDepth gets the color and depth images from the cameras as close to simultaneously as possible.
 func (orbSvc *orbslamService) getSimultaneousColorAndDepth(
 	ctx context.Context,
@@ -667,6 +1093,8 @@ func (orbSvc *orbslamService) getSimultaneousColorAndDepth(
 	var images [2][]byte
 	var releaseFuncs [2]func()
 	var errs [2]error
+	var receivedAt [2]time.Time
+	var encodeLatencies [2]time.Duration
 
 	for i := 0; i < 2; i++ {
 		orbSvc.activeBackgroundWorkers.Add(1)
@@ -679,20 +1107,36 @@ func (orbSvc *orbslamService) getSimultaneousColorAndDepth(
 			return images, releaseFuncs, err
 		}
 		iLoop := i
+		orbSvc.stats.GoroutineStarted(ctx)
 		goutils.PanicCapturingGo(func() {
 			defer orbSvc.activeBackgroundWorkers.Done()
 			defer wg.Done()
+			defer orbSvc.stats.GoroutineFinished(ctx)
+			encodeStart := time.Now()
 			images[iLoop], releaseFuncs[iLoop], errs[iLoop] = orbSlamSensorUtils.GetPNGImage(ctx, cams[iLoop])
+			receivedAt[iLoop] = time.Now()
+			encodeLatencies[iLoop] = receivedAt[iLoop].Sub(encodeStart)
 		})
 	}
 	wg.Wait()
 
+	for i, err := range errs {
+		if err == nil {
+			orbSvc.stats.RecordPNGEncodeLatency(ctx, encodeLatencies[i])
+		}
+	}
 	for _, err := range errs {
 		if err != nil {
 			return images, releaseFuncs, err
 		}
 	}
 
+	skew := receivedAt[0].Sub(receivedAt[1])
+	if skew < 0 {
+		skew = -skew
+	}
+	orbSvc.stats.RecordRGBDSkew(ctx, skew)
+
 	return images, releaseFuncs, nil
 }
 
@@ -1,12 +1,17 @@
 // This is an experimental integration of orbslam into RDK.
 #include "orbslam_server_v1.h"
 
+#include <openssl/sha.h>
+
 #include <algorithm>
 #include <cfenv>
+#include <fstream>
+#include <iomanip>
 #define BOOST_NO_CXX11_SCOPED_ENUMS
 #include <boost/filesystem.hpp>
 #include <boost/format.hpp>
 #undef BOOST_NO_CXX11_SCOPED_ENUMS
+#include <boost/algorithm/string/trim.hpp>
 #include <boost/log/core.hpp>
 #include <boost/log/expressions.hpp>
 #include <boost/log/trivial.hpp>
@@ -44,11 +49,24 @@ std::atomic<bool> b_continue_session{true};
 ::grpc::Status SLAMServiceImpl::GetPosition(ServerContext *context,
                                             const GetPositionRequest *request,
                                             GetPositionResponse *response) {
+    auto status = CheckAuthorization(context);
+    if (!status.ok()) return status;
+
     Sophus::SE3f currPose;
-    // Copy pose to new location
+    int currTrackingState;
+    bool hasBeenOk;
+    double currPoseFrameTime;
+    std::chrono::steady_clock::time_point lastOkTime;
+    int currMapVersion;
+    // Copy pose and tracking state to new location
     {
         std::lock_guard<std::mutex> lk(slam_mutex);
         currPose = poseGrpc;
+        currMapVersion = map_version;
+        currTrackingState = tracking_state;
+        hasBeenOk = tracking_has_been_ok;
+        currPoseFrameTime = pose_frame_time;
+        lastOkTime = last_tracking_ok_time;
     }
 
     Pose *responsePose = response->mutable_pose();
@@ -77,6 +95,26 @@ std::atomic<bool> b_continue_session{true};
     q->mutable_fields()->operator[]("jmag").set_number_value(actualPose[1]);
     q->mutable_fields()->operator[]("kmag").set_number_value(actualPose[2]);
 
+    // The pose is only updated while tracking is OK, so report how stale it is
+    extra->mutable_fields()
+        ->operator[]("tracking_state")
+        .set_string_value(utils::TrackingStateToString(currTrackingState));
+    if (hasBeenOk) {
+        extra->mutable_fields()
+            ->operator[]("pose_age_sec")
+            .set_number_value(utils::CurrentTime() - currPoseFrameTime);
+        std::chrono::duration<double> timeSinceLastOk =
+            std::chrono::steady_clock::now() - lastOkTime;
+        extra->mutable_fields()
+            ->operator[]("time_since_last_ok_sec")
+            .set_number_value(timeSinceLastOk.count());
+    }
+
+    // Lets clients cache the point cloud map until it changes
+    extra->mutable_fields()
+        ->operator[]("map_version")
+        .set_number_value(currMapVersion);
+
     response->set_component_reference(camera_name);
 
     return grpc::Status::OK;
@@ -85,6 +123,9 @@ std::atomic<bool> b_continue_session{true};
 ::grpc::Status SLAMServiceImpl::GetPointCloudMap(
     ServerContext *context, const GetPointCloudMapRequest *request,
     ServerWriter<GetPointCloudMapResponse> *writer) {
+    auto status = CheckAuthorization(context);
+    if (!status.ok()) return status;
+
     std::vector<ORB_SLAM3::MapPoint *> actualMap;
     {
         std::lock_guard<std::mutex> lk(slam_mutex);
@@ -122,18 +163,54 @@ std::atomic<bool> b_continue_session{true};
 ::grpc::Status SLAMServiceImpl::GetInternalState(
     ServerContext *context, const GetInternalStateRequest *request,
     ServerWriter<GetInternalStateResponse> *writer) {
-    std::stringbuf buffer;
-    // deferring reading the osa file in chunks until we run into issues
-    // with loading the file into memory
-    bool success = ArchiveSlam(buffer);
-    if (!success)
-        return grpc::Status(grpc::StatusCode::UNAVAILABLE,
-                            "SLAM is not yet initialized");
+    auto status = CheckAuthorization(context);
+    if (!status.ok()) return status;
+
+    const auto &metadata = context->client_metadata();
+    auto metadataValue = [&metadata](const string &key) {
+        auto value = metadata.find(key);
+        if (value == metadata.end()) return string();
+        return string(value->second.data(), value->second.length());
+    };
+
+    // A resumed download continues from the internal state it started from,
+    // as long as it is cached.
+    string sha256 = metadataValue(internalStateSHA256Header);
+    std::shared_ptr<const string> state;
+    if (!sha256.empty()) {
+        state = internal_state_cache.Get(sha256,
+                                         InternalStateCache::Clock::now());
+    }
+    if (!state) {
+        std::stringbuf buffer;
+        // deferring reading the osa file in chunks until we run into issues
+        // with loading the file into memory
+        bool success = ArchiveSlam(buffer);
+        if (!success)
+            return grpc::Status(grpc::StatusCode::UNAVAILABLE,
+                                "SLAM is not yet initialized");
+        state = std::make_shared<const string>(buffer.str());
+        sha256 = utils::SHA256Hex(*state);
+        internal_state_cache.Put(state, sha256,
+                                 InternalStateCache::Clock::now());
+    }
+    const string &buffer_str = *state;
 
     std::string internal_state_chunk;
     GetInternalStateResponse response;
-    std::string buffer_str = buffer.str();
-    for (int start_index = 0; start_index < buffer_str.size();
+
+    // The checksum and size let the client verify the download, and resume
+    // it from an offset if it is interrupted.
+    const size_t offset = utils::InternalStateResumeOffset(
+        metadataValue(internalStateOffsetHeader),
+        metadataValue(internalStateSHA256Header), sha256, buffer_str.size());
+    context->AddInitialMetadata(internalStateSHA256Header, sha256);
+    context->AddInitialMetadata(internalStateSizeHeader,
+                                std::to_string(buffer_str.size()));
+    context->AddInitialMetadata(internalStateOffsetHeader,
+                                std::to_string(offset));
+
+    for (size_t start_index = offset; start_index < buffer_str.size();
          start_index += maximumGRPCByteChunkSize) {
         internal_state_chunk =
             buffer_str.substr(start_index, maximumGRPCByteChunkSize);
@@ -146,6 +223,36 @@ std::atomic<bool> b_continue_session{true};
     return grpc::Status::OK;
 }
 
+std::shared_ptr<const string> InternalStateCache::Get(const string &sha256,
+                                                      Clock::time_point now) {
+    std::lock_guard<std::mutex> lock(mutex);
+    if (!state || sha256 != this->sha256 || now >= expiry) return nullptr;
+    return state;
+}
+
+void InternalStateCache::Put(std::shared_ptr<const string> state,
+                             const string &sha256, Clock::time_point now) {
+    std::lock_guard<std::mutex> lock(mutex);
+    this->state = std::move(state);
+    this->sha256 = sha256;
+    expiry = now + ttl;
+}
+
+::grpc::Status SLAMServiceImpl::CheckAuthorization(ServerContext *context) {
+    if (auth_token.empty()) return grpc::Status::OK;
+
+    const auto &metadata = context->client_metadata();
+    auto header = metadata.find("authorization");
+    if (header == metadata.end() ||
+        !utils::ConstantTimeEquals(
+            string(header->second.data(), header->second.length()),
+            "Bearer " + auth_token)) {
+        return grpc::Status(grpc::StatusCode::UNAUTHENTICATED,
+                            "missing or invalid auth token");
+    }
+    return grpc::Status::OK;
+}
+
 // TODO: This is an antipattern, which only exists b/c:
 // 1. we only have one class for both the data thread(s)
 //    & GRPC server
@@ -254,7 +361,7 @@ void SLAMServiceImpl::ProcessDataOnline(ORB_SLAM3::System *SLAM) {
                 BOOST_LOG_TRIVIAL(fatal) << "Invalid slam_mode=" << slam_mode;
             }
 
-            UpdateMapAndPose(SLAM, tmpPose);
+            UpdateMapAndPose(SLAM, tmpPose, currTime);
 
             // This log line is needed by rdk integration tests.
             BOOST_LOG_TRIVIAL(debug) << "Passed image to SLAM";
@@ -321,7 +428,7 @@ void SLAMServiceImpl::ProcessDataOffline(ORB_SLAM3::System *SLAM) {
                 BOOST_LOG_TRIVIAL(fatal) << "Invalid slam_mode=" << slam_mode;
             }
 
-            UpdateMapAndPose(SLAM, tmpPose);
+            UpdateMapAndPose(SLAM, tmpPose, timeStamp + fileTimeStart);
 
             // This log line is needed by rdk integration tests.
             BOOST_LOG_TRIVIAL(debug) << "Passed image to SLAM";
@@ -336,19 +443,37 @@ void SLAMServiceImpl::ProcessDataOffline(ORB_SLAM3::System *SLAM) {
 }
 
 // UpdateMapAndPose updates the copy of the current map and pose when a change
-// in keyframes occurs
+// in keyframes occurs, along with the tracking state. frameTime is the time the
+// frame that produced tmpPose was captured.
 void SLAMServiceImpl::UpdateMapAndPose(ORB_SLAM3::System *SLAM,
-                                       Sophus::SE3f tmpPose) {
+                                       Sophus::SE3f tmpPose, double frameTime) {
     ORB_SLAM3::Map *currMap = SLAM->GetAtlas()->GetCurrentMap();
     std::vector<ORB_SLAM3::KeyFrame *> keyframes = currMap->GetAllKeyFrames();
+    const int trackingState = SLAM->GetTrackingState();
     {
         std::lock_guard<std::mutex> lock(slam_mutex);
-        if (SLAM->GetTrackingState() ==
-            ORB_SLAM3::Tracking::eTrackingState::OK) {
+        // These log lines are parsed by the go module to track tracking
+        // changes.
+        if (trackingState != tracking_state) {
+            if (tracking_state == ORB_SLAM3::Tracking::eTrackingState::OK) {
+                BOOST_LOG_TRIVIAL(warning)
+                    << "Tracking lost, tracking state is now "
+                    << utils::TrackingStateToString(trackingState);
+            } else if (trackingState ==
+                       ORB_SLAM3::Tracking::eTrackingState::OK) {
+                BOOST_LOG_TRIVIAL(info) << "Tracking is OK";
+            }
+        }
+        tracking_state = trackingState;
+        if (trackingState == ORB_SLAM3::Tracking::eTrackingState::OK) {
             poseGrpc = tmpPose.inverse();
+            pose_frame_time = frameTime;
+            last_tracking_ok_time = std::chrono::steady_clock::now();
+            tracking_has_been_ok = true;
             if ((n_key_frames != keyframes.size()) ||
                 (curr_map_id != currMap->GetId())) {
                 currMapPoints = currMap->GetAllMapPoints();
+                map_version++;
             }
         }
     }
@@ -375,6 +500,23 @@ void SLAMServiceImpl::StopSaveAtlasAsOsa() {
     thread_save_atlas_as_osa_with_timestamp->join();
 }
 
+void SLAMServiceImpl::SaveAtlas(ORB_SLAM3::System *SLAM,
+                                const string &path) {
+    size_t key_frames = 0;
+    size_t map_points = 0;
+    {
+        std::lock_guard<std::mutex> lock(slam_mutex);
+        SLAM->SaveAtlasAsOsaWithTimestamp(path);
+        for (auto map : SLAM->GetAtlas()->GetAllMaps()) {
+            key_frames += map->KeyFramesInMap();
+            map_points += map->MapPointsInMap();
+        }
+    }
+    // This log line is parsed by the go module to track saved maps.
+    BOOST_LOG_TRIVIAL(info)
+        << utils::SavedMapLogMessage(path, key_frames, map_points);
+}
+
 void SLAMServiceImpl::SaveAtlasAsOsaWithTimestamp(ORB_SLAM3::System *SLAM) {
     auto check_for_shutdown_interval_usec =
         chrono::microseconds(checkForShutdownIntervalMicroseconds);
@@ -383,21 +525,20 @@ void SLAMServiceImpl::SaveAtlasAsOsaWithTimestamp(ORB_SLAM3::System *SLAM) {
         string path_save_file_name =
             utils::MakeFilenameWithTimestamp(path_to_map, camera_name);
         if (!use_live_data && finished_processing_offline) {
-            {
-                std::lock_guard<std::mutex> lock(slam_mutex);
-                SLAM->SaveAtlasAsOsaWithTimestamp(path_save_file_name);
-            }
+            SaveAtlas(SLAM, path_save_file_name);
 
-            // This log line is needed by rdk integration tests.
-            BOOST_LOG_TRIVIAL(debug) << "Finished saving final map";
+            // This log line is needed by rdk integration tests and the SLAM
+            // service, which waits for it before it stops the server.
+            BOOST_LOG_SEV(boost::log::trivial::logger::get(),
+                          finalMapSavedLogSeverity)
+                << "Finished saving final map";
             return;
         }
         if ((SLAM->GetAtlas()->GetCurrentMap()->GetAllKeyFrames().size() !=
              0) &&
             (SLAM->GetTrackingState() ==
              ORB_SLAM3::Tracking::eTrackingState::OK)) {
-            std::lock_guard<std::mutex> lock(slam_mutex);
-            SLAM->SaveAtlasAsOsaWithTimestamp(path_save_file_name);
+            SaveAtlas(SLAM, path_save_file_name);
         }
 
         // Sleep for map_rate_sec duration, but check frequently for
@@ -531,7 +672,10 @@ void ParseAndValidateArguments(const vector<string> &args,
     if (data_dir.empty()) {
         throw runtime_error("No data directory given");
     }
-    slamService.path_to_vocab = data_dir + "/config/ORBvoc.txt";
+    slamService.path_to_vocab = ArgParser(args, "-vocab_path=");
+    if (slamService.path_to_vocab.empty()) {
+        slamService.path_to_vocab = data_dir + "/config/ORBvoc.txt";
+    }
     slamService.path_to_settings = data_dir + "/config";
 
     slamService.path_to_data = data_dir + "/data";
@@ -568,6 +712,41 @@ void ParseAndValidateArguments(const vector<string> &args,
             << "map_rate_sec set to 0, setting SLAM to pure localization mode";
     }
 
+    const auto tls_cert = ArgParser(args, "-tls_cert=");
+    const auto tls_key = ArgParser(args, "-tls_key=");
+    const auto tls_client_ca = ArgParser(args, "-tls_client_ca=");
+    if (tls_cert.empty() != tls_key.empty()) {
+        throw runtime_error("-tls_cert and -tls_key must be given together");
+    }
+    if (!tls_client_ca.empty() && tls_cert.empty()) {
+        throw runtime_error("-tls_client_ca requires -tls_cert and -tls_key");
+    }
+    if (!tls_cert.empty()) {
+        slamService.tls_cert = ReadFileToString(tls_cert);
+        slamService.tls_key = ReadFileToString(tls_key);
+    }
+    if (!tls_client_ca.empty()) {
+        slamService.tls_client_ca = ReadFileToString(tls_client_ca);
+    }
+
+    // The token is read from a file so that it does not show up in the
+    // process list
+    const auto auth_token_file = ArgParser(args, "-auth_token_file=");
+    if (!auth_token_file.empty()) {
+        slamService.auth_token = ReadFileToString(auth_token_file);
+        boost::algorithm::trim(slamService.auth_token);
+        if (slamService.auth_token.empty()) {
+            throw runtime_error("auth token file " + auth_token_file +
+                                " is empty");
+        }
+        if (tls_cert.empty()) {
+            BOOST_LOG_TRIVIAL(warning)
+                << "an auth token is set without TLS, it is sent unencrypted";
+        }
+    }
+
+    slamService.ready_file = ArgParser(args, "-ready_file=");
+
     slamService.camera_name = ArgParser(args, "-sensors=");
 
     auto use_live_data = ArgParser(args, "-use_live_data=");
@@ -718,6 +897,152 @@ int FindFrameIndex(const std::vector<std::string> &filesRGB,
     return -1;
 }
 
+// Converts an ORB_SLAM3 tracking state into the name reported to the go
+// module.
+string TrackingStateToString(int trackingState) {
+    switch (trackingState) {
+        case ORB_SLAM3::Tracking::eTrackingState::OK:
+        case ORB_SLAM3::Tracking::eTrackingState::OK_KLT:
+            return "ok";
+        case ORB_SLAM3::Tracking::eTrackingState::RECENTLY_LOST:
+            return "recently_lost";
+        case ORB_SLAM3::Tracking::eTrackingState::LOST:
+            return "lost";
+        default:
+            return "not_initialized";
+    }
+}
+
+// Returns the current time in the representation used by
+// ReadTimeFromTimestamp, so that the two can be compared.
+double CurrentTime() {
+    auto now = std::chrono::system_clock::now();
+    std::time_t t = std::chrono::system_clock::to_time_t(now);
+    std::tm dt = *std::gmtime(&t);
+    auto subSecUsec = std::chrono::duration_cast<std::chrono::microseconds>(
+                          now.time_since_epoch())
+                          .count() %
+                      1000000;
+    return (double)std::mktime(&dt) + (double)subSecUsec / 1e6;
+}
+
+string ReadFileToString(const string path) {
+    std::ifstream file(path, std::ios::binary);
+    if (!file) {
+        throw runtime_error("unable to read " + path);
+    }
+    std::stringstream contents;
+    contents << file.rdbuf();
+    return contents.str();
+}
+
+bool ConstantTimeEquals(const string &a, const string &b) {
+    if (a.size() != b.size()) return false;
+    unsigned char diff = 0;
+    for (size_t i = 0; i < a.size(); i++) {
+        diff |= a[i] ^ b[i];
+    }
+    return diff == 0;
+}
+
+string JSONEscape(const string &s) {
+    std::ostringstream escaped;
+    for (const char c : s) {
+        switch (c) {
+            case '"':
+                escaped << "\\\"";
+                break;
+            case '\\':
+                escaped << "\\\\";
+                break;
+            case '\n':
+                escaped << "\\n";
+                break;
+            case '\t':
+                escaped << "\\t";
+                break;
+            default:
+                if (static_cast<unsigned char>(c) < 0x20) {
+                    escaped << boost::format("\\u%04x") %
+                                   static_cast<int>(c);
+                } else {
+                    escaped << c;
+                }
+        }
+    }
+    return escaped.str();
+}
+
+string ReadyFileContent(const string &address, const string &status,
+                        bool map_loaded, const string &error) {
+    std::ostringstream content;
+    content << "{\"address\":\"" << JSONEscape(address) << "\","
+            << "\"version\":\"" << serverVersion << "\","
+            << "\"orbslam_version\":\"" << orbslamVersion << "\","
+            << "\"modes\":[";
+    for (size_t i = 0; i < sizeof(supportedModes) / sizeof(*supportedModes);
+         i++) {
+        if (i > 0) content << ",";
+        content << "\"" << supportedModes[i] << "\"";
+    }
+    content << "],\"status\":\"" << status << "\","
+            << "\"map_loaded\":" << (map_loaded ? "true" : "false");
+    if (!error.empty()) {
+        content << ",\"error\":\"" << JSONEscape(error) << "\"";
+    }
+    content << "}\n";
+    return content.str();
+}
+
+void WriteReadyFile(const string &path, const string &content) {
+    const string tmp_path = path + ".tmp";
+    {
+        std::ofstream file(tmp_path, std::ios::binary | std::ios::trunc);
+        file << content;
+        if (!file) {
+            throw runtime_error("unable to write " + tmp_path);
+        }
+    }
+    if (std::rename(tmp_path.c_str(), path.c_str()) != 0) {
+        throw runtime_error("unable to replace " + path);
+    }
+}
+
+string SHA256Hex(const string &data) {
+    unsigned char digest[SHA256_DIGEST_LENGTH];
+    SHA256(reinterpret_cast<const unsigned char *>(data.data()), data.size(),
+           digest);
+    std::ostringstream hex;
+    hex << std::hex << std::setfill('0');
+    for (unsigned char byte : digest) {
+        hex << std::setw(2) << static_cast<int>(byte);
+    }
+    return hex.str();
+}
+
+size_t InternalStateResumeOffset(const string &requested_offset,
+                                 const string &requested_sha256,
+                                 const string &sha256, size_t size) {
+    if (requested_offset.empty() || requested_sha256 != sha256) return 0;
+    if (requested_offset.find_first_not_of("0123456789") != string::npos) {
+        return 0;
+    }
+    try {
+        const unsigned long long offset = std::stoull(requested_offset);
+        return offset <= size ? offset : 0;
+    } catch (const std::out_of_range &) {
+        return 0;
+    }
+}
+
+string SavedMapLogMessage(const string &path, size_t key_frames,
+                          size_t map_points) {
+    std::ostringstream message;
+    message << "Saved map to " << path << " (keyframes=" << key_frames
+            << ", map_points=" << map_points << ")";
+    return message.str();
+}
+
 void RemoveFile(std::string file_path) {
     if (remove(file_path.c_str()) != 0) {
         BOOST_LOG_TRIVIAL(error) << "Error removing file";
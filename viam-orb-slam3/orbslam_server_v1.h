@@ -5,6 +5,10 @@
 #include <grpcpp/server_context.h>
 
 #include <atomic>
+#include <boost/log/trivial.hpp>
+#include <chrono>
+#include <memory>
+#include <mutex>
 
 #include "common/v1/common.grpc.pb.h"
 #include "common/v1/common.pb.h"
@@ -31,6 +35,56 @@ extern std::atomic<bool> b_continue_session;
 static const int maximumGRPCByteLimit = 32 * 1024 * 1024;
 // Byte limit for chunks on GRPC, used for streaming apis
 static const int maximumGRPCByteChunkSize = 1 * 1024 * 1024;
+// Minimum interval between keepalive pings accepted from clients
+static const int minKeepalivePingIntervalMs = 10 * 1000;
+// Version reported in the ready file, bump it when the gRPC API or the ready
+// file changes
+static const char *const serverVersion = "1.1.0";
+// Modes reported in the ready file
+static const char *const supportedModes[] = {"mono", "rgbd"};
+// Version of the ORB_SLAM3 library, reported in the ready file
+static const char *const orbslamVersion = "1.0";
+// Metadata of GetInternalState. The server reports the checksum and size of
+// the internal state and the offset it is sent from. The client may request
+// an offset to resume from, along with the checksum it expects, which is
+// honored while that internal state is cached.
+static const char *const internalStateSHA256Header = "x-internal-state-sha256";
+static const char *const internalStateSizeHeader = "x-internal-state-size";
+static const char *const internalStateOffsetHeader = "x-internal-state-offset";
+// Severity of the line logged once the final map is saved in offline mode.
+// The SLAM service waits for it before it stops the server, so it must pass
+// the log filter that applies without the debug config param.
+static const boost::log::trivial::severity_level finalMapSavedLogSeverity =
+    boost::log::trivial::info;
+// Time an internal state is kept after it was sent, for interrupted downloads
+// of it to be resumed
+static const std::chrono::minutes internalStateResumeWindow(5);
+
+// Keeps the last internal state sent. A map that is still being built is
+// serialized differently every time, so resuming a download requires the
+// internal state it started from.
+class InternalStateCache {
+   public:
+    using Clock = std::chrono::steady_clock;
+
+    explicit InternalStateCache(Clock::duration ttl) : ttl(ttl) {}
+
+    // Returns the cached internal state if its checksum is sha256 and it did
+    // not expire, or nullptr otherwise.
+    std::shared_ptr<const string> Get(const string &sha256,
+                                      Clock::time_point now);
+
+    // Replaces the cached internal state, which expires ttl after now.
+    void Put(std::shared_ptr<const string> state, const string &sha256,
+             Clock::time_point now);
+
+   private:
+    const Clock::duration ttl;
+    std::mutex mutex;
+    std::shared_ptr<const string> state;
+    string sha256;
+    Clock::time_point expiry;
+};
 
 class SLAMServiceImpl final : public SLAMService::Service {
    public:
@@ -61,7 +115,8 @@ class SLAMServiceImpl final : public SLAMService::Service {
 
     void ProcessDataOffline(ORB_SLAM3::System *SLAM);
 
-    void UpdateMapAndPose(ORB_SLAM3::System *SLAM, Sophus::SE3f tmpPose);
+    void UpdateMapAndPose(ORB_SLAM3::System *SLAM, Sophus::SE3f tmpPose,
+                          double frameTime);
 
     void StartSaveAtlasAsOsa(ORB_SLAM3::System *SLAM);
 
@@ -77,6 +132,15 @@ class SLAMServiceImpl final : public SLAMService::Service {
     string path_to_settings;
     string slam_mode;
     string slam_port;
+    // PEM encoded TLS credentials, TLS is used when tls_cert is set and
+    // client certificates are required when tls_client_ca is set
+    string tls_cert;
+    string tls_key;
+    string tls_client_ca;
+    // Bearer token clients must present, if set
+    string auth_token;
+    // File the address and load status are reported in, if set
+    string ready_file;
     string camera_name;
     chrono::milliseconds frame_delay_msec;
     chrono::seconds map_rate_sec;
@@ -95,13 +159,29 @@ class SLAMServiceImpl final : public SLAMService::Service {
    private:
     void SaveAtlasAsOsaWithTimestamp(ORB_SLAM3::System *SLAM);
 
+    // Saves the atlas to path and logs its size.
+    void SaveAtlas(ORB_SLAM3::System *SLAM, const string &path);
+
+    // Returns UNAUTHENTICATED if an auth token is set and the request does
+    // not carry it.
+    ::grpc::Status CheckAuthorization(ServerContext *context);
+
     std::atomic<bool> finished_processing_offline{false};
+    InternalStateCache internal_state_cache{internalStateResumeWindow};
     std::thread *thread_save_atlas_as_osa_with_timestamp;
 
     ORB_SLAM3::System *slam;
     std::mutex slam_mutex;
     Sophus::SE3f poseGrpc;
     std::vector<ORB_SLAM3::MapPoint *> currMapPoints;
+    int tracking_state = ORB_SLAM3::Tracking::eTrackingState::NO_IMAGES_YET;
+    bool tracking_has_been_ok = false;
+    // Capture time of the frame that produced poseGrpc, in the representation
+    // returned by ReadTimeFromTimestamp
+    double pose_frame_time = 0;
+    std::chrono::steady_clock::time_point last_tracking_ok_time;
+    // Incremented every time currMapPoints is updated
+    int map_version = 0;
 };
 
 namespace utils {
@@ -148,9 +228,52 @@ int FindFrameIndex(const std::vector<std::string> &filesRGB,
 // currently does not support millisecond resolution
 string MakeFilenameWithTimestamp(string path_to_dir, string camera_name);
 
+// Returns the log line written after saving a map, which the go module
+// parses to record the map and its size.
+string SavedMapLogMessage(const string &path, size_t key_frames,
+                          size_t map_points);
+
 // Removes data file
 void RemoveFile(std::string file_path);
 
+// Converts an ORB_SLAM3 tracking state into one of not_initialized, ok,
+// recently_lost or lost
+string TrackingStateToString(int trackingState);
+
+// Returns the current time in the representation used by
+// ReadTimeFromTimestamp
+double CurrentTime();
+
+// Reads a whole file into a string. Throws an exception if the file cannot
+// be read.
+string ReadFileToString(const string path);
+
+// Compares two strings in time independent of where they differ.
+bool ConstantTimeEquals(const string &a, const string &b);
+
+// Returns the hex encoded SHA-256 checksum of data.
+string SHA256Hex(const string &data);
+
+// Returns the offset to resume streaming the internal state from, given the
+// offset and checksum requested by the client. The internal state is sent
+// from the start if it changed since the client started downloading it, or
+// if the requested offset is invalid.
+size_t InternalStateResumeOffset(const string &requested_offset,
+                                 const string &requested_sha256,
+                                 const string &sha256, size_t size);
+
+// Escapes a string for use in a JSON string literal.
+string JSONEscape(const string &s);
+
+// Returns the content of the ready file. status is one of loading, ready or
+// failed, and error is only reported when it is not empty.
+string ReadyFileContent(const string &address, const string &status,
+                        bool map_loaded, const string &error);
+
+// Writes the ready file to path, replacing any previous one atomically.
+// Throws an exception if the file cannot be written.
+void WriteReadyFile(const string &path, const string &content);
+
 std::string PcdHeader(int mapSize);
 
 void WriteFloatToBufferInBytes(std::string &buffer, float f);
@@ -3,9 +3,11 @@
 #define BOOST_NO_CXX11_SCOPED_ENUMS
 #include <boost/filesystem.hpp>
 #undef BOOST_NO_CXX11_SCOPED_ENUMS
+#include <grpcpp/health_check_service_interface.h>
 #include <grpcpp/security/server_credentials.h>
 #include <grpcpp/server_builder.h>
 #include <signal.h>
+#include <sys/stat.h>
 
 #include <boost/dll/runtime_symbol_info.hpp>
 #include <boost/log/core.hpp>
@@ -38,33 +40,99 @@ int main(int argc, char **argv) {
     viam::SLAMServiceImpl slamService;
     slamService.SetSlam(nullptr);
 
+    // Reports the address and load status in the ready file, if one is set
+    string address;
+    auto report_status = [&slamService, &address](const string &status,
+                                                  bool map_loaded,
+                                                  const string &error) {
+        if (slamService.ready_file.empty()) return;
+        try {
+            viam::utils::WriteReadyFile(
+                slamService.ready_file,
+                viam::utils::ReadyFileContent(address, status, map_loaded,
+                                              error));
+        } catch (const runtime_error &write_error) {
+            BOOST_LOG_TRIVIAL(error) << write_error.what();
+        }
+    };
+    auto fail = [&report_status](const string &message) {
+        BOOST_LOG_TRIVIAL(fatal) << message;
+        report_status("failed", false, message);
+        return 1;
+    };
+
+    const vector<string> args(argv + 1, argv + argc);
     try {
-        const vector<string> args(argv + 1, argv + argc);
         viam::utils::ParseAndValidateArguments(args, slamService);
     } catch (const runtime_error &error) {
-        BOOST_LOG_TRIVIAL(fatal) << error.what();
-        return 1;
+        slamService.ready_file = viam::utils::ArgParser(args, "-ready_file=");
+        return fail(error.what());
     }
 
-    // setup the SLAM server
+    // setup the SLAM server, with the standard health service so that
+    // clients can detect when it is unavailable
+    grpc::EnableDefaultHealthCheckService(true);
     ServerBuilder builder;
 
+    std::shared_ptr<grpc::ServerCredentials> credentials =
+        grpc::InsecureServerCredentials();
+    if (!slamService.tls_cert.empty()) {
+        grpc::SslServerCredentialsOptions ssl_options;
+        ssl_options.pem_key_cert_pairs.push_back(
+            {slamService.tls_key, slamService.tls_cert});
+        if (!slamService.tls_client_ca.empty()) {
+            ssl_options.pem_root_certs = slamService.tls_client_ca;
+            ssl_options.client_certificate_request =
+                GRPC_SSL_REQUEST_AND_REQUIRE_CLIENT_CERTIFICATE_AND_VERIFY;
+        }
+        credentials = grpc::SslServerCredentials(ssl_options);
+        BOOST_LOG_TRIVIAL(info) << "Serving with TLS";
+    }
+
     std::unique_ptr<int> selected_port = std::make_unique<int>(0);
-    builder.AddListeningPort(slamService.slam_port,
-                             grpc::InsecureServerCredentials(),
+    builder.AddListeningPort(slamService.slam_port, credentials,
                              selected_port.get());
 
     // Increasing the gRPC max message size from the default value of 4MB to
     // 32MB, to match the limit that is set in RDK. This is necessary for
     // transmitting large pointclouds.
     builder.SetMaxSendMessageSize(viam::maximumGRPCByteLimit);
+    // Allow the keepalive pings clients send to detect lost connections,
+    // including while no call is in progress.
+    builder.AddChannelArgument(
+        GRPC_ARG_HTTP2_MIN_RECV_PING_INTERVAL_WITHOUT_DATA_MS,
+        viam::minKeepalivePingIntervalMs);
+    builder.AddChannelArgument(GRPC_ARG_KEEPALIVE_PERMIT_WITHOUT_CALLS, 1);
     builder.RegisterService(&slamService);
 
-    // Start the SLAM gRPC server
+    // Start the SLAM gRPC server. A unix socket is made accessible only to
+    // the user running the server once it is bound, since changing the
+    // umask would affect files other threads create meanwhile.
+    const string unix_prefix = "unix:";
+    const bool is_unix_socket =
+        slamService.slam_port.rfind(unix_prefix, 0) == 0;
     std::unique_ptr<Server> server(builder.BuildAndStart());
+    if (!server) {
+        return fail("Unable to listen on " + slamService.slam_port);
+    }
+    if (is_unix_socket) {
+        string socket_path = slamService.slam_port.substr(unix_prefix.size());
+        if (socket_path.rfind("//", 0) == 0) socket_path.erase(0, 2);
+        if (chmod(socket_path.c_str(), S_IRUSR | S_IWUSR) != 0) {
+            server->Shutdown();
+            return fail("Unable to restrict access to " + socket_path);
+        }
+    }
 
-    // This log line is needed by rdk to get the port.
-    BOOST_LOG_TRIVIAL(info) << "Server listening on " << *selected_port;
+    if (is_unix_socket) {
+        address = slamService.slam_port;
+    } else {
+        address = slamService.slam_port.substr(
+                      0, slamService.slam_port.rfind(':') + 1) +
+                  to_string(*selected_port);
+    }
+    BOOST_LOG_TRIVIAL(info) << "Server listening on " << address;
+    report_status("loading", false, "");
 
     // Determine which settings file to use(.yaml)
     const path myPath(slamService.path_to_settings);
@@ -87,10 +155,9 @@ int main(int argc, char **argv) {
         }
     }
     if (latest.empty()) {
-        BOOST_LOG_TRIVIAL(fatal)
-            << "No correctly formatted .yaml file found, Expected:\n"
-               "{sensor}_data_{dateformat}.yaml";
-        return 1;
+        return fail(
+            "No correctly formatted .yaml file found, Expected:\n"
+            "{sensor}_data_{dateformat}.yaml");
     }
 
     // report the current yaml file check if it matches our format
@@ -103,11 +170,10 @@ int main(int argc, char **argv) {
         if (myYAML.find("_data_") != string::npos)
             slamService.camera_name = myYAML.substr(0, myYAML.find("_data_"));
         else {
-            BOOST_LOG_TRIVIAL(fatal)
-                << "No correctly formatted .yaml file found, Expected:\n"
-                   "{sensor}_data_{dateformat}.yaml\n"
-                   "as most the recent config in directory";
-            return 1;
+            return fail(
+                "No correctly formatted .yaml file found, Expected:\n"
+                "{sensor}_data_{dateformat}.yaml\n"
+                "as most the recent config in directory");
         }
     }
 
@@ -131,10 +197,9 @@ int main(int argc, char **argv) {
                 << "Using vocabulary file from relative path";
             slamService.path_to_vocab = relativePathToVocab.string();
         } else {
-            BOOST_LOG_TRIVIAL(fatal)
-                << "No vocabulary file found, looked in "
-                << pathToVocabFromConfig << " and " << relativePathToVocab;
-            return 1;
+            return fail("No vocabulary file found, looked in " +
+                        pathToVocabFromConfig.string() + " and " +
+                        relativePathToVocab.string());
         }
     }
 
@@ -171,6 +236,12 @@ int main(int argc, char **argv) {
         SLAM->GetAtlas()->ChangeMap(largestMap);
     }
 
+    bool map_loaded = false;
+    for (auto map : SLAM->GetAtlas()->GetAllMaps()) {
+        if (map->KeyFramesInMap() > 0) map_loaded = true;
+    }
+    report_status("ready", map_loaded, "");
+
     slamService.SetSlam(SLAM.get());
     if (!slamService.use_live_data) {
         BOOST_LOG_TRIVIAL(info) << "Running in offline mode";
@@ -137,6 +137,24 @@ BOOST_AUTO_TEST_CASE(ParseAndValidateArguments_valid_config) {
     BOOST_TEST(slamService.delete_processed_data == false);
 }
 
+BOOST_AUTO_TEST_CASE(ParseAndValidateArguments_no_debug_logs_final_map_save) {
+    const vector<string> args{"-data_dir=/path/to",
+                              "-config_param={mode=rgbd}",
+                              "-port=20000",
+                              "-sensors=color",
+                              "-data_rate_ms=200",
+                              "-map_rate_sec=60",
+                              "-delete_processed_data=false",
+                              "-use_live_data=false"};
+    SLAMServiceImpl slamService;
+    utils::ParseAndValidateArguments(args, slamService);
+    auto &logger = boost::log::trivial::logger::get();
+    BOOST_TEST(static_cast<bool>(logger.open_record(
+        boost::log::keywords::severity = finalMapSavedLogSeverity)));
+    BOOST_TEST(!logger.open_record(boost::log::keywords::severity =
+                                       boost::log::trivial::debug));
+}
+
 BOOST_AUTO_TEST_CASE(
     ParseAndValidateArguments_valid_config_capitalized_slam_mode) {
     const vector<string> args{"-data_dir=/path/to",
@@ -336,6 +354,113 @@ BOOST_AUTO_TEST_CASE(ParseAndValidateArguments_config_no_use_live_data) {
     checkParseAndValidateArgumentsException(args, message);
 }
 
+BOOST_AUTO_TEST_CASE(ParseAndValidateArguments_tls_cert_without_key) {
+    const vector<string> args{"-data_dir=/path/to",
+                              "-config_param={mode=rgbd}",
+                              "-port=20000",
+                              "-sensors=color",
+                              "-data_rate_ms=200",
+                              "-map_rate_sec=60",
+                              "-delete_processed_data=false",
+                              "-use_live_data=true",
+                              "-tls_cert=/path/to/cert.pem"};
+    const string message = "-tls_cert and -tls_key must be given together";
+    checkParseAndValidateArgumentsException(args, message);
+}
+
+BOOST_AUTO_TEST_CASE(ParseAndValidateArguments_vocab_path) {
+    const vector<string> args{"-data_dir=/path/to",
+                              "-config_param={mode=rgbd}",
+                              "-port=20000",
+                              "-sensors=color",
+                              "-data_rate_ms=200",
+                              "-map_rate_sec=60",
+                              "-delete_processed_data=false",
+                              "-use_live_data=true",
+                              "-vocab_path=/vocabularies/ORBvoc.txt"};
+    SLAMServiceImpl slamService;
+    utils::ParseAndValidateArguments(args, slamService);
+    BOOST_TEST(slamService.path_to_vocab == "/vocabularies/ORBvoc.txt");
+}
+
+BOOST_AUTO_TEST_CASE(ParseAndValidateArguments_auth_token_file) {
+    auto tmp_dir = fs::temp_directory_path() / fs::unique_path();
+    fs::create_directory(tmp_dir);
+    const auto token_file = tmp_dir / "token";
+    fs::ofstream ofs(token_file);
+    ofs << "secret\n";
+    ofs.close();
+
+    const vector<string> args{"-data_dir=/path/to",
+                              "-config_param={mode=rgbd}",
+                              "-port=20000",
+                              "-sensors=color",
+                              "-data_rate_ms=200",
+                              "-map_rate_sec=60",
+                              "-delete_processed_data=false",
+                              "-use_live_data=true",
+                              "-auth_token_file=" + token_file.string()};
+    SLAMServiceImpl slamService;
+    utils::ParseAndValidateArguments(args, slamService);
+    BOOST_TEST(slamService.auth_token == "secret");
+    fs::remove_all(tmp_dir);
+}
+
+BOOST_AUTO_TEST_CASE(ReadFileToString_missing_file) {
+    const string message = "unable to read /path/to/missing";
+    BOOST_CHECK_EXCEPTION(utils::ReadFileToString("/path/to/missing"),
+                          runtime_error, [&message](const runtime_error& ex) {
+                              BOOST_CHECK_EQUAL(ex.what(), message);
+                              return true;
+                          });
+}
+
+BOOST_AUTO_TEST_CASE(ConstantTimeEquals_compares_strings) {
+    BOOST_TEST(utils::ConstantTimeEquals("Bearer abc", "Bearer abc"));
+    BOOST_TEST(!utils::ConstantTimeEquals("Bearer abc", "Bearer abd"));
+    BOOST_TEST(!utils::ConstantTimeEquals("Bearer abc", "Bearer ab"));
+    BOOST_TEST(utils::ConstantTimeEquals("", ""));
+}
+
+BOOST_AUTO_TEST_CASE(SHA256Hex_hashes_data) {
+    BOOST_TEST(
+        utils::SHA256Hex("abc") ==
+        "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad");
+    BOOST_TEST(
+        utils::SHA256Hex("") ==
+        "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855");
+}
+
+BOOST_AUTO_TEST_CASE(InternalStateResumeOffset_resumes_same_state) {
+    BOOST_TEST(utils::InternalStateResumeOffset("", "", "abc", 10) == 0);
+    BOOST_TEST(utils::InternalStateResumeOffset("4", "abc", "abc", 10) == 4);
+    BOOST_TEST(utils::InternalStateResumeOffset("10", "abc", "abc", 10) == 10);
+    // The internal state changed since the download started.
+    BOOST_TEST(utils::InternalStateResumeOffset("4", "abd", "abc", 10) == 0);
+    BOOST_TEST(utils::InternalStateResumeOffset("11", "abc", "abc", 10) == 0);
+    BOOST_TEST(utils::InternalStateResumeOffset("-4", "abc", "abc", 10) == 0);
+    BOOST_TEST(utils::InternalStateResumeOffset("4x", "abc", "abc", 10) == 0);
+    BOOST_TEST(utils::InternalStateResumeOffset("99999999999999999999999",
+                                                "abc", "abc", 10) == 0);
+}
+
+BOOST_AUTO_TEST_CASE(InternalStateCache_keeps_last_state_until_expired) {
+    InternalStateCache cache(std::chrono::seconds(10));
+    const auto now = InternalStateCache::Clock::now();
+    BOOST_TEST(cache.Get("abc", now) == nullptr);
+
+    const auto state = std::make_shared<const string>("state");
+    cache.Put(state, "abc", now);
+    BOOST_TEST(cache.Get("abc", now + std::chrono::seconds(9)) == state);
+    BOOST_TEST(cache.Get("abd", now) == nullptr);
+    BOOST_TEST(cache.Get("abc", now + std::chrono::seconds(10)) == nullptr);
+
+    // Only the last internal state is kept.
+    cache.Put(std::make_shared<const string>("other"), "abd", now);
+    BOOST_TEST(cache.Get("abc", now) == nullptr);
+    BOOST_TEST(*cache.Get("abd", now) == "other");
+}
+
 BOOST_AUTO_TEST_CASE(ReadTimeFromTimestamp_missing_timestamp) {
     // Provide a filename with a missing timestamp
     std::string timestamp = "no-timestamp";
@@ -498,5 +623,74 @@ BOOST_AUTO_TEST_CASE(FindFrameIndex_Recent_found_time_rgbd) {
     fs::remove_all(tmp_dir);
 }
 
+BOOST_AUTO_TEST_CASE(TrackingStateToString_all_states) {
+    BOOST_TEST(utils::TrackingStateToString(
+                   ORB_SLAM3::Tracking::eTrackingState::SYSTEM_NOT_READY) ==
+               "not_initialized");
+    BOOST_TEST(utils::TrackingStateToString(
+                   ORB_SLAM3::Tracking::eTrackingState::NO_IMAGES_YET) ==
+               "not_initialized");
+    BOOST_TEST(utils::TrackingStateToString(
+                   ORB_SLAM3::Tracking::eTrackingState::NOT_INITIALIZED) ==
+               "not_initialized");
+    BOOST_TEST(utils::TrackingStateToString(
+                   ORB_SLAM3::Tracking::eTrackingState::OK) == "ok");
+    BOOST_TEST(utils::TrackingStateToString(
+                   ORB_SLAM3::Tracking::eTrackingState::RECENTLY_LOST) ==
+               "recently_lost");
+    BOOST_TEST(utils::TrackingStateToString(
+                   ORB_SLAM3::Tracking::eTrackingState::LOST) == "lost");
+}
+
+BOOST_AUTO_TEST_CASE(CurrentTime_matches_timestamp_representation) {
+    std::time_t t = std::time(nullptr);
+    char timestamp[100];
+    std::strftime(timestamp, sizeof(timestamp), utils::time_format.c_str(),
+                  std::gmtime(&t));
+    const auto now = utils::CurrentTime();
+    BOOST_TEST(now - utils::ReadTimeFromTimestamp(timestamp) >= 0);
+    BOOST_TEST(now - utils::ReadTimeFromTimestamp(timestamp) < 2);
+}
+
+BOOST_AUTO_TEST_CASE(JSONEscape_escapes_special_characters) {
+    BOOST_TEST(utils::JSONEscape("plain") == "plain");
+    BOOST_TEST(utils::JSONEscape("a \"b\" \\c\n") ==
+               "a \\\"b\\\" \\\\c\\n");
+    BOOST_TEST(utils::JSONEscape(string(1, '\x01')) == "\\u0001");
+}
+
+BOOST_AUTO_TEST_CASE(ReadyFileContent_reports_status) {
+    BOOST_TEST(utils::ReadyFileContent("localhost:4000", "ready", true, "") ==
+               "{\"address\":\"localhost:4000\",\"version\":\"" +
+                   string(serverVersion) + "\",\"orbslam_version\":\"" +
+                   string(orbslamVersion) +
+                   "\",\"modes\":[\"mono\",\"rgbd\"],\"status\":\"ready\","
+                   "\"map_loaded\":true}\n");
+    BOOST_TEST(utils::ReadyFileContent("", "failed", false,
+                                       "No \"vocabulary\"") ==
+               "{\"address\":\"\",\"version\":\"" + string(serverVersion) +
+                   "\",\"orbslam_version\":\"" + string(orbslamVersion) +
+                   "\",\"modes\":[\"mono\",\"rgbd\"],\"status\":\"failed\","
+                   "\"map_loaded\":false,\"error\":\"No \\\"vocabulary\\\"\"}\n");
+}
+
+BOOST_AUTO_TEST_CASE(SavedMapLogMessage_reports_map_size) {
+    BOOST_TEST(
+        utils::SavedMapLogMessage("/data/map/cam_data_1.osa", 3, 120) ==
+        "Saved map to /data/map/cam_data_1.osa (keyframes=3, map_points=120)");
+}
+
+BOOST_AUTO_TEST_CASE(WriteReadyFile_replaces_file) {
+    auto tmp_dir = fs::temp_directory_path() / fs::unique_path();
+    fs::create_directory(tmp_dir);
+    const auto ready_file = (tmp_dir / "ready.json").string();
+
+    utils::WriteReadyFile(ready_file, "first");
+    utils::WriteReadyFile(ready_file, "second");
+    BOOST_TEST(utils::ReadFileToString(ready_file) == "second");
+    BOOST_TEST(!fs::exists(ready_file + ".tmp"));
+    fs::remove_all(tmp_dir);
+}
+
 }  // namespace
 }  // namespace viam
@@ -6,24 +6,37 @@ package viamorbslam3_test
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"image"
 	"net"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/edaniels/golog"
+	"github.com/golang/geo/r3"
+	geo "github.com/kellydunn/golang-geo"
 	"github.com/pkg/errors"
 	"github.com/viamrobotics/gostream"
+	commonpb "go.viam.com/api/common/v1"
+	pb "go.viam.com/api/service/slam/v1"
 	"go.viam.com/rdk/components/camera"
+	"go.viam.com/rdk/components/movementsensor"
 	"go.viam.com/rdk/pointcloud"
 	"go.viam.com/rdk/resource"
 	"go.viam.com/rdk/rimage"
 	"go.viam.com/rdk/rimage/transform"
 	"go.viam.com/rdk/services/slam"
+	"go.viam.com/rdk/spatialmath"
 	"go.viam.com/rdk/testutils/inject"
 	rdkutils "go.viam.com/rdk/utils"
 	"go.viam.com/test"
@@ -33,15 +46,20 @@ import (
 	viamorbslam3 "github.com/viamrobotics/viam-orb-slam3"
 	orbSlamConfig "github.com/viamrobotics/viam-orb-slam3/config"
 	"github.com/viamrobotics/viam-orb-slam3/internal/testhelper"
+	"github.com/viamrobotics/viam-orb-slam3/internalstate"
+	"github.com/viamrobotics/viam-orb-slam3/mapmeta"
+	"github.com/viamrobotics/viam-orb-slam3/pcd"
+	"github.com/viamrobotics/viam-orb-slam3/scale"
 )
 
 const (
-	validDataRateMS    = 200
-	dataBufferSize     = 4
-	testExecutableName = "true" // the program "true", not the boolean value
+	validDataRateMS = 200
+	dataBufferSize  = 4
 )
 
 var (
+	// testExecutableName is the fake SLAM process written by TestMain.
+	testExecutableName                string
 	orbslamIntCameraMutex             sync.Mutex
 	orbslamIntCameraReleaseImagesChan = make(chan int, 2)
 	orbslamIntWebcamReleaseImageChan  = make(chan int, 1)
@@ -62,6 +80,20 @@ func getNumOrbslamImages(mode viamorbslam3.SubAlgo) int {
 	}
 }
 
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "*")
+	if err != nil {
+		panic(err)
+	}
+	testExecutableName, err = testhelper.WriteFakeSLAMExecutable(dir)
+	if err != nil {
+		panic(err)
+	}
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
 func closeOutSLAMService(t *testing.T, name string) {
 	t.Helper()
 
@@ -80,6 +112,81 @@ func setupTestGRPCServer(tb testing.TB) (*grpc.Server, int) {
 	return grpcServer, listener.Addr().(*net.TCPAddr).Port
 }
 
+// fakeSLAMServer is a SLAM server that returns the pose, point cloud map and internal state it is given.
+type fakeSLAMServer struct {
+	pb.UnimplementedSLAMServiceServer
+
+	mu            sync.Mutex
+	pose          spatialmath.Pose
+	mapVersion    int64
+	points        []r3.Vector
+	internalState []byte
+}
+
+func (s *fakeSLAMServer) set(pose spatialmath.Pose, mapVersion int64, points []r3.Vector) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pose, s.mapVersion, s.points = pose, mapVersion, points
+}
+
+func (s *fakeSLAMServer) GetPosition(ctx context.Context, req *pb.GetPositionRequest) (*pb.GetPositionResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pose := s.pose
+	if pose == nil {
+		pose = spatialmath.NewZeroPose()
+	}
+	q := pose.Orientation().Quaternion()
+	extra, err := structpb.NewStruct(map[string]interface{}{
+		"quat":           map[string]interface{}{"real": q.Real, "imag": q.Imag, "jmag": q.Jmag, "kmag": q.Kmag},
+		"tracking_state": "ok",
+		"map_version":    float64(s.mapVersion),
+	})
+	if err != nil {
+		return nil, err
+	}
+	point := pose.Point()
+	return &pb.GetPositionResponse{
+		Pose:               &commonpb.Pose{X: point.X, Y: point.Y, Z: point.Z, OZ: 1},
+		ComponentReference: "fake_camera",
+		Extra:              extra,
+	}, nil
+}
+
+func (s *fakeSLAMServer) GetPointCloudMap(req *pb.GetPointCloudMapRequest, stream pb.SLAMService_GetPointCloudMapServer) error {
+	s.mu.Lock()
+	data := pcd.Write(s.points)
+	s.mu.Unlock()
+	return stream.Send(&pb.GetPointCloudMapResponse{PointCloudPcdChunk: data})
+}
+
+func (s *fakeSLAMServer) GetInternalState(req *pb.GetInternalStateRequest, stream pb.SLAMService_GetInternalStateServer) error {
+	s.mu.Lock()
+	data := s.internalState
+	s.mu.Unlock()
+	sum := sha256.Sum256(data)
+	if err := stream.SendHeader(metadata.Pairs(
+		internalstate.SHA256Header, hex.EncodeToString(sum[:]),
+		internalstate.SizeHeader, strconv.Itoa(len(data)),
+		internalstate.OffsetHeader, "0",
+	)); err != nil {
+		return err
+	}
+	return stream.Send(&pb.GetInternalStateResponse{InternalStateChunk: data})
+}
+
+// setupFakeSLAMServer starts a fakeSLAMServer and returns the port it listens on.
+func setupFakeSLAMServer(tb testing.TB) (*grpc.Server, int, *fakeSLAMServer) {
+	listener, err := net.Listen("tcp", "localhost:0")
+	test.That(tb, err, test.ShouldBeNil)
+	grpcServer := grpc.NewServer()
+	fake := &fakeSLAMServer{}
+	pb.RegisterSLAMServiceServer(grpcServer, fake)
+	go grpcServer.Serve(listener)
+
+	return grpcServer, listener.Addr().(*net.TCPAddr).Port, fake
+}
+
 func getGoodOrMissingDistortionParamsCamera(projA transform.Projector) *inject.Camera {
 	cam := &inject.Camera{}
 	cam.StreamFunc = func(ctx context.Context, errHandlers ...gostream.ErrorHandler) (gostream.VideoStream, error) {
@@ -148,6 +255,15 @@ func setupDeps(attr *orbSlamConfig.Config) resource.Dependencies {
 	}
 	projWebcam = intrinsicsWebcam
 
+	if attr.OdometrySensor != "" {
+		// The odometry does not move, so it never adds to the scale estimate.
+		odometry := &inject.MovementSensor{}
+		odometry.PositionFunc = func(ctx context.Context, extra map[string]interface{}) (*geo.Point, float64, error) {
+			return geo.NewPoint(0, 0), 0, nil
+		}
+		deps[movementsensor.Named(attr.OdometrySensor)] = odometry
+	}
+
 	for _, sensor := range attr.Sensors {
 		cam := &inject.Camera{}
 		switch sensor {
@@ -415,7 +531,11 @@ func createSLAMService(
 	if err != nil {
 		return nil, err
 	}
-	test.That(t, sensorDeps, test.ShouldResemble, cfg.Sensors)
+	expectedDeps := append([]string{}, cfg.Sensors...)
+	if cfg.OdometrySensor != "" {
+		expectedDeps = append(expectedDeps, cfg.OdometrySensor)
+	}
+	test.That(t, sensorDeps, test.ShouldResemble, expectedDeps)
 
 	viamorbslam3.SetCameraValidationMaxTimeoutSecForTesting(1)
 	viamorbslam3.SetDialMaxTimeoutSecForTesting(2)
@@ -761,6 +881,458 @@ func TestEndpointFailures(t *testing.T) {
 	closeOutSLAMService(t, name)
 }
 
+func TestScaledPose(t *testing.T) {
+	logger := golog.NewTestLogger(t)
+	name, err := testhelper.CreateTempFolderArchitecture(logger)
+	test.That(t, err, test.ShouldBeNil)
+
+	// A saved scale is only used along with the map it was estimated for.
+	mapPath := filepath.Join(name, "map", "fake_camera_data_2023-01-01T00:00:00.0000Z.osa")
+	test.That(t, os.WriteFile(mapPath, nil, 0o644), test.ShouldBeNil)
+	test.That(t, scale.WriteFile(filepath.Join(name, "map", "scale.json"), scale.Estimate{Scale: 0.5, Samples: 5}),
+		test.ShouldBeNil)
+
+	grpcServer, port, fake := setupFakeSLAMServer(t)
+	orientation := &spatialmath.OrientationVectorDegrees{OZ: 1, Theta: 90}
+	attrCfg := &orbSlamConfig.Config{
+		Sensors:        []string{},
+		ConfigParams:   map[string]string{"mode": "mono"},
+		DataDirectory:  name,
+		Port:           "localhost:" + strconv.Itoa(port),
+		UseLiveData:    &_false,
+		OdometrySensor: "odometry",
+		CameraExtrinsic: &orbSlamConfig.ExtrinsicConfig{
+			Translation: r3.Vector{X: 100, Z: 200},
+			Orientation: orientation,
+		},
+	}
+	svc, err := createSLAMService(t, attrCfg, logger, false, true, testExecutableName)
+	test.That(t, err, test.ShouldBeNil)
+
+	// 2 SLAM units at 0.5 m per SLAM unit are 1000 mm, in the same units as the extrinsic. The map holds a point
+	// where the camera is.
+	slamOrientation := &spatialmath.OrientationVectorDegrees{OY: 1, Theta: 30}
+	fake.set(spatialmath.NewPose(r3.Vector{X: 2}, slamOrientation), 1, []r3.Vector{{X: 2}})
+
+	pose, frame, err := svc.GetPosition(context.Background())
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, frame, test.ShouldEqual, "base")
+	extrinsic := attrCfg.CameraExtrinsic.Pose()
+	expected := spatialmath.Compose(spatialmath.Compose(extrinsic, spatialmath.NewPose(r3.Vector{X: 1000}, slamOrientation)),
+		spatialmath.PoseInverse(extrinsic))
+	test.That(t, spatialmath.PoseAlmostEqual(pose, expected), test.ShouldBeTrue)
+
+	callback, err := svc.GetPointCloudMap(context.Background())
+	test.That(t, err, test.ShouldBeNil)
+	var data []byte
+	for {
+		chunk, err := callback()
+		if err != nil {
+			break
+		}
+		data = append(data, chunk...)
+	}
+	points, err := pcd.Read(data)
+	test.That(t, err, test.ShouldBeNil)
+	// The map is in the base frame like the pose, so the point at the camera is where the extrinsic puts the camera
+	// relative to the base.
+	test.That(t, len(points), test.ShouldEqual, 1)
+	cameraPoint := spatialmath.Compose(pose, extrinsic).Point()
+	test.That(t, points[0].Sub(cameraPoint).Norm(), test.ShouldBeLessThan, 1e-3)
+
+	grpcServer.Stop()
+	test.That(t, svc.Close(context.Background()), test.ShouldBeNil)
+
+	closeOutSLAMService(t, name)
+}
+
+func TestUnscaledPose(t *testing.T) {
+	logger := golog.NewTestLogger(t)
+	name, err := testhelper.CreateTempFolderArchitecture(logger)
+	test.That(t, err, test.ShouldBeNil)
+
+	grpcServer, port, fake := setupFakeSLAMServer(t)
+	attrCfg := &orbSlamConfig.Config{
+		Sensors:        []string{},
+		ConfigParams:   map[string]string{"mode": "mono"},
+		DataDirectory:  name,
+		Port:           "localhost:" + strconv.Itoa(port),
+		UseLiveData:    &_false,
+		OdometrySensor: "odometry",
+	}
+	svc, err := createSLAMService(t, attrCfg, logger, false, true, testExecutableName)
+	test.That(t, err, test.ShouldBeNil)
+	fake.set(spatialmath.NewPoseFromPoint(r3.Vector{X: 2}), 1, []r3.Vector{{X: 2}})
+
+	// With odometry, poses and maps are reported in mm, so they are held back until the scale is estimated rather than
+	// switching units once it is.
+	_, _, err = svc.GetPosition(context.Background())
+	test.That(t, err.Error(), test.ShouldContainSubstring, "the scale of the map is not known yet")
+	callback, err := svc.GetPointCloudMap(context.Background())
+	test.That(t, err, test.ShouldBeNil)
+	_, err = callback()
+	test.That(t, err.Error(), test.ShouldContainSubstring, "the scale of the map is not known yet")
+
+	grpcServer.Stop()
+	test.That(t, svc.Close(context.Background()), test.ShouldBeNil)
+
+	closeOutSLAMService(t, name)
+}
+
+func TestPointCloudMapCommands(t *testing.T) {
+	logger := golog.NewTestLogger(t)
+	name, err := testhelper.CreateTempFolderArchitecture(logger)
+	test.That(t, err, test.ShouldBeNil)
+
+	mapPath := filepath.Join(name, "map", "fake_camera_data_2023-01-01T00:00:00.0000Z.osa")
+	test.That(t, os.WriteFile(mapPath, nil, 0o644), test.ShouldBeNil)
+	test.That(t, scale.WriteFile(filepath.Join(name, "map", "scale.json"), scale.Estimate{Scale: 0.5, Samples: 5}),
+		test.ShouldBeNil)
+
+	grpcServer, port, fake := setupFakeSLAMServer(t)
+	attrCfg := &orbSlamConfig.Config{
+		Sensors:              []string{},
+		ConfigParams:         map[string]string{"mode": "mono"},
+		DataDirectory:        name,
+		Port:                 "localhost:" + strconv.Itoa(port),
+		UseLiveData:          &_false,
+		PositionCacheTTLMsec: 1,
+		OdometrySensor:       "odometry",
+	}
+	svc, err := createSLAMService(t, attrCfg, logger, false, true, testExecutableName)
+	test.That(t, err, test.ShouldBeNil)
+
+	getDelta := func(args map[string]interface{}) map[string]interface{} {
+		// Wait for the cached position, which holds the map version, to expire.
+		time.Sleep(10 * time.Millisecond)
+		resp, err := svc.DoCommand(context.Background(), map[string]interface{}{viamorbslam3.GetPointCloudMapDeltaCommand: args})
+		test.That(t, err, test.ShouldBeNil)
+		return resp
+	}
+	decodePoints := func(encoded interface{}) []r3.Vector {
+		data, err := base64.StdEncoding.DecodeString(encoded.(string))
+		test.That(t, err, test.ShouldBeNil)
+		points, err := pcd.Read(data)
+		test.That(t, err, test.ShouldBeNil)
+		return points
+	}
+
+	t.Run("Delta between two versions of the map", func(t *testing.T) {
+		fake.set(nil, 1, []r3.Vector{{X: 1}, {Y: 1}, {Z: 1}})
+		resp := getDelta(map[string]interface{}{})
+		test.That(t, resp["version"], test.ShouldEqual, int64(1))
+		test.That(t, resp["full"], test.ShouldBeTrue)
+		// Points are returned in mm, at 0.5 m per SLAM unit.
+		test.That(t, decodePoints(resp["map"]), test.ShouldResemble, []r3.Vector{{X: 500}, {Y: 500}, {Z: 500}})
+
+		fake.set(nil, 2, []r3.Vector{{X: 1}, {Y: 1}, {Z: 2}})
+		resp = getDelta(map[string]interface{}{"since_version": 1.0})
+		test.That(t, resp["version"], test.ShouldEqual, int64(2))
+		test.That(t, resp["full"], test.ShouldBeFalse)
+		test.That(t, decodePoints(resp["added"]), test.ShouldResemble, []r3.Vector{{Z: 1000}})
+		test.That(t, decodePoints(resp["removed"]), test.ShouldResemble, []r3.Vector{{Z: 500}})
+	})
+
+	t.Run("Full map for an unknown version", func(t *testing.T) {
+		resp := getDelta(map[string]interface{}{"since_version": 7.0})
+		test.That(t, resp["version"], test.ShouldEqual, int64(2))
+		test.That(t, resp["full"], test.ShouldBeTrue)
+		test.That(t, decodePoints(resp["map"]), test.ShouldResemble, []r3.Vector{{X: 500}, {Y: 500}, {Z: 1000}})
+	})
+
+	t.Run("Point cloud map with the filters of a request", func(t *testing.T) {
+		crop := map[string]interface{}{
+			"crop": map[string]interface{}{
+				"min": map[string]interface{}{"x": 0.0, "y": 0.0, "z": 0.0},
+				"max": map[string]interface{}{"x": 600.0, "y": 0.0, "z": 1000.0},
+			},
+		}
+		resp, err := svc.DoCommand(context.Background(), map[string]interface{}{
+			viamorbslam3.GetPointCloudMapCommand: map[string]interface{}{"point_cloud_filters": crop},
+		})
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, decodePoints(resp["map"]), test.ShouldResemble, []r3.Vector{{X: 500}, {Z: 1000}})
+
+		// The filters of one request do not apply to others.
+		resp, err = svc.DoCommand(context.Background(), map[string]interface{}{viamorbslam3.GetPointCloudMapCommand: true})
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, decodePoints(resp["map"]), test.ShouldResemble, []r3.Vector{{X: 500}, {Y: 500}, {Z: 1000}})
+	})
+
+	t.Run("Map uploads are rejected in offline mode", func(t *testing.T) {
+		_, err := svc.DoCommand(context.Background(), map[string]interface{}{viamorbslam3.BeginMapUploadCommand: map[string]interface{}{
+			"size": 4.0, "sha256": strings.Repeat("0", 64),
+		}})
+		test.That(t, err, test.ShouldBeError, errors.New("maps can only be uploaded when use_live_data is true"))
+	})
+
+	grpcServer.Stop()
+	test.That(t, svc.Close(context.Background()), test.ShouldBeNil)
+
+	closeOutSLAMService(t, name)
+}
+
+func TestDoCommand(t *testing.T) {
+	logger := golog.NewTestLogger(t)
+	name, err := testhelper.CreateTempFolderArchitecture(logger)
+	test.That(t, err, test.ShouldBeNil)
+
+	grpcServer, port := setupTestGRPCServer(t)
+	attrCfg := &orbSlamConfig.Config{
+		Sensors:       []string{"good_color_camera"},
+		ConfigParams:  map[string]string{"mode": "mono"},
+		DataDirectory: name,
+		DataRateMsec:  validDataRateMS,
+		Port:          "localhost:" + strconv.Itoa(port),
+		UseLiveData:   &_true,
+	}
+
+	// Create slam service
+	svc, err := createSLAMService(t, attrCfg, logger, false, true, testExecutableName)
+	test.That(t, err, test.ShouldBeNil)
+
+	t.Run("Get stats", func(t *testing.T) {
+		// Runtime validation captures frames before the service is returned.
+		resp, err := svc.DoCommand(context.Background(), map[string]interface{}{viamorbslam3.GetStatsCommand: true})
+		test.That(t, err, test.ShouldBeNil)
+		frames := resp["frames"].(map[string]interface{})
+		test.That(t, frames["captured"], test.ShouldBeGreaterThanOrEqualTo, 1)
+		test.That(t, resp, test.ShouldContainKey, "png_encode_latency_ms")
+		test.That(t, resp, test.ShouldContainKey, "get_position_latency_ms")
+	})
+
+	t.Run("Get tracking state fails without a SLAM server", func(t *testing.T) {
+		_, err := svc.DoCommand(context.Background(), map[string]interface{}{viamorbslam3.GetTrackingStateCommand: true})
+		test.That(t, err.Error(), test.ShouldContainSubstring, "error getting SLAM position")
+	})
+
+	t.Run("Get extrapolated position fails without a SLAM server", func(t *testing.T) {
+		_, err := svc.DoCommand(context.Background(), map[string]interface{}{viamorbslam3.GetExtrapolatedPositionCommand: true})
+		test.That(t, err.Error(), test.ShouldContainSubstring, "error getting SLAM position")
+	})
+
+	t.Run("Get scale before it is estimated", func(t *testing.T) {
+		resp, err := svc.DoCommand(context.Background(), map[string]interface{}{viamorbslam3.GetScaleCommand: true})
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, resp, test.ShouldResemble, map[string]interface{}{"estimated": false})
+	})
+
+	t.Run("Get point cloud map with invalid filters", func(t *testing.T) {
+		_, err := svc.DoCommand(context.Background(), map[string]interface{}{
+			viamorbslam3.GetPointCloudMapCommand: map[string]interface{}{"point_cloud_filters": map[string]interface{}{"voxel": 0.05}},
+		})
+		test.That(t, err.Error(), test.ShouldContainSubstring, "invalid point cloud filters")
+
+		_, err = svc.DoCommand(context.Background(), map[string]interface{}{
+			viamorbslam3.GetPointCloudMapCommand: map[string]interface{}{
+				"point_cloud_filters": map[string]interface{}{"radius": map[string]interface{}{"radius": 0.1}},
+			},
+		})
+		test.That(t, err, test.ShouldBeError,
+			errors.New("point_cloud_filters.radius requires a radius and min_neighbors greater than zero"))
+
+		_, err = svc.DoCommand(context.Background(), map[string]interface{}{
+			viamorbslam3.GetPointCloudMapCommand: map[string]interface{}{"point_cloud_filters": map[string]interface{}{"voxel_size": 0.05}},
+		})
+		test.That(t, err.Error(), test.ShouldContainSubstring, "error getting point cloud map")
+	})
+
+	t.Run("Export a map snapshot", func(t *testing.T) {
+		points := []r3.Vector{{X: -1.5, Y: 0.25, Z: 2}, {X: 3, Y: -4.125, Z: 0.5}, {X: 0, Y: 1, Z: -2}}
+		snapshot := filepath.Join(name, "map", "snapshot.pcd")
+		test.That(t, os.WriteFile(snapshot, pcd.Write(points), 0o644), test.ShouldBeNil)
+
+		resp, err := svc.DoCommand(context.Background(), map[string]interface{}{
+			viamorbslam3.ExportMapCommand: map[string]interface{}{"format": "las", "source": "map/snapshot.pcd"},
+		})
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, resp["format"], test.ShouldEqual, "las")
+		test.That(t, resp["points"], test.ShouldEqual, 3)
+		test.That(t, resp["bounds"], test.ShouldResemble, map[string]interface{}{
+			"min": map[string]interface{}{"x": -1.5, "y": -4.125, "z": -2.0},
+			"max": map[string]interface{}{"x": 3.0, "y": 1.0, "z": 2.0},
+		})
+		path := resp["path"].(string)
+		test.That(t, filepath.Dir(path), test.ShouldEqual, filepath.Join(name, "exports"))
+		test.That(t, filepath.Ext(path), test.ShouldEqual, ".las")
+		_, err = os.Stat(path)
+		test.That(t, err, test.ShouldBeNil)
+	})
+
+	t.Run("Export a map with invalid arguments", func(t *testing.T) {
+		_, err := svc.DoCommand(context.Background(), map[string]interface{}{
+			viamorbslam3.ExportMapCommand: map[string]interface{}{"format": "obj"},
+		})
+		test.That(t, err.Error(), test.ShouldContainSubstring, "unsupported map export format \"obj\"")
+
+		_, err = svc.DoCommand(context.Background(), map[string]interface{}{
+			viamorbslam3.ExportMapCommand: map[string]interface{}{"format": "ply", "source": "../outside.pcd"},
+		})
+		test.That(t, err.Error(), test.ShouldContainSubstring, "is not within the data directory")
+	})
+
+	t.Run("Export an occupancy grid", func(t *testing.T) {
+		_, err := svc.DoCommand(context.Background(), map[string]interface{}{
+			viamorbslam3.ExportOccupancyGridCommand: map[string]interface{}{"max_height": 1},
+		})
+		test.That(t, err, test.ShouldBeError, errors.New("occupancy grid resolution must be greater than zero"))
+
+		_, err = svc.DoCommand(context.Background(), map[string]interface{}{
+			viamorbslam3.ExportOccupancyGridCommand: map[string]interface{}{"cell_size": 0.05},
+		})
+		test.That(t, err.Error(), test.ShouldContainSubstring, "invalid occupancy grid parameters")
+
+		_, err = svc.DoCommand(context.Background(), map[string]interface{}{
+			viamorbslam3.ExportOccupancyGridCommand: map[string]interface{}{"resolution": 0.05, "max_height": 1},
+		})
+		test.That(t, err.Error(), test.ShouldContainSubstring, "error getting point cloud map")
+	})
+
+	t.Run("Get point cloud map delta", func(t *testing.T) {
+		_, err := svc.DoCommand(context.Background(), map[string]interface{}{
+			viamorbslam3.GetPointCloudMapDeltaCommand: map[string]interface{}{"since_version": 1.5},
+		})
+		test.That(t, err, test.ShouldBeError, errors.New("since_version must be an integer, got 1.5"))
+
+		_, err = svc.DoCommand(context.Background(), map[string]interface{}{
+			viamorbslam3.GetPointCloudMapDeltaCommand: map[string]interface{}{"since_version": 3.0},
+		})
+		test.That(t, err.Error(), test.ShouldContainSubstring, "error getting SLAM position")
+	})
+
+	t.Run("List maps", func(t *testing.T) {
+		resp, err := svc.DoCommand(context.Background(), map[string]interface{}{viamorbslam3.ListMapsCommand: true})
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, resp, test.ShouldResemble, map[string]interface{}{"maps": []interface{}{}})
+
+		oldMap := filepath.Join(name, "map", "good_color_camera_data_2023-01-01T00:00:00.0000Z.osa")
+		newMap := filepath.Join(name, "map", "good_color_camera_data_2023-02-01T00:00:00.0000Z.osa")
+		for _, path := range []string{newMap, oldMap} {
+			test.That(t, os.WriteFile(path, nil, 0o644), test.ShouldBeNil)
+		}
+		test.That(t, mapmeta.Write(newMap, mapmeta.Metadata{Mode: "mono", KeyFrames: 4, MapPoints: 250}), test.ShouldBeNil)
+
+		resp, err = svc.DoCommand(context.Background(), map[string]interface{}{viamorbslam3.ListMapsCommand: true})
+		test.That(t, err, test.ShouldBeNil)
+		maps := resp["maps"].([]interface{})
+		test.That(t, maps, test.ShouldHaveLength, 2)
+		test.That(t, maps[0], test.ShouldResemble, map[string]interface{}{
+			"path":      oldMap,
+			"timestamp": "2023-01-01T00:00:00Z",
+		})
+		newEntry := maps[1].(map[string]interface{})
+		test.That(t, newEntry["path"], test.ShouldEqual, newMap)
+		metadata := newEntry["metadata"].(map[string]interface{})
+		test.That(t, metadata["mode"], test.ShouldEqual, "mono")
+		test.That(t, metadata["keyframes"], test.ShouldEqual, 4.0)
+		test.That(t, metadata["map_points"], test.ShouldEqual, 250.0)
+
+		for _, path := range []string{newMap, oldMap, mapmeta.SidecarPath(newMap)} {
+			test.That(t, os.Remove(path), test.ShouldBeNil)
+		}
+	})
+
+	t.Run("Upload a map", func(t *testing.T) {
+		content := []byte("an uploaded map")
+		sum := sha256.Sum256(content)
+		checksum := hex.EncodeToString(sum[:])
+		ctx := context.Background()
+
+		_, err := svc.DoCommand(ctx, map[string]interface{}{viamorbslam3.BeginMapUploadCommand: map[string]interface{}{
+			"size": float64(len(content)), "sha256": checksum, "metadata": map[string]interface{}{"mode": "rgbd"},
+		}})
+		test.That(t, err.Error(), test.ShouldContainSubstring, "uploaded map is incompatible with the current settings")
+
+		resp, err := svc.DoCommand(ctx, map[string]interface{}{viamorbslam3.BeginMapUploadCommand: map[string]interface{}{
+			"size": float64(len(content)), "sha256": checksum,
+		}})
+		test.That(t, err, test.ShouldBeNil)
+		uploadID := resp["upload_id"].(string)
+
+		_, err = svc.DoCommand(ctx, map[string]interface{}{viamorbslam3.UploadMapChunkCommand: map[string]interface{}{
+			"upload_id": "unknown", "offset": 0.0, "data": base64.StdEncoding.EncodeToString(content),
+		}})
+		test.That(t, err.Error(), test.ShouldContainSubstring, "no map upload in progress with upload_id unknown")
+
+		for _, bounds := range [][2]int{{0, 6}, {6, len(content)}} {
+			resp, err = svc.DoCommand(ctx, map[string]interface{}{viamorbslam3.UploadMapChunkCommand: map[string]interface{}{
+				"upload_id": uploadID,
+				"offset":    float64(bounds[0]),
+				"data":      base64.StdEncoding.EncodeToString(content[bounds[0]:bounds[1]]),
+			}})
+			test.That(t, err, test.ShouldBeNil)
+			test.That(t, resp["received"], test.ShouldEqual, int64(bounds[1]))
+		}
+
+		resp, err = svc.DoCommand(ctx, map[string]interface{}{viamorbslam3.FinishMapUploadCommand: map[string]interface{}{
+			"upload_id": uploadID,
+		}})
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, resp["reloaded"], test.ShouldBeTrue)
+		mapPath := resp["map"].(string)
+		data, err := os.ReadFile(mapPath)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, data, test.ShouldResemble, content)
+		metadata, err := mapmeta.Read(mapPath)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, metadata.Mode, test.ShouldEqual, "mono")
+
+		// The SLAM process is restarted in localization mode.
+		processCfg := svc.(testhelper.Service).GetSLAMProcessConfig()
+		test.That(t, processCfg.Args, test.ShouldContain, "-map_rate_sec=0")
+
+		_, err = svc.DoCommand(ctx, map[string]interface{}{viamorbslam3.FinishMapUploadCommand: map[string]interface{}{
+			"upload_id": uploadID,
+		}})
+		test.That(t, err.Error(), test.ShouldContainSubstring, "no map upload in progress")
+
+		for _, path := range []string{mapPath, mapmeta.SidecarPath(mapPath)} {
+			test.That(t, os.Remove(path), test.ShouldBeNil)
+		}
+	})
+
+	t.Run("Upload a map that does not match its checksum", func(t *testing.T) {
+		ctx := context.Background()
+		resp, err := svc.DoCommand(ctx, map[string]interface{}{viamorbslam3.BeginMapUploadCommand: map[string]interface{}{
+			"size": 4.0, "sha256": strings.Repeat("0", 64),
+		}})
+		test.That(t, err, test.ShouldBeNil)
+		uploadID := resp["upload_id"].(string)
+		_, err = svc.DoCommand(ctx, map[string]interface{}{viamorbslam3.UploadMapChunkCommand: map[string]interface{}{
+			"upload_id": uploadID, "offset": 0.0, "data": base64.StdEncoding.EncodeToString([]byte("map!")),
+		}})
+		test.That(t, err, test.ShouldBeNil)
+		_, err = svc.DoCommand(ctx, map[string]interface{}{viamorbslam3.FinishMapUploadCommand: map[string]interface{}{
+			"upload_id": uploadID,
+		}})
+		test.That(t, err.Error(), test.ShouldContainSubstring, "upload has checksum")
+
+		entries, err := os.ReadDir(filepath.Join(name, "map"))
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, entries, test.ShouldBeEmpty)
+	})
+
+	t.Run("Download internal state fails without a SLAM server", func(t *testing.T) {
+		_, err := svc.DoCommand(context.Background(), map[string]interface{}{
+			viamorbslam3.DownloadInternalStateCommand: map[string]interface{}{"path": "../internal_state.osa"},
+		})
+		test.That(t, err.Error(), test.ShouldContainSubstring, "is not within the data directory")
+
+		_, err = svc.DoCommand(context.Background(), map[string]interface{}{viamorbslam3.DownloadInternalStateCommand: true})
+		test.That(t, err.Error(), test.ShouldContainSubstring, "error downloading internal state")
+	})
+
+	t.Run("Unknown command", func(t *testing.T) {
+		_, err := svc.DoCommand(context.Background(), map[string]interface{}{"unknown_command": true})
+		test.That(t, err, test.ShouldBeError, resource.ErrDoUnimplemented)
+	})
+
+	grpcServer.Stop()
+	test.That(t, svc.Close(context.Background()), test.ShouldBeNil)
+
+	closeOutSLAMService(t, name)
+}
+
 func TestSLAMProcessSuccess(t *testing.T) {
 	logger := golog.NewTestLogger(t)
 	name, err := testhelper.CreateTempFolderArchitecture(logger)
@@ -794,6 +1366,7 @@ func TestSLAMProcessSuccess(t *testing.T) {
 			{"-delete_processed_data=true"},
 			{"-use_live_data=true"},
 			{"-port=localhost:" + strconv.Itoa(port)},
+			{"-ready_file=" + filepath.Join(name, "orbslam_ready.json")},
 			{"--aix-auto-update"},
 		}
 
@@ -835,6 +1408,7 @@ func TestSLAMProcessSuccess(t *testing.T) {
 			{"-delete_processed_data=false"},
 			{"-use_live_data=false"},
 			{"-port=localhost:" + strconv.Itoa(port)},
+			{"-ready_file=" + filepath.Join(name, "orbslam_ready.json")},
 			{"--aix-auto-update"},
 		}
 
@@ -848,6 +1422,36 @@ func TestSLAMProcessSuccess(t *testing.T) {
 		test.That(t, svc.Close(context.Background()), test.ShouldBeNil)
 	})
 
+	t.Run("Test online SLAM process over a unix socket", func(t *testing.T) {
+		socketPath := filepath.Join(name, "orbslam.sock")
+		listener, err := net.Listen("unix", socketPath)
+		test.That(t, err, test.ShouldBeNil)
+		grpcServer := grpc.NewServer()
+		go grpcServer.Serve(listener)
+
+		attrCfg := &orbSlamConfig.Config{
+			Sensors:       []string{"good_color_camera", "good_depth_camera"},
+			ConfigParams:  map[string]string{"mode": "rgbd"},
+			DataDirectory: name,
+			Transport:     viamorbslam3.TransportUnix,
+			UseLiveData:   &_true,
+		}
+
+		// Create slam service
+		svc, err := createSLAMService(t, attrCfg, logger, false, true, testExecutableName)
+		test.That(t, err, test.ShouldBeNil)
+
+		orbSvc := svc.(testhelper.Service)
+		processCfg := orbSvc.GetSLAMProcessConfig()
+		test.That(t, processCfg.Args, test.ShouldContain, "-port=unix:"+socketPath)
+
+		// The socket is removed on close.
+		test.That(t, svc.Close(context.Background()), test.ShouldBeNil)
+		_, err = os.Stat(socketPath)
+		test.That(t, os.IsNotExist(err), test.ShouldBeTrue)
+		grpcServer.Stop()
+	})
+
 	closeOutSLAMService(t, name)
 }
 
@@ -874,6 +1478,110 @@ func TestSLAMProcessFail(t *testing.T) {
 		test.That(t, fmt.Sprint(err), test.ShouldContainSubstring, "executable file not found in $PATH")
 	})
 
+	t.Run("Run SLAM process with a missing vocabulary", func(t *testing.T) {
+		cfg := *attrCfg
+		cfg.VocabularyPath = filepath.Join(name, "config", "missing_ORBvoc.txt")
+		_, err := createSLAMService(t, &cfg, logger, false, true, testExecutableName)
+		test.That(t, fmt.Sprint(err), test.ShouldContainSubstring, "unable to open vocabulary "+cfg.VocabularyPath)
+	})
+
+	t.Run("Run SLAM process with a truncated vocabulary", func(t *testing.T) {
+		cfg := *attrCfg
+		cfg.VocabularyPath = filepath.Join(name, "config", "truncated_ORBvoc.txt")
+		test.That(t, os.WriteFile(cfg.VocabularyPath, []byte("10 6 0 0\n"), 0o644), test.ShouldBeNil)
+		_, err := createSLAMService(t, &cfg, logger, false, true, testExecutableName)
+		test.That(t, fmt.Sprint(err), test.ShouldContainSubstring, "vocabulary "+cfg.VocabularyPath+" is truncated")
+	})
+
+	grpcServer.Stop()
+
+	closeOutSLAMService(t, name)
+}
+
+func TestReplay(t *testing.T) {
+	logger := golog.NewTestLogger(t)
+	name, err := testhelper.CreateTempFolderArchitecture(logger)
+	test.That(t, err, test.ShouldBeNil)
+
+	grpcServer, port := setupTestGRPCServer(t)
+	attrCfg := &orbSlamConfig.Config{
+		Sensors:       []string{},
+		ConfigParams:  map[string]string{"mode": "mono"},
+		DataDirectory: name,
+		Port:          "localhost:" + strconv.Itoa(port),
+		UseLiveData:   &_false,
+	}
+	viamorbslam3.SetDialMaxTimeoutSecForTesting(2)
+
+	t.Run("Replay with live data", func(t *testing.T) {
+		cfg := *attrCfg
+		cfg.Sensors = []string{"good_color_camera"}
+		_, err := viamorbslam3.Replay(context.Background(), &cfg, viamorbslam3.ReplayOptions{}, logger)
+		test.That(t, fmt.Sprint(err), test.ShouldContainSubstring, "replay runs in offline mode")
+	})
+
+	t.Run("Replay with a slam process that does not finish", func(t *testing.T) {
+		outDir := filepath.Join(name, "replay")
+		_, err := viamorbslam3.Replay(context.Background(), attrCfg, viamorbslam3.ReplayOptions{
+			ExecutableName:  testExecutableName,
+			OutputDirectory: outDir,
+			Timeout:         100 * time.Millisecond,
+		}, logger)
+		test.That(t, fmt.Sprint(err), test.ShouldContainSubstring, "slam process did not finish processing the recorded data")
+		_, err = os.Stat(filepath.Join(outDir, "pose.json"))
+		test.That(t, os.IsNotExist(err), test.ShouldBeTrue)
+	})
+
+	t.Run("Replay writes the results once the final map is saved", func(t *testing.T) {
+		fakeServer, fakePort, fake := setupFakeSLAMServer(t)
+		defer fakeServer.Stop()
+		points := []r3.Vector{{X: 1}, {Y: 2, Z: 3}}
+		fake.set(spatialmath.NewPoseFromPoint(r3.Vector{X: 1, Y: 2, Z: 3}), 1, points)
+		fake.mu.Lock()
+		fake.internalState = []byte("internal state")
+		fake.mu.Unlock()
+		// The lines the slam server logs at info, which is all it logs without debug in config_params.
+		executable, err := testhelper.WriteFakeSLAMExecutable(t.TempDir(),
+			"[2023-06-13 15:04:05.123456] [0x00007f8b2c1a2740] [info]    Finished processing offline images",
+			"[2023-06-13 15:04:06.123456] [0x00007f8b2c1a2740] [info]    Finished saving final map")
+		test.That(t, err, test.ShouldBeNil)
+
+		cfg := *attrCfg
+		cfg.Port = "localhost:" + strconv.Itoa(fakePort)
+		result, err := viamorbslam3.Replay(context.Background(), &cfg, viamorbslam3.ReplayOptions{
+			ExecutableName:  executable,
+			OutputDirectory: filepath.Join(name, "replay_done"),
+			Timeout:         10 * time.Second,
+		}, logger)
+		test.That(t, err, test.ShouldBeNil)
+
+		data, err := os.ReadFile(result.PosePath)
+		test.That(t, err, test.ShouldBeNil)
+		var pose struct {
+			ComponentReference string `json:"component_reference"`
+			Pose               struct {
+				X, Y, Z float64
+			} `json:"pose"`
+		}
+		test.That(t, json.Unmarshal(data, &pose), test.ShouldBeNil)
+		test.That(t, pose.ComponentReference, test.ShouldEqual, "fake_camera")
+		test.That(t, pose.Pose.X, test.ShouldEqual, 1)
+		test.That(t, pose.Pose.Y, test.ShouldEqual, 2)
+		test.That(t, pose.Pose.Z, test.ShouldEqual, 3)
+
+		data, err = os.ReadFile(result.PointCloudPath)
+		test.That(t, err, test.ShouldBeNil)
+		pointCloud, err := pcd.Read(data)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, pointCloud, test.ShouldResemble, points)
+
+		data, err = os.ReadFile(result.InternalStatePath)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, string(data), test.ShouldEqual, "internal state")
+		sum := sha256.Sum256(data)
+		test.That(t, result.InternalStateSHA256, test.ShouldEqual, hex.EncodeToString(sum[:]))
+	})
+
 	grpcServer.Stop()
 
 	closeOutSLAMService(t, name)
@@ -0,0 +1,89 @@
+package viamorbslam3
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	goutils "go.viam.com/utils"
+
+	"github.com/viamrobotics/viam-orb-slam3/vocabulary"
+)
+
+// vocabularyPath is the path of the vocabulary the SLAM process loads, by default the one in the data directory.
+func (orbSvc *orbslamService) vocabularyPath() string {
+	if orbSvc.vocabularyFile != "" {
+		return orbSvc.vocabularyFile
+	}
+	return filepath.Join(orbSvc.dataDirectory, "config", vocabulary.DefaultFileName)
+}
+
+// vocabularyFileInfo identifies a version of the vocabulary file, so that it is checked again once it is replaced.
+type vocabularyFileInfo struct {
+	size    int64
+	modTime time.Time
+}
+
+// checkVocabulary checks the vocabulary and returns its checksum. Since the vocabulary is large, the result is reused
+// until the size or modification time of the file changes.
+func (orbSvc *orbslamService) checkVocabulary() (string, error) {
+	orbSvc.vocabularyMu.Lock()
+	defer orbSvc.vocabularyMu.Unlock()
+
+	path := orbSvc.vocabularyPath()
+	var fileInfo vocabularyFileInfo
+	stat, statErr := os.Stat(path)
+	if statErr == nil {
+		fileInfo = vocabularyFileInfo{size: stat.Size(), modTime: stat.ModTime()}
+		if orbSvc.vocabularyChecked && orbSvc.vocabularyFileInfo == fileInfo {
+			return orbSvc.vocabularyResult()
+		}
+	}
+
+	info, err := vocabulary.Check(path, orbSvc.expectedVocabSHA256)
+	// A missing vocabulary is checked again every time, since it may be added later.
+	orbSvc.vocabularyChecked, orbSvc.vocabularyFileInfo = statErr == nil, fileInfo
+	orbSvc.metadataMu.Lock()
+	orbSvc.vocabularySHA256, orbSvc.vocabularyErr = info.SHA256, err
+	orbSvc.metadataMu.Unlock()
+	return info.SHA256, err
+}
+
+// vocabularyResult returns the result of the last check of the vocabulary.
+func (orbSvc *orbslamService) vocabularyResult() (string, error) {
+	orbSvc.metadataMu.Lock()
+	defer orbSvc.metadataMu.Unlock()
+	return orbSvc.vocabularySHA256, orbSvc.vocabularyErr
+}
+
+// validateVocabulary returns an error if the vocabulary the SLAM process would load is missing, truncated or does not
+// match the configured checksum. If no vocabulary is configured and there is none in the data directory, the SLAM
+// process falls back to the one it was installed with, which is not checked.
+func (orbSvc *orbslamService) validateVocabulary() error {
+	if orbSvc.vocabularyFile == "" && orbSvc.expectedVocabSHA256 == "" {
+		if _, err := os.Stat(orbSvc.vocabularyPath()); os.IsNotExist(err) {
+			return nil
+		}
+	}
+	_, err := orbSvc.checkVocabulary()
+	return err
+}
+
+// vocabularyChecksum returns the checksum of the vocabulary, or an empty string if it cannot be read or is invalid.
+func (orbSvc *orbslamService) vocabularyChecksum() string {
+	sum, err := orbSvc.checkVocabulary()
+	if err != nil {
+		orbSvc.logger.Debugw("unable to compute the checksum of the vocabulary", "error", err)
+	}
+	return sum
+}
+
+// startVocabularyChecksum computes the checksum of the vocabulary in the background, to record it in the metadata of
+// saved maps without delaying them.
+func (orbSvc *orbslamService) startVocabularyChecksum() {
+	orbSvc.activeBackgroundWorkers.Add(1)
+	goutils.PanicCapturingGo(func() {
+		defer orbSvc.activeBackgroundWorkers.Done()
+		orbSvc.vocabularyChecksum()
+	})
+}
@@ -0,0 +1,175 @@
+// Package vocabulary checks the integrity of ORB vocabularies in the DBoW2 text format used by ORB_SLAM3. ORB_SLAM3
+// only loads the text format, so vocabularies are not converted into a faster loading binary form.
+package vocabulary
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultFileName is the name of the vocabulary distributed with ORB_SLAM3.
+const DefaultFileName = "ORBvoc.txt"
+
+// nodeFields is the number of fields of a node of an ORB vocabulary: the id of its parent, whether it is a leaf, the
+// 32 bytes of its descriptor and its weight.
+const nodeFields = 35
+
+// node is the part of a node of the vocabulary tree needed to check its structure.
+type node struct {
+	depth       int
+	leaf        bool
+	hasChildren bool
+}
+
+// Info describes a vocabulary.
+type Info struct {
+	SHA256 string
+	// BranchingFactor and Depth are the shape of the vocabulary tree.
+	BranchingFactor int
+	Depth           int
+	// Nodes is the number of nodes of the tree, not counting the root.
+	Nodes int
+}
+
+// Check reads the vocabulary at path and returns an error if it is malformed or truncated, or if expectedSHA256 is
+// given and does not match the checksum of the file. Nodes are stored breadth first, so a vocabulary truncated between
+// lines is detected by the nodes left without children, unless it is cut among the children of its last parent;
+// only a checksum rules that out.
+func Check(path, expectedSHA256 string) (Info, error) {
+	//nolint:gosec
+	f, err := os.Open(path)
+	if err != nil {
+		return Info{}, errors.Wrapf(err, "unable to open vocabulary %v", path)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	reader := bufio.NewReaderSize(io.TeeReader(f, h), 1<<20)
+	var info Info
+	// nodes holds the root followed by the nodes in the order they are stored, which is also their id.
+	nodes := []node{{}}
+	lineNumber := 0
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return Info{}, errors.Wrapf(err, "unable to read vocabulary %v", path)
+		}
+		if line == "" && errors.Is(err, io.EOF) {
+			break
+		}
+		lineNumber++
+		if !strings.HasSuffix(line, "\n") {
+			return Info{}, errors.Errorf("vocabulary %v is truncated, its last line %d is incomplete", path, lineNumber)
+		}
+		fields := strings.Fields(line)
+		if lineNumber == 1 {
+			if info.BranchingFactor, info.Depth, err = parseHeader(fields); err != nil {
+				return Info{}, errors.Wrapf(err, "vocabulary %v has an invalid header", path)
+			}
+			continue
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != nodeFields {
+			return Info{}, errors.Errorf("vocabulary %v is invalid, line %d has %d fields instead of %d",
+				path, lineNumber, len(fields), nodeFields)
+		}
+		n, err := parseNode(fields, nodes, info.Depth)
+		if err != nil {
+			return Info{}, errors.Wrapf(err, "vocabulary %v is invalid at line %d", path, lineNumber)
+		}
+		nodes = append(nodes, n)
+		info.Nodes++
+	}
+	if lineNumber == 0 {
+		return Info{}, errors.Errorf("vocabulary %v is empty", path)
+	}
+	if info.Nodes < info.BranchingFactor {
+		return Info{}, errors.Errorf("vocabulary %v is truncated, it has %d nodes", path, info.Nodes)
+	}
+	if maxNodes := maxNodes(info.BranchingFactor, info.Depth); info.Nodes > maxNodes {
+		return Info{}, errors.Errorf("vocabulary %v is invalid, it has %d nodes but its header allows at most %d",
+			path, info.Nodes, maxNodes)
+	}
+	for id, n := range nodes {
+		if !n.leaf && !n.hasChildren {
+			return Info{}, errors.Errorf("vocabulary %v is truncated, node %d has no children", path, id)
+		}
+	}
+
+	info.SHA256 = hex.EncodeToString(h.Sum(nil))
+	if expectedSHA256 != "" && !strings.EqualFold(info.SHA256, expectedSHA256) {
+		return Info{}, errors.Errorf("vocabulary %v has checksum %v, expected %v", path, info.SHA256, expectedSHA256)
+	}
+	return info, nil
+}
+
+// parseNode parses a node of the vocabulary, given the nodes stored before it, and marks its parent as having
+// children.
+func parseNode(fields []string, nodes []node, maxDepth int) (node, error) {
+	parent, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return node{}, err
+	}
+	if parent < 0 || parent >= len(nodes) {
+		return node{}, errors.Errorf("parent %d is not stored before node %d", parent, len(nodes))
+	}
+	if nodes[parent].leaf {
+		return node{}, errors.Errorf("parent %d is a leaf", parent)
+	}
+	var n node
+	switch fields[1] {
+	case "0":
+	case "1":
+		n.leaf = true
+	default:
+		return node{}, errors.Errorf("leaf flag %v is neither 0 nor 1", fields[1])
+	}
+	n.depth = nodes[parent].depth + 1
+	if n.depth > maxDepth {
+		return node{}, errors.Errorf("node is deeper than the depth %d of the vocabulary", maxDepth)
+	}
+	nodes[parent].hasChildren = true
+	return n, nil
+}
+
+// maxNodes returns the number of nodes of a full vocabulary tree, not counting the root.
+func maxNodes(branchingFactor, depth int) int {
+	total, level := 0, 1
+	for d := 0; d < depth; d++ {
+		if level > math.MaxInt/branchingFactor {
+			return math.MaxInt
+		}
+		level *= branchingFactor
+		total += level
+	}
+	return total
+}
+
+// parseHeader parses the first line of a vocabulary, which holds its branching factor, depth, scoring and weighting.
+func parseHeader(fields []string) (int, int, error) {
+	if len(fields) != 4 {
+		return 0, 0, errors.Errorf("expected 4 fields, got %d", len(fields))
+	}
+	values := make([]int, len(fields))
+	for i, field := range fields {
+		v, err := strconv.Atoi(field)
+		if err != nil {
+			return 0, 0, err
+		}
+		values[i] = v
+	}
+	if values[0] < 2 || values[1] < 1 {
+		return 0, 0, errors.Errorf("branching factor %d and depth %d are out of range", values[0], values[1])
+	}
+	return values[0], values[1], nil
+}
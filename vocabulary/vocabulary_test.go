@@ -0,0 +1,110 @@
+package vocabulary
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.viam.com/test"
+)
+
+// testVocabulary returns a vocabulary with a branching factor of 2 and a depth of 1.
+func testVocabulary() string {
+	descriptor := strings.Repeat("0 ", 32)
+	return "2 1 0 0\n" +
+		"0 1 " + descriptor + "0.5\n" +
+		"0 1 " + descriptor + "0.25\n"
+}
+
+func writeVocabulary(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), DefaultFileName)
+	test.That(t, os.WriteFile(path, []byte(content), 0o644), test.ShouldBeNil)
+	return path
+}
+
+func TestCheck(t *testing.T) {
+	content := testVocabulary()
+	sum := sha256.Sum256([]byte(content))
+	checksum := hex.EncodeToString(sum[:])
+
+	t.Run("Valid vocabulary", func(t *testing.T) {
+		path := writeVocabulary(t, content)
+		info, err := Check(path, "")
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, info, test.ShouldResemble, Info{SHA256: checksum, BranchingFactor: 2, Depth: 1, Nodes: 2})
+
+		_, err = Check(path, strings.ToUpper(checksum))
+		test.That(t, err, test.ShouldBeNil)
+	})
+
+	t.Run("Checksum mismatch", func(t *testing.T) {
+		path := writeVocabulary(t, content)
+		_, err := Check(path, strings.Repeat("0", 64))
+		test.That(t, err.Error(), test.ShouldContainSubstring, "has checksum "+checksum)
+	})
+
+	t.Run("Missing vocabulary", func(t *testing.T) {
+		_, err := Check(filepath.Join(t.TempDir(), DefaultFileName), "")
+		test.That(t, err.Error(), test.ShouldContainSubstring, "unable to open vocabulary")
+	})
+
+	t.Run("Truncated vocabulary", func(t *testing.T) {
+		_, err := Check(writeVocabulary(t, content[:len(content)-10]), "")
+		test.That(t, err.Error(), test.ShouldContainSubstring, "is truncated, its last line 3 is incomplete")
+
+		lines := strings.SplitAfter(content, "\n")
+		_, err = Check(writeVocabulary(t, lines[0]+lines[1]), "")
+		test.That(t, err.Error(), test.ShouldContainSubstring, "is truncated, it has 1 nodes")
+
+		_, err = Check(writeVocabulary(t, ""), "")
+		test.That(t, err.Error(), test.ShouldContainSubstring, "is empty")
+
+		// Cut after the first level of a vocabulary of depth 2, so that its non leaf nodes have no children.
+		descriptor := strings.Repeat("0 ", 32)
+		_, err = Check(writeVocabulary(t, "2 2 0 0\n"+
+			"0 0 "+descriptor+"0\n"+
+			"0 0 "+descriptor+"0\n"), "")
+		test.That(t, err.Error(), test.ShouldContainSubstring, "is truncated, node 1 has no children")
+
+		_, err = Check(writeVocabulary(t, "2 2 0 0\n"+
+			"0 0 "+descriptor+"0\n"+
+			"0 0 "+descriptor+"0\n"+
+			"1 1 "+descriptor+"0.5\n"), "")
+		test.That(t, err.Error(), test.ShouldContainSubstring, "is truncated, node 2 has no children")
+	})
+
+	t.Run("Invalid vocabulary structure", func(t *testing.T) {
+		descriptor := strings.Repeat("0 ", 32)
+		leaf := func(parent string) string { return parent + " 1 " + descriptor + "0.5\n" }
+
+		_, err := Check(writeVocabulary(t, "2 1 0 0\n"+leaf("0")+leaf("3")), "")
+		test.That(t, err.Error(), test.ShouldContainSubstring, "invalid at line 3: parent 3 is not stored before node 2")
+
+		_, err = Check(writeVocabulary(t, "2 1 0 0\n"+leaf("0")+leaf("1")), "")
+		test.That(t, err.Error(), test.ShouldContainSubstring, "invalid at line 3: parent 1 is a leaf")
+
+		_, err = Check(writeVocabulary(t, "2 1 0 0\n"+leaf("0")+"0 2 "+descriptor+"0.5\n"), "")
+		test.That(t, err.Error(), test.ShouldContainSubstring, "leaf flag 2 is neither 0 nor 1")
+
+		_, err = Check(writeVocabulary(t, "2 1 0 0\n"+"0 0 "+descriptor+"0\n"+leaf("0")+leaf("1")), "")
+		test.That(t, err.Error(), test.ShouldContainSubstring, "invalid at line 4: node is deeper than the depth 1")
+
+		_, err = Check(writeVocabulary(t, "2 1 0 0\n"+leaf("0")+leaf("0")+leaf("0")), "")
+		test.That(t, err.Error(), test.ShouldContainSubstring, "it has 3 nodes but its header allows at most 2")
+	})
+
+	t.Run("Invalid vocabulary", func(t *testing.T) {
+		_, err := Check(writeVocabulary(t, "2 1 0\n"), "")
+		test.That(t, err.Error(), test.ShouldContainSubstring, "has an invalid header: expected 4 fields, got 3")
+
+		_, err = Check(writeVocabulary(t, "1 1 0 0\n"), "")
+		test.That(t, err.Error(), test.ShouldContainSubstring, "branching factor 1 and depth 1 are out of range")
+
+		_, err = Check(writeVocabulary(t, "2 1 0 0\n0 1 0.5\n"), "")
+		test.That(t, err.Error(), test.ShouldContainSubstring, "line 2 has 3 fields instead of 35")
+	})
+}